@@ -0,0 +1,89 @@
+package orient
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultInsertBatchSize is used by InsertAll if batch size is not set.
+const DefaultInsertBatchSize = 100
+
+// ErrBatchInsert is returned by InsertAll when one of batches failed. Batches are executed in transactions,
+// so documents from the failed batch are not inserted, while all documents from previous batches are.
+type ErrBatchInsert struct {
+	Batch    int // index of failed batch
+	Inserted int // number of documents inserted by previous batches
+	Err      error
+}
+
+func (e ErrBatchInsert) Error() string {
+	return fmt.Sprintf("batch %d failed (%d documents inserted): %v", e.Batch, e.Inserted, e.Err)
+}
+
+// insertScript builds SQL batch script that inserts documents in a single transaction and returns their RIDs.
+func insertScript(class string, docs []*Document) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString("begin\n")
+	for i, doc := range docs {
+		data, err := contentJSON(doc)
+		if err != nil {
+			return "", err
+		}
+		// batch scripts are split by semicolons, which can occur only in JSON strings
+		content := strings.Replace(string(data), ";", `\u003b`, -1)
+		buf.WriteString("let r" + strconv.Itoa(i) + " = INSERT INTO " + class + " CONTENT " + content + "\n")
+	}
+	buf.WriteString("commit\nreturn [")
+	for i := range docs {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString("$r" + strconv.Itoa(i))
+	}
+	buf.WriteString("]")
+	return buf.String(), nil
+}
+
+// InsertAll inserts documents into a given class using SQL batch scripts with batchSize documents each
+// (or DefaultInsertBatchSize, if batchSize is not positive). RIDs of inserted documents are returned in
+// the same order and are also set on documents.
+//
+// Each batch is executed in a separate transaction. If a batch fails, RIDs of documents inserted by previous
+// batches are returned together with ErrBatchInsert.
+func (db *Database) InsertAll(class string, docs []*Document, batchSize int) ([]RID, error) {
+	if class == "" {
+		return nil, fmt.Errorf("class name is required")
+	} else if !isSQLName(class) {
+		return nil, fmt.Errorf("invalid class name: %q", class)
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultInsertBatchSize
+	}
+	rids := make([]RID, 0, len(docs))
+	for b := 0; len(rids) < len(docs); b++ {
+		batch := docs[len(rids):]
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		fail := func(err error) ([]RID, error) {
+			return rids, ErrBatchInsert{Batch: b, Inserted: len(rids), Err: err}
+		}
+		script, err := insertScript(class, batch)
+		if err != nil {
+			return fail(err)
+		}
+		var out []RID
+		if err = db.Command(NewScriptCommand(LangSQL, script)).All(&out); err != nil {
+			return fail(err)
+		} else if len(out) != len(batch) {
+			return fail(fmt.Errorf("expected %d records, got %d", len(batch), len(out)))
+		}
+		for i, doc := range batch {
+			doc.RID = out[i]
+		}
+		rids = append(rids, out...)
+	}
+	return rids, nil
+}
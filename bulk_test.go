@@ -0,0 +1,30 @@
+package orient
+
+import "testing"
+
+func TestInsertScript(t *testing.T) {
+	a := NewDocument("Cat")
+	a.SetField("name", "Linus; Jr.")
+	a.SetFieldWithType("age", int64(15), LONG)
+	b := NewEmptyDocument()
+	b.SetField("name", "Keiko")
+
+	script, err := insertScript("Cat", []*Document{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "begin\n" +
+		`let r0 = INSERT INTO Cat CONTENT {"name":"Linus\u003b Jr.","age":15,"@fieldTypes":"age=l"}` + "\n" +
+		`let r1 = INSERT INTO Cat CONTENT {"name":"Keiko"}` + "\n" +
+		"commit\nreturn [$r0,$r1]"
+	if script != expect {
+		t.Fatalf("wrong script:\n%s\nexpected:\n%s", script, expect)
+	}
+}
+
+func TestInsertAllInvalidClass(t *testing.T) {
+	var db Database // class name is checked before any request is made
+	if _, err := db.InsertAll("Cat CONTENT {}\nDELETE VERTEX V", []*Document{NewEmptyDocument()}, 1); err == nil {
+		t.Fatal("expected error for invalid class name")
+	}
+}
@@ -0,0 +1,151 @@
+package orient
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// recordCache is a thread-safe LRU cache of documents by RID.
+type recordCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	items map[RID]*list.Element
+	lru   *list.List // front is the most recently used
+}
+
+// cacheEntry keeps serialized content of a document, so each cache hit decodes a separate copy of it.
+type cacheEntry struct {
+	rid    RID
+	vers   int
+	data   []byte
+	ser    RecordSerializer
+	stored time.Time
+}
+
+func newRecordCache(size int) *recordCache {
+	return &recordCache{size: size, items: make(map[RID]*list.Element), lru: list.New()}
+}
+
+// get returns a copy of a cached document, unless it's older than TTL.
+func (c *recordCache) get(rid RID) *Document {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[rid]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Since(e.stored) > c.ttl {
+		c.lru.Remove(el)
+		delete(c.items, rid)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	doc := NewDocumentFromRID(e.rid)
+	doc.SetSerializer(e.ser)
+	doc.Fill(e.rid, e.vers, e.data)
+	return doc
+}
+
+// put stores a copy of a document in cache. Cached document is not replaced with an older version of the same record.
+// Documents without serialized content are evicted instead.
+func (c *recordCache) put(doc *Document) {
+	rid := doc.GetIdentity()
+	if !rid.IsPersistent() {
+		return
+	}
+	data := doc.RawBytes()
+	if data == nil || doc.ser == nil {
+		c.remove(rid)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[rid]; ok {
+		e := el.Value.(*cacheEntry)
+		if e.vers > doc.Vers {
+			return
+		}
+		e.vers, e.data, e.ser, e.stored = doc.Vers, append([]byte(nil), data...), doc.ser, time.Now()
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.items[rid] = c.lru.PushFront(&cacheEntry{
+		rid: rid, vers: doc.Vers, data: append([]byte(nil), data...), ser: doc.ser, stored: time.Now(),
+	})
+	for c.lru.Len() > c.size {
+		el := c.lru.Back()
+		c.lru.Remove(el)
+		delete(c.items, el.Value.(*cacheEntry).rid)
+	}
+}
+
+func (c *recordCache) remove(rid RID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[rid]; ok {
+		c.lru.Remove(el)
+		delete(c.items, rid)
+	}
+}
+
+// EnableRecordCache enables client-side LRU cache for documents loaded by RID, including records loaded by
+// LinkLoader. Size is the maximal number of cached documents; zero or negative value disables the cache.
+//
+// Records created, updated or deleted through this Database (directly or in transactions) are updated in
+// or evicted from the cache, but changes made by SQL commands or other clients are not tracked.
+// Use SetRecordCacheTTL to limit staleness of cached records, or ignoreCache flag of GetRecordByRID
+// to bypass the cache. Each cache hit returns a separate copy of the document, so callers may modify it.
+func (db *Database) EnableRecordCache(size int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if size <= 0 {
+		db.cache = nil
+		return
+	}
+	c := newRecordCache(size)
+	if db.cache != nil {
+		c.ttl = db.cache.ttl
+	}
+	db.cache = c
+}
+
+// SetRecordCacheTTL sets the time after which cached records are considered stale and are loaded again.
+// Zero means that cached records are returned until evicted. Record cache must be enabled first.
+func (db *Database) SetRecordCacheTTL(d time.Duration) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.cache != nil {
+		db.cache.mu.Lock()
+		db.cache.ttl = d
+		db.cache.mu.Unlock()
+	}
+}
+
+func (db *Database) recordCache() *recordCache {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.cache
+}
+
+// cacheRecord stores a document in record cache, if it's enabled. Other records are evicted.
+func (db *Database) cacheRecord(rec ORecord) {
+	c := db.recordCache()
+	if c == nil || rec == nil {
+		return
+	}
+	if doc, ok := rec.(*Document); ok {
+		c.put(doc)
+	} else {
+		c.remove(rec.GetIdentity())
+	}
+}
+
+// evictRecord removes a record from record cache, if it's enabled.
+func (db *Database) evictRecord(rid RID) {
+	if c := db.recordCache(); c != nil {
+		c.remove(rid)
+	}
+}
@@ -0,0 +1,80 @@
+package orient
+
+import (
+	"testing"
+	"time"
+)
+
+func cachedDoc(pos int64, vers int) *Document {
+	data, err := NewEmptyDocument().SetField("pos", pos).Content()
+	if err != nil {
+		panic(err)
+	}
+	doc := NewDocumentFromRID(RID{ClusterID: 9, ClusterPos: pos})
+	doc.Fill(doc.RID, vers, data)
+	return doc
+}
+
+func TestRecordCacheLRU(t *testing.T) {
+	c := newRecordCache(2)
+	c.put(cachedDoc(1, 1))
+	c.put(cachedDoc(2, 1))
+	if c.get(RID{ClusterID: 9, ClusterPos: 1}) == nil {
+		t.Fatal("record is not cached")
+	}
+	c.put(cachedDoc(3, 1)) // evicts #9:2, as #9:1 was used recently
+	if c.get(RID{ClusterID: 9, ClusterPos: 2}) != nil {
+		t.Fatal("least recently used record is not evicted")
+	} else if c.get(RID{ClusterID: 9, ClusterPos: 1}) == nil || c.get(RID{ClusterID: 9, ClusterPos: 3}) == nil {
+		t.Fatal("record is evicted")
+	}
+	c.remove(RID{ClusterID: 9, ClusterPos: 3})
+	if c.get(RID{ClusterID: 9, ClusterPos: 3}) != nil {
+		t.Fatal("record is not removed")
+	}
+	c.put(NewDocument("V")) // not persistent
+	if c.lru.Len() != 1 || len(c.items) != 1 {
+		t.Fatalf("unexpected cache size: %d", c.lru.Len())
+	}
+}
+
+func TestRecordCacheVersions(t *testing.T) {
+	c := newRecordCache(10)
+	rid := RID{ClusterID: 9, ClusterPos: 1}
+	c.put(cachedDoc(1, 3))
+	c.put(cachedDoc(1, 2))
+	if doc := c.get(rid); doc == nil || doc.Vers != 3 {
+		t.Fatalf("newer version is replaced: %v", doc)
+	}
+	c.put(cachedDoc(1, 4))
+	if doc := c.get(rid); doc == nil || doc.Vers != 4 {
+		t.Fatalf("older version is not replaced: %v", doc)
+	}
+	c.ttl = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if c.get(rid) != nil {
+		t.Fatal("stale record returned")
+	}
+}
+
+func TestRecordCacheCopies(t *testing.T) {
+	c := newRecordCache(10)
+	rid := RID{ClusterID: 9, ClusterPos: 1}
+	doc := cachedDoc(1, 1)
+	c.put(doc)
+	doc.SetField("pos", int64(2))
+	a := c.get(rid)
+	if a == nil || a == doc {
+		t.Fatalf("expected a copy of cached document, got: %p", a)
+	} else if v := a.GetField("pos").Value; v != int64(1) {
+		t.Fatalf("cached document was changed: %v", v)
+	}
+	a.SetField("pos", int64(3))
+	if v := c.get(rid).GetField("pos").Value; v != int64(1) {
+		t.Fatalf("cached document was changed by a caller: %v", v)
+	}
+	c.put(NewDocumentFromRID(rid)) // no serialized content
+	if c.get(rid) != nil {
+		t.Fatal("document without content is not evicted")
+	}
+}
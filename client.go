@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -60,17 +61,38 @@ const (
 //
 //		import _  "gopkg.in/istreamdata/orientgo.v2/obinary"
 //
-// Address must be in host:port format. Connection to OrientDB cluster is not supported yet.
+// Address must be in host:port format. Use DialCluster to connect to OrientDB cluster.
 //
 // Returned Client uses connection pool under the hood, so it can be shared between goroutines.
-func Dial(addr string) (*Client, error) {
+//
+// Additional connection settings can be passed as options:
+//
+//		cli, err := orient.Dial(addr, orient.WithTLS(&tls.Config{RootCAs: pool}))
+//
+func Dial(addr string, opts ...DialOption) (*Client, error) {
+	return DialCluster([]string{addr}, opts...)
+}
+
+// DialCluster creates a client for OrientDB cluster with given node addresses. Each new connection is opened
+// to the first available node, in the order selected by node policy (see WithNodePolicy).
+//
+// Broken connections are replaced with connections to other nodes, so the client fails over to the rest
+// of the cluster if a node goes down. Database sessions are opened again on a new node with the same
+// credentials, and idempotent requests are retried according to the ReconnectPolicy.
+func DialCluster(addrs []string, opts ...DialOption) (*Client, error) {
 	dial := protos[ProtoBinary]
 	if dial == nil {
 		return nil, fmt.Errorf("orientgo: no protocols are active; forgot to import obinary package?")
+	} else if len(addrs) == 0 {
+		return nil, fmt.Errorf("orientgo: no server addresses")
 	}
+	o := newDialOptions(opts)
+	addrs = append([]string(nil), addrs...)
 	cli := &Client{
 		dial: func() (DBConnection, error) {
-			return dial(addr)
+			return dialNodes(addrs, o.NodePolicy, func(addr string) (DBConnection, error) {
+				return dial(addr, o)
+			})
 		},
 	}
 	conn, err := cli.dial()
@@ -81,6 +103,21 @@ func Dial(addr string) (*Client, error) {
 	return cli, nil
 }
 
+// ConnectServer dials the server and opens a database management session. It's a shorthand for Dial and Auth.
+// Closing returned Admin also closes the connection.
+func ConnectServer(addr, user, pass string, opts ...DialOption) (*Admin, error) {
+	cli, err := Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := cli.Auth(user, pass)
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+	return admin, nil
+}
+
 func newConnPool(size int, dial func() (DBSession, error)) *connPool {
 	if size == 0 {
 		size = MaxConnections
@@ -101,9 +138,10 @@ func newConnPool(size int, dial func() (DBSession, error)) *connPool {
 }
 
 type connPool struct {
-	dial func() (DBSession, error)
-	ch   chan DBSession
-	toks chan struct{}
+	dial    func() (DBSession, error)
+	ch      chan DBSession
+	toks    chan struct{}
+	onClose func(conn DBSession) // called for each session closed by the pool
 }
 
 func (p *connPool) getConn() (DBSession, error) {
@@ -136,7 +174,24 @@ func (p *connPool) putConn(conn DBSession) {
 			default:
 			}
 		}
-		conn.Close()
+		p.close(conn)
+	}
+}
+// discard closes a broken connection and frees it's slot in the pool.
+func (p *connPool) discard(conn DBSession) {
+	p.close(conn)
+	if p.toks != nil {
+		select {
+		case p.toks <- struct{}{}:
+		default:
+		}
+	}
+}
+// close closes a session and reports it to onClose.
+func (p *connPool) close(conn DBSession) {
+	conn.Close()
+	if p.onClose != nil {
+		p.onClose(conn)
 	}
 }
 func (p *connPool) clear() {
@@ -145,7 +200,7 @@ loop:
 		select {
 		case conn := <-p.ch:
 			if conn != nil {
-				conn.Close()
+				p.close(conn)
 			}
 		case <-p.toks:
 		default:
@@ -192,22 +247,28 @@ func (s sessionAndConn) Close() error {
 	return err
 }
 
+// openSess opens a new database session on a dedicated server connection.
+func (c *Client) openSess(name string, dbType DatabaseType, user, pass string) (DBSession, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	ds, err := conn.Open(name, dbType, user, pass)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return sessionAndConn{DBSession: ds, conn: conn}, nil
+}
+
 // Open initiates a new database session, allowing to make queries to selected database.
 //
 // For database management use Auth instead.
 func (c *Client) Open(name string, dbType DatabaseType, user, pass string) (*Database, error) {
 	db := &Database{pool: newConnPool(0, func() (DBSession, error) {
-		conn, err := c.dial()
-		if err != nil {
-			return nil, err
-		}
-		ds, err := conn.Open(name, dbType, user, pass)
-		if err != nil {
-			conn.Close()
-			return nil, err
-		}
-		return sessionAndConn{DBSession: ds, conn: conn}, nil
-	}), cli: c}
+		return c.openSess(name, dbType, user, pass)
+	}), cli: c, reconnect: DefaultReconnectPolicy}
+	db.pool.onClose = db.forgetSchema
 	conn, err := db.pool.getConn()
 	if err != nil {
 		return nil, err
@@ -236,13 +297,22 @@ func (a *Admin) DatabaseExists(name string, storageType StorageType) (bool, erro
 }
 
 // CreateDatabase creates a new database with given database type (Document or Graph) and storage type (Persistent or Volatile).
+// If database already exists, ErrDatabaseExists is returned.
 func (a *Admin) CreateDatabase(name string, dbType DatabaseType, storageType StorageType) error {
-	return a.db.CreateDatabase(name, dbType, storageType)
+	err := a.db.CreateDatabase(name, dbType, storageType)
+	if serverErrorContains(err, "already exists") {
+		return ErrDatabaseExists{Name: name}
+	}
+	return err
 }
 
-// DropDatabase removes database from the server.
+// DropDatabase removes database from the server. If database does not exist, ErrDatabaseNotFound is returned.
 func (a *Admin) DropDatabase(name string, storageType StorageType) error {
-	return a.db.DropDatabase(name, storageType)
+	err := a.db.DropDatabase(name, storageType)
+	if serverErrorContains(err, "does not exist", "not exists") {
+		return ErrDatabaseNotFound{Name: name}
+	}
+	return err
 }
 
 // ListDatabases returns a list of databases in a form:
@@ -253,6 +323,17 @@ func (a *Admin) ListDatabases() (map[string]string, error) {
 	return a.db.ListDatabases()
 }
 
+// DatabaseList is the same as ListDatabases.
+func (a *Admin) DatabaseList() (map[string]string, error) {
+	return a.db.ListDatabases()
+}
+
+// ProtocolVersion returns a version of binary protocol used by the server, for example 36 for OrientDB 2.2.
+// The protocol does not report OrientDB release, but features of the server depend on this version.
+func (a *Admin) ProtocolVersion() (int, error) {
+	return a.db.ProtocolVersion()
+}
+
 // Close closes DB management session.
 func (a *Admin) Close() error {
 	return a.db.Close()
@@ -262,44 +343,115 @@ func (a *Admin) Close() error {
 type Database struct {
 	pool *connPool
 	cli  *Client
+
+	mu        sync.Mutex // protects settings
+	reconnect ReconnectPolicy
+	timeout   time.Duration
+	cache     *recordCache
+	hooks     Hooks
+	fetchPlan FetchPlan
+	intType   OType
+	ddl       DDLDetector
+	names     *FieldMapping
+	workers   int // goroutines for parallel decoding
+	threshold int // minimal number of records for parallel decoding
+
+	schemamu   sync.Mutex
+	schemaGen  uint64               // incremented by InvalidateSchema
+	schemaSeen map[DBSession]uint64 // session -> schemaGen of the last reload
+
+	livemu sync.Mutex
+	live   map[int]DBSession // live query token -> dedicated connection
 }
 
 // Size return the size of current database (in bytes).
 func (db *Database) Size() (int64, error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, err
-	}
-	defer db.pool.putConn(conn)
-	return conn.Size()
+	var n int64
+	err := db.withConn(true, func(conn DBSession) (err error) {
+		n, err = conn.Size()
+		return
+	})
+	return n, err
 }
 
 // Close closes database session.
 func (db *Database) Close() error {
 	if db != nil && db.pool != nil {
+		db.closeLive()
 		db.pool.clear()
+		db.schemamu.Lock()
+		db.schemaSeen = nil
+		db.schemamu.Unlock()
 	}
 	return nil
 }
 
 // ReloadSchema reloads documents schema from database.
 func (db *Database) ReloadSchema() error {
+	return db.withConn(true, func(conn DBSession) error {
+		return conn.ReloadSchema()
+	})
+}
+
+// GetCurDB returns database metadata
+func (db *Database) GetCurDB() *ODatabase {
 	conn, err := db.pool.getConn()
 	if err != nil {
-		return err
+		return nil
 	}
 	defer db.pool.putConn(conn)
-	return conn.ReloadSchema()
+	return conn.GetCurDB()
 }
 
-// GetCurDB returns database metadata
-func (db *Database) GetCurDB() *ODatabase {
+// ProtocolVersion returns a binary protocol version negotiated with the server. It can be used to check
+// if server supports certain features. Zero is returned if connection cannot be established.
+func (db *Database) ProtocolVersion() int16 {
+	conn, err := db.pool.getConn()
+	if err != nil {
+		return 0
+	}
+	defer db.pool.putConn(conn)
+	return conn.ProtocolVersion()
+}
+
+// SessionToken returns a token of database session, if connection was opened with WithTokenSession option,
+// or nil otherwise. Each pooled connection has it's own session, so the token of one of them is returned.
+// Token can be used by custom transports to send requests on behalf of this session.
+func (db *Database) SessionToken() []byte {
 	conn, err := db.pool.getConn()
 	if err != nil {
 		return nil
 	}
 	defer db.pool.putConn(conn)
-	return conn.GetCurDB()
+	return conn.SessionToken()
+}
+
+var _ RidBagTreeReader = (*Database)(nil)
+
+// RidBagFirstKey returns the first link of a tree-based RidBag. It's used by RidBag.Iterate.
+func (db *Database) RidBagFirstKey(ptr RidBagPointer) (rid RID, ok bool, err error) {
+	err = db.withConn(true, func(conn DBSession) (err error) {
+		r, ok2 := conn.(RidBagTreeReader)
+		if !ok2 {
+			return fmt.Errorf("tree-based RidBags are not supported by %T", conn)
+		}
+		rid, ok, err = r.RidBagFirstKey(ptr)
+		return
+	})
+	return
+}
+
+// RidBagEntriesMajor returns a page of entries of a tree-based RidBag. It's used by RidBag.Iterate.
+func (db *Database) RidBagEntriesMajor(ptr RidBagPointer, key RID, inclusive bool, pageSize int) (out []RidBagEntry, err error) {
+	err = db.withConn(true, func(conn DBSession) (err error) {
+		r, ok := conn.(RidBagTreeReader)
+		if !ok {
+			return fmt.Errorf("tree-based RidBags are not supported by %T", conn)
+		}
+		out, err = r.RidBagEntriesMajor(ptr, key, inclusive, pageSize)
+		return
+	})
+	return
 }
 
 // AddCluster creates new cluster with given name and returns its ID.
@@ -309,119 +461,366 @@ func (db *Database) AddCluster(name string) (int16, error) {
 
 // AddClusterWithID creates new cluster with given cluster position and name
 func (db *Database) AddClusterWithID(name string, clusterID int16) (int16, error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, err
-	}
-	defer db.pool.putConn(conn)
-	return conn.AddClusterWithID(name, clusterID)
+	var id int16
+	err := db.withConn(false, func(conn DBSession) (err error) {
+		id, err = conn.AddClusterWithID(name, clusterID)
+		return
+	})
+	return id, err
 }
 
 // DropCluster deletes cluster from database
 func (db *Database) DropCluster(name string) error {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return err
-	}
-	defer db.pool.putConn(conn)
-	return conn.DropCluster(name)
+	return db.withConn(false, func(conn DBSession) error {
+		return conn.DropCluster(name)
+	})
 }
 
 // GetClusterDataRange returns the begin and end positions of data in the requested cluster.
 func (db *Database) GetClusterDataRange(clusterName string) (begin, end int64, err error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, 0, err
-	}
-	defer db.pool.putConn(conn)
-	return conn.GetClusterDataRange(clusterName)
+	err = db.withConn(true, func(conn DBSession) (err error) {
+		begin, end, err = conn.GetClusterDataRange(clusterName)
+		return
+	})
+	return
+}
+
+// ClusterInfo describes a single record cluster of the database.
+type ClusterInfo struct {
+	Name string
+	ID   int16
+	// Type is a cluster type, such as PHYSICAL or MEMORY. Binary protocol used for OrientDB 2.x
+	// does not report cluster types, so it is empty for these servers.
+	Type    string
+	Records int64 // number of records in cluster, not including deleted ones
+}
+
+// Clusters returns a list of database clusters with their records count.
+func (db *Database) Clusters() ([]ClusterInfo, error) {
+	var clusters []ClusterInfo
+	err := db.withConn(true, func(conn DBSession) (err error) {
+		clusters, err = conn.Clusters()
+		return
+	})
+	return clusters, err
+}
+
+// CountCluster returns the number of records in a given cluster.
+func (db *Database) CountCluster(name string) (int64, error) {
+	return db.ClustersCount(false, name)
 }
 
 // ClustersCount returns total count of records in given clusters
 func (db *Database) ClustersCount(withDeleted bool, clusterNames ...string) (int64, error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, err
-	}
-	defer db.pool.putConn(conn)
-	return conn.ClustersCount(withDeleted, clusterNames...)
+	var n int64
+	err := db.withConn(true, func(conn DBSession) (err error) {
+		n, err = conn.ClustersCount(withDeleted, clusterNames...)
+		return
+	})
+	return n, err
 }
 
 // CreateRecord saves a record to the database. Record RID and version will be changed.
 func (db *Database) CreateRecord(rec ORecord) error {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return err
+	err := db.withConn(false, func(conn DBSession) error {
+		return conn.CreateRecord(rec)
+	})
+	if err == nil {
+		db.cacheRecord(rec)
 	}
-	defer db.pool.putConn(conn)
-	return conn.CreateRecord(rec)
+	return err
 }
 
 // DeleteRecordByRID removes a record from database
 func (db *Database) DeleteRecordByRID(rid RID, recVersion int) error {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return err
+	err := db.withConn(false, func(conn DBSession) error {
+		return conn.DeleteRecordByRID(rid, recVersion)
+	})
+	db.evictRecord(rid)
+	return convertError(err)
+}
+
+// Delete removes a record regardless of it's version. If record does not exist, ErrRecordNotFound is returned.
+func (db *Database) Delete(rid RID) error {
+	return db.DeleteRecordByRID(rid, -1)
+}
+
+// DeleteDocument removes a document, if it's version in database matches the version of the document.
+// Otherwise ErrConcurrentModification is returned. If record does not exist, ErrRecordNotFound is returned.
+func (db *Database) DeleteDocument(doc *Document) error {
+	if doc == nil {
+		return fmt.Errorf("document is nil")
 	}
-	defer db.pool.putConn(conn)
-	return conn.DeleteRecordByRID(rid, recVersion)
+	return db.DeleteRecordByRID(doc.GetIdentity(), doc.Version())
 }
 
 // GetRecordByRID returns a record using specified fetch plan. If ignoreCache is set to true implementations will
 // not use local records cache and will fetch record from database.
+//
+// If record cache is enabled (see EnableRecordCache), cached document is returned for an empty fetch plan.
 func (db *Database) GetRecordByRID(rid RID, fetchPlan FetchPlan, ignoreCache bool) (ORecord, error) {
-	conn, err := db.pool.getConn()
+	if c := db.recordCache(); c != nil && !ignoreCache && fetchPlan == "" {
+		if doc := c.get(rid); doc != nil {
+			return doc, nil
+		}
+	}
+	var rec ORecord
+	err := db.withConn(true, func(conn DBSession) (err error) {
+		rec, err = conn.GetRecordByRID(rid, fetchPlan, ignoreCache)
+		return
+	})
+	if err == nil {
+		db.cacheRecord(rec)
+	}
+	return rec, err
+}
+
+// Load reads a document by RID using a given fetch plan (for example "*:2" or "out_*:-1") and returns it
+// together with related documents pre-fetched by the server in the same request. Default fetch plan of the
+// database is used if fetchPlan is empty (see SetDefaultFetchPlan). Use PrefetchedLoader
+// to resolve links of the document from the pre-fetched set.
+func (db *Database) Load(rid RID, fetchPlan FetchPlan) (*Document, []*Document, error) {
+	if fetchPlan == "" {
+		fetchPlan = db.defaultFetchPlan()
+	}
+	if fetchPlan == "" {
+		rec, err := db.GetRecordByRID(rid, "", false)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc, err := loadedDocument(rid, rec)
+		return doc, nil, err
+	}
+	var (
+		rec     ORecord
+		related []ORecord
+	)
+	err := db.withConn(true, func(conn DBSession) (err error) {
+		rec, related, err = conn.LoadRecord(rid, fetchPlan, false)
+		return
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	doc, err := loadedDocument(rid, rec)
+	if err != nil {
+		return nil, nil, err
+	}
+	db.cacheRecord(doc)
+	docs := make([]*Document, 0, len(related))
+	for _, r := range related {
+		if d, ok := r.(*Document); ok {
+			db.cacheRecord(d)
+			docs = append(docs, d)
+		}
+	}
+	return doc, docs, nil
+}
+
+func loadedDocument(rid RID, rec ORecord) (*Document, error) {
+	if rec == nil {
+		return nil, ErrNoRecord
+	}
+	doc, ok := rec.(*Document)
+	if !ok {
+		return nil, fmt.Errorf("record %v is not a document: %T", rid, rec)
+	}
+	return doc, nil
+}
+
+// CreateBlob stores raw bytes as a new blob record (record type 'b') and returns it's RID.
+// Data is sent as-is, without using a document serializer.
+//
+// Binary protocol transfers records as a single chunk, so the whole blob is kept in memory.
+func (db *Database) CreateBlob(data []byte) (RID, error) {
+	if data == nil {
+		data = []byte{}
+	}
+	rec := &BytesRecord{RID: NewEmptyRID(), Data: data}
+	if err := db.CreateRecord(rec); err != nil {
+		return NewEmptyRID(), err
+	}
+	return rec.RID, nil
+}
+
+// LoadBlob reads the content of a blob record (record type 'b') created by CreateBlob.
+// An error is returned if record with given RID is not a blob.
+func (db *Database) LoadBlob(rid RID) ([]byte, error) {
+	rec, err := db.GetRecordByRID(rid, "", true)
 	if err != nil {
 		return nil, err
 	}
-	defer db.pool.putConn(conn)
-	return conn.GetRecordByRID(rid, fetchPlan, ignoreCache)
+	switch r := rec.(type) {
+	case *BytesRecord:
+		return r.Data, nil
+	case nil:
+		return nil, ErrRecordNotFound{RID: rid}
+	}
+	return nil, fmt.Errorf("record %v is not a blob: %T", rid, rec)
 }
 
 // UpdateRecord updates given record in a database. Record version will be changed after the call.
+// If record was changed concurrently, ErrConcurrentModification is returned.
 func (db *Database) UpdateRecord(rec ORecord) error {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return err
+	err := db.withConn(false, func(conn DBSession) error {
+		return conn.UpdateRecord(rec)
+	})
+	if err == nil {
+		db.cacheRecord(rec)
+	} else {
+		db.evictRecord(rec.GetIdentity())
 	}
-	defer db.pool.putConn(conn)
-	return conn.UpdateRecord(rec)
+	return convertError(err)
+}
+
+// Save creates a document if it's RID is not set or temporary, or updates it otherwise. RID and version
+// of the document are changed after the call. If document was changed concurrently, ErrConcurrentModification is returned.
+func (db *Database) Save(doc *Document) error {
+	if doc == nil {
+		return fmt.Errorf("document is nil")
+	} else if doc.GetIdentity().IsPersistent() {
+		return db.UpdateRecord(doc)
+	}
+	return db.CreateRecord(doc)
 }
 
 // CountRecords returns total records count.
 func (db *Database) CountRecords() (int64, error) {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return 0, err
-	}
-	defer db.pool.putConn(conn)
-	return conn.CountRecords()
+	var n int64
+	err := db.withConn(true, func(conn DBSession) (err error) {
+		n, err = conn.CountRecords()
+		return
+	})
+	return n, err
 }
 
 // Command executes command against current database. Example:
 //
 //		result := db.Command(NewSQLQuery("SELECT FROM V WHERE id = ?", id).Limit(10))
 //
+// Default fetch plan of the database is applied to SQL queries that have no fetch plan (see SetDefaultFetchPlan).
 func (db *Database) Command(cmd OCommandRequestText) Results {
-	conn, err := db.pool.getConn()
-	if err != nil {
-		return errorResult{err: err}
+	if q, ok := cmd.(SQLQuery); ok && q.plan == "" {
+		if plan := db.defaultFetchPlan(); plan != "" {
+			cmd = q.FetchPlan(plan)
+		}
 	}
-	defer db.pool.putConn(conn)
-	var result interface{}
-	for i := 0; concurrentRetries < 0 || i < concurrentRetries; i++ {
-		result, err = conn.Command(cmd)
-		err = convertError(err)
-		switch err.(type) {
-		case ErrConcurrentModification:
-			continue
+	if tp := db.defaultIntType(); tp == INTEGER || tp == LONG {
+		if c, ok := cmd.(intTypeSetter); ok {
+			cmd = c.withIntType(tp)
 		}
-		break
 	}
+	var result interface{}
+	hooks, start := db.getHooks(), time.Now()
+	hooks.commandStart(cmd.GetText())
+	err := db.withConn(isIdempotent(cmd), func(conn DBSession) (err error) {
+		for i := 0; concurrentRetries < 0 || i < concurrentRetries; i++ {
+			result, err = conn.Command(cmd)
+			err = convertError(err)
+			switch err.(type) {
+			case ErrConcurrentModification:
+				continue
+			}
+			break
+		}
+		return err
+	})
+	err = convertError(err)
+	hooks.commandEnd(cmd.GetText(), start, err)
 	if err != nil {
-		return errorResult{err: convertError(err)}
+		return &errorResult{err: err}
+	}
+	if db.ddlDetector()(cmd) {
+		db.InvalidateSchema()
 	}
-	return newResults(result)
+	res := newLoaderResults(result, db.LinkLoader())
+	res.hooks = hooks
+	res.names = db.fieldMapping()
+	res.workers, res.threshold = db.parallelDecode()
+	if DetectResultLeaks {
+		res.trackLeaks(cmd.GetText(), 1)
+	}
+	return res
+}
+
+// CommandLang executes a command written in a given language and returns it's results. SQL text is sent as
+// SQLCommand, Gremlin as GremlinCommand, and other languages (like JavaScript) as ScriptCommand.
+func (db *Database) CommandLang(lang ScriptLang, text string, params ...interface{}) Results {
+	switch lang {
+	case LangSQL:
+		return db.Command(NewSQLCommand(text, params...))
+	case LangGremlin:
+		return db.Command(NewGremlinCommand(text, params...))
+	case "":
+		return &errorResult{err: fmt.Errorf("no language provided for command")}
+	}
+	return db.Command(NewScriptCommand(lang, text, params...))
+}
+
+// SetCommandTimeout sets a timeout for each request to the database. Requests that are not completed in time
+// return ErrCommandTimeout. Zero means no timeout.
+func (db *Database) SetCommandTimeout(d time.Duration) {
+	db.mu.Lock()
+	db.timeout = d
+	db.mu.Unlock()
+}
+
+func (db *Database) commandTimeout() time.Duration {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.timeout
+}
+
+// SetDefaultFetchPlan sets a fetch plan for Load calls and SQL queries that have no fetch plan of their own.
+// Plans set for a single call override the default one; NoFollow can be used to skip fetching for a call.
+// Empty plan removes the default.
+func (db *Database) SetDefaultFetchPlan(plan FetchPlan) {
+	db.mu.Lock()
+	db.fetchPlan = plan
+	db.mu.Unlock()
+}
+
+func (db *Database) defaultFetchPlan() FetchPlan {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.fetchPlan
+}
+
+// SetDefaultIntType sets a type used to send command parameters of Go int and uint types. It can be INTEGER
+// or LONG, and should match the type of indexed properties compared with such parameters, otherwise index
+// may not be used. Other types restore the default: LONG on 64-bit platforms, and INTEGER on 32-bit ones.
+// Use TypedParam to set a type of a single parameter.
+func (db *Database) SetDefaultIntType(tp OType) {
+	db.mu.Lock()
+	db.intType = tp
+	db.mu.Unlock()
+}
+
+func (db *Database) defaultIntType() OType {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.intType
+}
+
+// defaultParallelDecodeThreshold is a minimal number of records to decode in parallel, if threshold is not set.
+const defaultParallelDecodeThreshold = 1000
+
+// SetParallelDecode enables decoding of large result sets into slices with a few goroutines: up to workers
+// goroutines are used for results with at least threshold records. Order of records is preserved.
+// Workers less than 2 disable parallel decoding, which is the default. Zero or negative threshold means 1000 records.
+func (db *Database) SetParallelDecode(workers, threshold int) {
+	if threshold <= 0 {
+		threshold = defaultParallelDecodeThreshold
+	}
+	db.mu.Lock()
+	db.workers, db.threshold = workers, threshold
+	db.mu.Unlock()
+}
+
+func (db *Database) parallelDecode() (workers, threshold int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.workers, db.threshold
 }
 
 func sqlEscape(s string) string { // TODO: get rid of it
@@ -0,0 +1,57 @@
+package orient
+
+import (
+	"fmt"
+	"testing"
+)
+
+type langSession struct {
+	fakeSession
+	classes []string
+}
+
+func (s *langSession) Command(cmd CustomSerializable) (interface{}, error) {
+	s.classes = append(s.classes, cmd.GetClassName())
+	return nil, nil
+}
+
+func TestCommandLang(t *testing.T) {
+	sess := &langSession{}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	for _, lang := range []ScriptLang{LangSQL, LangGremlin, LangJS} {
+		if err := db.CommandLang(lang, "text").Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := []string{"c", "com.orientechnologies.orient.graph.gremlin.OCommandGremlin", "s"}
+	if fmt.Sprint(sess.classes) != fmt.Sprint(exp) {
+		t.Fatalf("wrong command classes: %q", sess.classes)
+	}
+	if err := db.CommandLang("", "text").Err(); err == nil {
+		t.Fatal("expected an error for empty language")
+	}
+}
+
+func TestSetParallelDecode(t *testing.T) {
+	sess := &cmdSession{result: benchRecords(300)}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	other := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	db.SetParallelDecode(4, 0)
+	if w, th := db.parallelDecode(); w != 4 || th != defaultParallelDecodeThreshold {
+		t.Fatalf("wrong settings: %d, %d", w, th)
+	}
+	db.SetParallelDecode(4, 100)
+	res := db.Command(NewSQLQuery("SELECT FROM V")).(*unknownResult)
+	if res.workers != 4 || res.threshold != 100 {
+		t.Fatalf("settings are not passed to results: %d, %d", res.workers, res.threshold)
+	}
+	var items []benchItem
+	if err := res.All(&items); err != nil {
+		t.Fatal(err)
+	} else if len(items) != 300 || items[299].ID != 299 {
+		t.Fatalf("wrong items: %d", len(items))
+	}
+	if res = other.Command(NewSQLQuery("SELECT FROM V")).(*unknownResult); res.workers != 0 {
+		t.Fatalf("settings leaked to other database: %d", res.workers)
+	}
+}
@@ -694,6 +694,25 @@ func TestRecordBytes(t *testing.T) {
 	Equals(t, newbindata, recFromQuery.Data)
 }
 
+func TestBlob(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	data := make([]byte, 1024*1024)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	rid, err := db.CreateBlob(data)
+	Nil(t, err)
+	True(t, rid.IsPersistent(), "RID should be filled in")
+
+	out, err := db.LoadBlob(rid)
+	Nil(t, err)
+	Equals(t, data, out)
+}
+
 func TestCommandsNativeAPI(t *testing.T) {
 	notShort(t)
 	db, closer := SpinOrientAndOpenDB(t, false)
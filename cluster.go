@@ -0,0 +1,50 @@
+package orient
+
+import (
+	"sync/atomic"
+)
+
+// NodePolicy returns addresses of cluster nodes in the order they should be tried when a new connection is opened.
+type NodePolicy func(addrs []string) []string
+
+// FirstAvailable is a node policy that tries nodes in the given order, so connections are opened to the first
+// node that is up. Other nodes are used only for failover.
+func FirstAvailable(addrs []string) []string {
+	return addrs
+}
+
+// RoundRobin returns a node policy that starts from the next node for each new connection,
+// spreading connections across the cluster.
+func RoundRobin() NodePolicy {
+	var n uint32
+	return func(addrs []string) []string {
+		i := int((atomic.AddUint32(&n, 1) - 1) % uint32(len(addrs)))
+		return append(append(make([]string, 0, len(addrs)), addrs[i:]...), addrs[:i]...)
+	}
+}
+
+// WithNodePolicy sets a policy for selecting cluster nodes for new connections. See DialCluster.
+func WithNodePolicy(p NodePolicy) DialOption {
+	return func(o *DialOptions) {
+		o.NodePolicy = p
+	}
+}
+
+// dialNodes opens a connection to the first available node.
+func dialNodes(addrs []string, policy NodePolicy, dial func(addr string) (DBConnection, error)) (DBConnection, error) {
+	if policy == nil {
+		policy = FirstAvailable
+	}
+	if len(addrs) == 1 { // keep original error for a single server
+		return dial(addrs[0])
+	}
+	errs := make(map[string]error)
+	for _, addr := range policy(addrs) {
+		conn, err := dial(addr)
+		if err == nil {
+			return conn, nil
+		}
+		errs[addr] = err
+	}
+	return nil, ErrNoNodes{Errs: errs}
+}
@@ -0,0 +1,41 @@
+package orient
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRoundRobin(t *testing.T) {
+	p := RoundRobin()
+	addrs := []string{"a", "b", "c"}
+	for _, expect := range [][]string{{"a", "b", "c"}, {"b", "c", "a"}, {"c", "a", "b"}, {"a", "b", "c"}} {
+		if got := p(addrs); !reflect.DeepEqual(got, expect) {
+			t.Fatalf("wrong order: %v, expected %v", got, expect)
+		}
+	}
+}
+
+func TestDialNodesFailover(t *testing.T) {
+	var tried []string
+	dial := func(addr string) (DBConnection, error) {
+		tried = append(tried, addr)
+		if addr != "c" {
+			return nil, fmt.Errorf("%s is down", addr)
+		}
+		return nil, nil
+	}
+	if _, err := dialNodes([]string{"a", "b", "c"}, nil, dial); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(tried, []string{"a", "b", "c"}) {
+		t.Fatalf("wrong nodes were tried: %v", tried)
+	}
+
+	tried = nil
+	_, err := dialNodes([]string{"a", "b"}, RoundRobin(), dial)
+	if e, ok := err.(ErrNoNodes); !ok || len(e.Errs) != 2 {
+		t.Fatalf("expected ErrNoNodes, got: %v", err)
+	} else if e.Error() != "cannot connect to any of cluster nodes: a: a is down; b: b is down" {
+		t.Fatalf("wrong error: %v", e)
+	}
+}
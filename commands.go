@@ -2,8 +2,10 @@ package orient
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"reflect"
+	"time"
 
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
 )
@@ -15,6 +17,7 @@ var (
 	_ OCommandRequestText = SQLCommand{}
 	_ OCommandRequestText = ScriptCommand{}
 	_ OCommandRequestText = FunctionCommand{}
+	_ OCommandRequestText = GremlinCommand{}
 )
 
 // OCommandRequestText is an interface for text-based database commands,
@@ -24,18 +27,123 @@ type OCommandRequestText interface {
 	GetText() string
 }
 
-func arrayToParamsMap(params []interface{}) interface{} {
-	if len(params) == 1 && reflect.TypeOf(params[0]).Kind() == reflect.Map {
-		return params[0]
+var reflOIdentifiableType = reflect.TypeOf((*OIdentifiable)(nil)).Elem()
+
+// TypedParam wraps a command parameter to send it with a given type, for example TypedParam(id, LONG),
+// so it matches an index on LONG property. Only types that have a Go representation are supported
+// (see OType.ReflectType).
+func TypedParam(val interface{}, tp OType) interface{} {
+	return typedParam{val: val, tp: tp}
+}
+
+type typedParam struct {
+	val interface{}
+	tp  OType
+}
+
+// convert returns parameter value converted to a Go type that corresponds to parameter type.
+func (p typedParam) convert() (interface{}, error) {
+	t := p.tp.ReflectType()
+	if t == nil || t == reflInterfaceType {
+		return nil, fmt.Errorf("unsupported parameter type: %v", p.tp)
+	} else if p.val == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(p.val)
+	if isNumberKind(rv.Kind()) && isNumberKind(t.Kind()) {
+		v, err := convertNumber(rv, t)
+		if err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	} else if rv.Type().ConvertibleTo(t) {
+		return rv.Convert(t).Interface(), nil
+	}
+	return nil, fmt.Errorf("cannot send %T parameter as %v", p.val, p.tp)
+}
+
+// convertParam prepares a single command parameter for sending to the server.
+// Records are replaced with their RIDs, so they are bound as links instead of embedded documents.
+// Values of int and uint types are sent with intType, if it's INTEGER or LONG, otherwise their type
+// depends on the platform (LONG on 64-bit platforms).
+func convertParam(p interface{}, intType OType) (interface{}, error) {
+	switch v := p.(type) {
+	case nil, RID, []RID, []byte, time.Time:
+		return p, nil
+	case *time.Time:
+		if v == nil {
+			return nil, nil
+		}
+		return *v, nil
+	case OIdentifiable:
+		return v.GetIdentity(), nil // use RID only
+	case typedParam:
+		return v.convert()
+	case int, uint:
+		if intType == INTEGER || intType == LONG {
+			return typedParam{val: p, tp: intType}.convert()
+		}
+		return p, nil
+	}
+	rv := reflect.ValueOf(p)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Implements(reflOIdentifiableType) {
+			rids := make([]RID, rv.Len())
+			for i := range rids {
+				if ide, ok := rv.Index(i).Interface().(OIdentifiable); ok && ide != nil {
+					rids[i] = ide.GetIdentity()
+				} else {
+					rids[i] = nilRID
+				}
+			}
+			return rids, nil
+		} else if rv.Type().Elem().Kind() == reflect.Interface {
+			arr := make([]interface{}, rv.Len())
+			for i := range arr {
+				v, err := convertParam(rv.Index(i).Interface(), intType)
+				if err != nil {
+					return nil, err
+				}
+				arr[i] = v
+			}
+			return arr, nil
+		}
+	}
+	return p, nil
+}
+
+// arrayToParamsMap converts command parameters to a map that is sent to the server.
+//
+// Single map argument is treated as a set of named parameters (":name" placeholders),
+// otherwise arguments are bound to positional "?" placeholders in order.
+func arrayToParamsMap(params []interface{}, intType OType) (interface{}, error) {
+	if len(params) == 1 && params[0] != nil && reflect.TypeOf(params[0]).Kind() == reflect.Map {
+		rv := reflect.ValueOf(params[0])
+		mp := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			v, err := convertParam(rv.MapIndex(k).Interface(), intType)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %v: %v", k.Interface(), err)
+			}
+			mp[fmt.Sprint(k.Interface())] = v
+		}
+		return mp, nil
 	}
 	mp := make(map[int32]interface{}, len(params))
 	for i, p := range params {
-		if ide, ok := p.(OIdentifiable); ok {
-			p = ide.GetIdentity() // use RID only
+		v, err := convertParam(p, intType)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %d: %v", i, err)
 		}
-		mp[int32(i)] = p
+		mp[int32(i)] = v
 	}
-	return mp
+	return mp, nil
+}
+
+// intTypeSetter is implemented by commands with parameters, to apply Database.SetDefaultIntType.
+type intTypeSetter interface {
+	withIntType(tp OType) OCommandRequestText
 }
 
 func newTextReqCommand(text string, params []interface{}) textReqCommand {
@@ -47,16 +155,25 @@ func newTextReqCommand(text string, params []interface{}) textReqCommand {
 // OCommandTextAbstract in Java world.
 type textReqCommand struct {
 	//OCommandReq
-	text   string
-	params []interface{}
+	text    string
+	params  []interface{}
+	intType OType // type of int parameters, see convertParam
+	idemp   idempotency
 }
 
 func (rq textReqCommand) GetText() string {
 	return rq.text
 }
 
+func (rq textReqCommand) idempotency() idempotency {
+	return rq.idemp
+}
+
 func (rq textReqCommand) ToStream(w io.Writer) error {
-	params := arrayToParamsMap(rq.params)
+	params, err := arrayToParamsMap(rq.params, rq.intType)
+	if err != nil {
+		return err
+	}
 	buf := bytes.NewBuffer(nil)
 	doc := NewEmptyDocument()
 	doc.SetField("parameters", params)
@@ -95,6 +212,18 @@ func NewFunctionCommand(name string, params ...interface{}) FunctionCommand {
 	}
 }
 
+// Idempotent marks a function call as safe (or not) to retry on a broken connection.
+// Function calls are not retried by default.
+func (rq FunctionCommand) Idempotent(v bool) FunctionCommand {
+	rq.idemp = markIdempotent(v)
+	return rq
+}
+
+func (rq FunctionCommand) withIntType(tp OType) OCommandRequestText {
+	rq.intType = tp
+	return rq
+}
+
 // GetClassName returns Java class name
 func (rq FunctionCommand) GetClassName() string {
 	return "com.orientechnologies.orient.core.command.script.OCommandFunction"
@@ -125,6 +254,18 @@ func NewScriptCommand(lang ScriptLang, body string, params ...interface{}) Scrip
 // GetClassName returns Java class name
 func (rq ScriptCommand) GetClassName() string { return "s" }
 
+// Idempotent marks a script as safe (or not) to retry on a broken connection.
+// Scripts are not retried by default.
+func (rq ScriptCommand) Idempotent(v bool) ScriptCommand {
+	rq.idemp = markIdempotent(v)
+	return rq
+}
+
+func (rq ScriptCommand) withIntType(tp OType) OCommandRequestText {
+	rq.intType = tp
+	return rq
+}
+
 // ToStream serializes command to specified Writer
 func (rq ScriptCommand) ToStream(w io.Writer) error {
 	if err := rw.NewWriter(w).WriteString(rq.lang); err != nil {
@@ -133,6 +274,40 @@ func (rq ScriptCommand) ToStream(w io.Writer) error {
 	return rq.textReqCommand.ToStream(w)
 }
 
+// GremlinCommand is a Gremlin traversal. Requires Gremlin support to be enabled on the server.
+//
+// OCommandGremlin in Java world.
+type GremlinCommand struct {
+	textReqCommand
+}
+
+// NewGremlinCommand creates a new Gremlin command with given params.
+//
+// Example:
+//
+//		NewGremlinCommand("g.v('#9:1').out('Friend')")
+//
+func NewGremlinCommand(text string, params ...interface{}) GremlinCommand {
+	return GremlinCommand{newTextReqCommand(text, params)}
+}
+
+// Idempotent marks a traversal as safe (or not) to retry on a broken connection.
+// Gremlin commands are not retried by default.
+func (rq GremlinCommand) Idempotent(v bool) GremlinCommand {
+	rq.idemp = markIdempotent(v)
+	return rq
+}
+
+func (rq GremlinCommand) withIntType(tp OType) OCommandRequestText {
+	rq.intType = tp
+	return rq
+}
+
+// GetClassName returns Java class name
+func (rq GremlinCommand) GetClassName() string {
+	return "com.orientechnologies.orient.graph.gremlin.OCommandGremlin"
+}
+
 // SQLCommand is a non-SELECT sql command (EXEC/INSERT/DELETE).
 //
 // OCommandSQL in Java world.
@@ -142,9 +317,13 @@ type SQLCommand struct {
 
 // NewSQLCommand creates a new SQL command request with given params.
 //
+// Params are sent to the server separately from the command text. Positional "?" placeholders
+// are bound to params in order, while named ":name" placeholders can be bound with a single map argument.
+//
 // Example:
 //
 //		NewSQLCommand("INSERT INTO People (id, name) VALUES (?, ?)", id, name)
+//		NewSQLCommand("UPDATE People SET name = :name WHERE id = :id", map[string]interface{}{"id": id, "name": name})
 //
 func NewSQLCommand(sql string, params ...interface{}) SQLCommand {
 	return SQLCommand{newTextReqCommand(sql, params)}
@@ -153,21 +332,36 @@ func NewSQLCommand(sql string, params ...interface{}) SQLCommand {
 // GetClassName returns Java class name
 func (rq SQLCommand) GetClassName() string { return "c" }
 
+// Idempotent marks a command as safe (or not) to retry on a broken connection. By default,
+// it's detected with IsIdempotentSQL.
+func (rq SQLCommand) Idempotent(v bool) SQLCommand {
+	rq.idemp = markIdempotent(v)
+	return rq
+}
+
+func (rq SQLCommand) withIntType(tp OType) OCommandRequestText {
+	rq.intType = tp
+	return rq
+}
+
 // SQLQuery is a SELECT-like SQL command.
 //
 // OSQLQuery in Java world.
 type SQLQuery struct {
-	text   string
-	limit  int
-	plan   string
-	params []interface{}
+	text    string
+	limit   int
+	plan    string
+	params  []interface{}
+	intType OType
+	idemp   idempotency
 }
 
-// NewSQLQuery creates a new SQL query with given params.
+// NewSQLQuery creates a new SQL query with given params. See NewSQLCommand for params binding rules.
 //
 // Example:
 //
 //		NewSQLQuery("SELECT FROM V WHERE id = ?", id)
+//		NewSQLQuery("SELECT FROM V WHERE id = :id", map[string]interface{}{"id": id})
 //
 func NewSQLQuery(sql string, params ...interface{}) SQLQuery {
 	return SQLQuery{text: sql, params: params, limit: -1}
@@ -191,6 +385,21 @@ func (rq SQLQuery) FetchPlan(plan FetchPlan) SQLQuery {
 	return rq
 }
 
+// Idempotent marks a query as safe (or not) to retry on a broken connection. Queries are retried by default.
+func (rq SQLQuery) Idempotent(v bool) SQLQuery {
+	rq.idemp = markIdempotent(v)
+	return rq
+}
+
+func (rq SQLQuery) idempotency() idempotency {
+	return rq.idemp
+}
+
+func (rq SQLQuery) withIntType(tp OType) OCommandRequestText {
+	rq.intType = tp
+	return rq
+}
+
 // ToStream serializes command to specified Writer
 func (rq SQLQuery) ToStream(w io.Writer) error {
 	sparams, err := rq.serializeQueryParameters(rq.params)
@@ -209,7 +418,11 @@ func (rq SQLQuery) serializeQueryParameters(params []interface{}) ([]byte, error
 		return nil, nil
 	}
 	doc := NewEmptyDocument()
-	doc.SetField("params", arrayToParamsMap(params)) // TODO: convertToRIDsIfPossible
+	mp, err := arrayToParamsMap(params, rq.intType)
+	if err != nil {
+		return nil, err
+	}
+	doc.SetField("params", mp)
 	buf := bytes.NewBuffer(nil)
 	if err := GetDefaultRecordSerializer().ToStream(buf, doc); err != nil {
 		return nil, err
@@ -1,16 +1,25 @@
 package orient
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"runtime"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
 )
 
 var (
-	_ Results = errorResult{}
+	_ Results = (*errorResult)(nil)
 	_ Results = (*unknownResult)(nil)
 )
 
 // Results is an interface for database command results. Must be closed.
+// Calling Close more than once, or calling Next or All after Close, is safe and returns ErrResultsClosed.
+// Set DetectResultLeaks to find results that are not closed.
 //
 // Individual results can be iterated in a next way:
 //
@@ -33,10 +42,21 @@ var (
 //			// handle command and/or type conversion errors
 //		}
 //
-// Some commands may return just one int/bool value:
+// Some commands may return just one int/bool value. For example, UPDATE and DELETE commands return
+// the number of affected records, unless they are asked to return records instead:
 //
 //		var affected int
-//		results.All(&affected)
+//		err := results.All(&affected) // returns ErrNoCount if the command returned records
+//
+// Rows of MATCH queries hold links to matched records under pattern aliases. Links that were not fetched
+// are loaded lazily when decoded into documents, or with LoadLinks when decoded into structs, so a row can be
+// decoded into map[string]*Document, or into a struct with fields named after aliases:
+//
+//		var rows []struct {
+//			Person Person
+//			Friend Person
+//		}
+//		err := db.Command(NewSQLQuery(`MATCH {class: Person, as: person}-Friend->{as: friend} RETURN person, friend`)).LoadLinks(1).All(&rows)
 //
 // Also results can be handled manually:
 //
@@ -45,53 +65,238 @@ var (
 //		switch out.(type) {
 //		case []OIdentifiable:
 //			// ...
-//		case *DocumentRecord:
+//		case *Document:
 //			// ...
 //		}
 //
+// Values decoded into interface{} (including interface{} struct fields and slice elements)
+// keep their native representation. Result sets are returned as []OIdentifiable, single records
+// as *Document, and values of document fields have the following types:
+//
+//		BOOLEAN                 bool
+//		BYTE                    byte
+//		SHORT, INTEGER, LONG    int16, int32, int64
+//		FLOAT, DOUBLE           float32, float64
+//		DECIMAL                 Decimal
+//		STRING                  string
+//		BINARY                  []byte
+//		DATE, DATETIME          time.Time
+//		EMBEDDED                *Document
+//		EMBEDDEDLIST, -SET      []interface{}
+//		EMBEDDEDMAP             map[string]T, where T is interface{} for values of different types
+//		LINK                    RID, or *Document if the record was fetched
+//		LINKLIST, LINKSET       []OIdentifiable
+//		LINKMAP                 map[string]OIdentifiable
+//		LINKBAG                 *RidBag
+//		null                    nil
+//
+// Values of map[string]interface{} targets are the exception: records and their fields are converted recursively
+// to plain Go values, so the result can be encoded to JSON as is. Embedded documents and LINKMAP fields become
+// map[string]interface{}, collections become []interface{}, and embedded LINKBAGs become lists of RIDs:
+//
+//		var rows []map[string]interface{}
+//		err := results.All(&rows)
+//
+// LINKMAP fields can also be decoded into map[string]RID, or into map[string]*Document, where links that were
+// not fetched become documents that are loaded lazily.
+//
+// Large result sets can be processed record by record with Stream:
+//
+//		ch := make(chan SomeStruct)
+//		go func() {
+//			errc <- results.Stream(ch, done)
+//		}()
+//		for one := range ch {
+//			// process result
+//		}
+//
 type Results interface {
 	Err() error
 	Close() error
 	Next(result interface{}) bool
 	All(result interface{}) error
+	// Stream decodes records one by one and sends them to ch, which must be a channel of the target type.
+	// Channel is closed when all records are sent or an error occurs. Stream blocks until each record is received,
+	// or until done is closed, in which case remaining records are skipped and nil is returned. Done can be nil.
+	Stream(ch interface{}, done <-chan struct{}) error
+	// NextDocument returns the next record as a document, without any type conversion. Links that were not
+	// fetched are loaded, if results have a link loader. It returns false when there are no more records,
+	// or if the result is not a record, in which case Err is set.
+	NextDocument() (*Document, bool)
+	// Count returns a single numeric value from the result, like the one returned by SELECT count(*)
+	// or by commands that return a number of affected records.
+	Count() (int64, error)
+	// Take decodes at most n first records into a slice, other records are discarded. Records are read
+	// from the server at once, so it does not reduce network traffic, use LIMIT in query for this.
+	Take(n int, result interface{}) error
+	// LoadLinks enables loading of links that were not fetched when they are decoded into structs, pointers
+	// to structs or maps, up to depth levels from returned records, with a separate request for each link.
+	// Deeper links are left empty. By default, such links are not loaded and cause decoding errors; use LazyLink
+	// fields to load links on demand, or a fetch plan to read linked records together with results.
+	LoadLinks(depth int) Results
 }
 
 // errorResult is a simple result type that returns one specific error. Useful for server-side errors.
 type errorResult struct {
-	err error
+	err    error
+	closed bool
+}
+
+func (e *errorResult) Err() error { return e.err }
+func (e *errorResult) Close() error {
+	if e.closed {
+		return ErrResultsClosed
+	}
+	e.closed = true
+	return e.err
+}
+func (e *errorResult) Next(result interface{}) bool    { return false }
+func (e *errorResult) NextDocument() (*Document, bool) { return nil, false }
+func (e *errorResult) Count() (int64, error) {
+	if e.closed {
+		return 0, ErrResultsClosed
+	}
+	return 0, e.err
+}
+func (e *errorResult) All(result interface{}) error {
+	if e.closed {
+		return ErrResultsClosed
+	}
+	return e.err
+}
+func (e *errorResult) LoadLinks(depth int) Results { return e }
+func (e *errorResult) Take(n int, result interface{}) error {
+	return e.All(result)
+}
+func (e *errorResult) Stream(ch interface{}, done <-chan struct{}) error {
+	cv, err := sendChan(ch)
+	if err != nil {
+		return err
+	}
+	cv.Close()
+	if e.closed {
+		return ErrResultsClosed
+	}
+	return e.err
 }
 
-func (e errorResult) Err() error                   { return e.err }
-func (e errorResult) Close() error                 { return e.err }
-func (e errorResult) Next(result interface{}) bool { return false }
-func (e errorResult) All(result interface{}) error { return e.err }
+// sendChan checks that ch is a channel that can be used for sending.
+func sendChan(ch interface{}) (reflect.Value, error) {
+	cv := reflect.ValueOf(ch)
+	if cv.Kind() != reflect.Chan || cv.Type().ChanDir()&reflect.SendDir == 0 {
+		return cv, fmt.Errorf("expected a channel for sending, got: %T", ch)
+	}
+	return cv, nil
+}
 
 func newResults(o interface{}) Results {
-	return &unknownResult{result: o}
+	return newLoaderResults(o, nil)
+}
+
+// newLoaderResults wraps command result. Related records from FetchedResult are used to resolve links,
+// other links are loaded with fallback loader, if it's set.
+func newLoaderResults(o interface{}, fallback LinkLoader) *unknownResult {
+	res, ok := o.(FetchedResult)
+	if !ok {
+		return &unknownResult{result: o, loader: fallback}
+	}
+	docs := make([]*Document, 0, len(res.Related))
+	for _, r := range res.Related {
+		if doc, ok := r.(*Document); ok {
+			docs = append(docs, doc)
+		}
+	}
+	return &unknownResult{result: res.Result, loader: PrefetchedLoader(docs, fallback)}
 }
 
 // unknownResult is a generic result type that uses reflection to iterate over returned records
 type unknownResult struct {
-	err    error
-	parsed bool
-	result interface{}
+	err       error
+	parsed    bool
+	closed    bool
+	result    interface{}
+	loader    LinkLoader
+	names     *FieldMapping
+	depth     int // levels of links loaded into structs and maps
+	workers   int // goroutines for parallel decoding (see Database.SetParallelDecode)
+	threshold int // minimal number of records for parallel decoding
+	pos       int // next record for NextDocument
+	hooks     Hooks
+	leak      *leakCheck
 }
 
-func (r *unknownResult) Err() error                     { return r.err }
-func (r *unknownResult) Close() error                   { return r.err }
+func (r *unknownResult) Err() error {
+	r.markUsed()
+	return r.err
+}
+func (r *unknownResult) Close() error {
+	if r.closed {
+		return ErrResultsClosed
+	}
+	if r.leak != nil {
+		runtime.SetFinalizer(r, nil)
+	}
+	r.closed = true
+	r.result = nil
+	return r.err
+}
 func (r *unknownResult) Next(result interface{}) bool { // TODO: implement
-	if r.parsed {
+	if r.parsed || r.closed {
 		return false
 	}
+	r.markIterating()
 	r.parsed = true
 	r.All(result)
 	return false
 }
+func (r *unknownResult) NextDocument() (*Document, bool) {
+	if r.closed || r.err != nil {
+		return nil, false
+	}
+	r.markIterating()
+	var rec interface{}
+	switch res := r.result.(type) {
+	case nil:
+		return nil, false
+	case []OIdentifiable:
+		if r.pos >= len(res) {
+			return nil, false
+		}
+		rec = res[r.pos]
+	default:
+		if r.pos != 0 {
+			return nil, false
+		}
+		rec = res
+	}
+	r.pos++
+	switch rec := rec.(type) {
+	case *Document:
+		return rec, true
+	case OIdentifiable:
+		if r.loader == nil {
+			r.err = fmt.Errorf("record %v was not fetched", rec.GetIdentity())
+			return nil, false
+		}
+		doc, err := r.loader.Load(rec.GetIdentity())
+		if err != nil {
+			r.err = err
+			return nil, false
+		}
+		return doc, true
+	}
+	r.err = fmt.Errorf("result is not a record: %T", rec)
+	return nil, false
+}
 func (r *unknownResult) All(result interface{}) error {
 	//	if r.parsed {
 	//		return fmt.Errorf("results are already parsed")
 	//	}
 	//	r.parsed = true
+	if r.closed {
+		return ErrResultsClosed
+	}
+	r.markUsed()
 
 	// check for pointer
 	targ := reflect.ValueOf(result)
@@ -101,8 +306,154 @@ func (r *unknownResult) All(result interface{}) error {
 		return fmt.Errorf("nil result pointer")
 	}
 	targ = targ.Elem()
+	if err := checkCount(targ, r.result); err != nil {
+		return err
+	}
+
+	c := &typeConverter{loader: r.loader, names: r.names, depth: r.depth, workers: r.workers, threshold: r.threshold}
+	if err := c.convert(targ, reflect.ValueOf(r.result)); err != nil {
+		return err
+	}
+	r.hooks.decoded(r.result)
+	return nil
+}
 
-	return convertTypes(targ, reflect.ValueOf(r.result))
+func (r *unknownResult) Count() (int64, error) {
+	if r.closed {
+		return 0, ErrResultsClosed
+	}
+	r.markUsed()
+	if r.err != nil {
+		return 0, r.err
+	}
+	return scalarCount(r.result)
+}
+
+// scalarCount extracts a number from a command result. The number can be returned as is, or as the only field
+// of a single record, which is the case for projections like count(*).
+func scalarCount(result interface{}) (int64, error) {
+	v := result
+	if list, ok := v.([]OIdentifiable); ok {
+		if len(list) != 1 {
+			return 0, fmt.Errorf("expected a single record with a number, got %d records", len(list))
+		}
+		v = list[0]
+	}
+	if doc, ok := v.(*Document); ok {
+		fields := doc.Fields()
+		if len(fields) != 1 {
+			return 0, fmt.Errorf("expected a record with a single field, got %d fields", len(fields))
+		}
+		for _, fld := range fields {
+			v = fld.Value
+		}
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || !isNumberKind(rv.Kind()) {
+		return 0, fmt.Errorf("result is not a number: %T", v)
+	}
+	out, err := convertNumber(rv, reflect.TypeOf(int64(0)))
+	if err != nil {
+		return 0, err
+	}
+	return out.Int(), nil
+}
+
+func (r *unknownResult) LoadLinks(depth int) Results {
+	if depth < 0 {
+		depth = 0
+	}
+	r.depth = depth
+	return r
+}
+func (r *unknownResult) Take(n int, result interface{}) error {
+	if n < 0 {
+		return fmt.Errorf("negative number of records: %d", n)
+	} else if r.closed {
+		return ErrResultsClosed
+	}
+	targ := reflect.ValueOf(result)
+	if targ.Kind() != reflect.Ptr || targ.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("result is not a pointer to slice: %T", result)
+	}
+	switch res := r.result.(type) {
+	case []OIdentifiable:
+		if len(res) > n {
+			r.result = res[:n]
+		}
+	case OIdentifiable:
+		if n == 0 {
+			r.result = []OIdentifiable{}
+		}
+	}
+	return r.All(result)
+}
+
+// ErrNoCount is returned when results are decoded into an integer (an affected records count), but the command
+// returned records. For example, INSERT returns created records, and UPDATE returns records if RETURN AFTER is used.
+type ErrNoCount struct {
+	Records int
+}
+
+func (e ErrNoCount) Error() string {
+	return fmt.Sprintf("command returned %d record(s) instead of a count", e.Records)
+}
+
+// checkCount returns ErrNoCount if command result is a record or a list of records, while target is an integer.
+func checkCount(targ reflect.Value, result interface{}) error {
+	switch targ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil
+	}
+	switch res := result.(type) {
+	case []OIdentifiable:
+		return ErrNoCount{Records: len(res)}
+	case OIdentifiable:
+		return ErrNoCount{Records: 1}
+	}
+	return nil
+}
+
+func (r *unknownResult) Stream(ch interface{}, done <-chan struct{}) error {
+	cv, err := sendChan(ch)
+	if err != nil {
+		return err
+	}
+	defer cv.Close()
+	if r.closed {
+		return ErrResultsClosed
+	}
+	r.markUsed()
+	var recs []reflect.Value
+	if src := reflect.ValueOf(r.result); !src.IsValid() {
+		// no records
+	} else if src.Kind() == reflect.Slice && src.Type().Elem().Kind() != reflect.Uint8 {
+		recs = make([]reflect.Value, src.Len())
+		for i := range recs {
+			recs[i] = src.Index(i)
+		}
+	} else {
+		recs = []reflect.Value{src}
+	}
+	cases := []reflect.SelectCase{{Dir: reflect.SelectSend, Chan: cv}}
+	if done != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)})
+	}
+	c := &typeConverter{loader: r.loader, names: r.names, depth: r.depth, workers: r.workers, threshold: r.threshold}
+	for _, rec := range recs {
+		v := reflect.New(cv.Type().Elem()).Elem()
+		if err := c.convert(v, rec); err != nil {
+			return err
+		}
+		cases[0].Send = v
+		if i, _, _ := reflect.Select(cases); i != 0 {
+			return nil // consumer is gone
+		}
+	}
+	r.hooks.decoded(r.result)
+	return nil
 }
 
 type ErrUnsupportedConversion struct {
@@ -125,31 +476,475 @@ func (e ErrUnsupportedConversion) Error() string {
 	return fmt.Sprintf("unsupported conversion: %v -> %v", a, b)
 }
 
+// ErrNumericOverflow is returned when a numeric value does not fit into a target type without loss of data,
+// for example when LONG value is decoded into int16 field, or a negative value into unsigned field.
+type ErrNumericOverflow struct {
+	Value interface{}
+	To    reflect.Type
+}
+
+func (e ErrNumericOverflow) Error() string {
+	return fmt.Sprintf("value %v (%T) cannot be converted to %v without loss of data", e.Value, e.Value, e.To)
+}
+
+// TruncateNumbers disables overflow checks for numeric conversions. If set, values are converted
+// the same way as Go conversions do, silently truncating them.
+var TruncateNumbers = false
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// convertNumber converts numeric value to a numeric target type, and checks that no data is lost.
+func convertNumber(src reflect.Value, typ reflect.Type) (reflect.Value, error) {
+	out := src.Convert(typ)
+	if TruncateNumbers {
+		return out, nil
+	}
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, ErrNumericOverflow{Value: src.Interface(), To: typ}
+	}
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v := src.Int()
+		switch out.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if out.OverflowInt(v) {
+				return fail()
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if v < 0 || out.OverflowUint(uint64(v)) {
+				return fail()
+			}
+		default:
+			if int64(out.Float()) != v {
+				return fail()
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v := src.Uint()
+		switch out.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v > math.MaxInt64 || out.OverflowInt(int64(v)) {
+				return fail()
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if out.OverflowUint(v) {
+				return fail()
+			}
+		default:
+			if uint64(out.Float()) != v {
+				return fail()
+			}
+		}
+	default:
+		v := src.Float()
+		switch out.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if !math.IsInf(v, 0) && !math.IsNaN(v) && out.OverflowFloat(v) {
+				return fail()
+			}
+		default:
+			if math.Trunc(v) != v || out.Convert(src.Type()).Float() != v {
+				return fail()
+			}
+		}
+	}
+	return out, nil
+}
+
 func mapToStruct(m interface{}, val interface{}) error {
-	dec, err := newMapDecoder(val)
+	return new(typeConverter).mapToStruct(m, val)
+}
+
+func (c *typeConverter) mapToStruct(m interface{}, val interface{}) error {
+	_, err := c.decodeStruct(m, val)
+	return err
+}
+
+// decodeStruct decodes a map or a document into a struct and reports if any of struct fields were assigned.
+func (c *typeConverter) decodeStruct(m interface{}, val interface{}) (bool, error) {
+	if doc, ok := m.(*Document); ok {
+		mp, err := doc.ToMap()
+		if err != nil {
+			return false, err
+		}
+		m = mp
+	}
+	orig, _ := m.(map[string]interface{})
+	if orig != nil {
+		m = c.names.applyRenames(orig)
+	}
+	var md mapstructure.Metadata
+	top := true
+	structHook := func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if top { // hooks are also called for the struct itself
+			top = false
+			return data, nil
+		}
+		return c.structPtrHook(f, t, data)
+	}
+	dec, err := newMapDecoder(val, &md, c.linkHook, structHook, c.names.decodeHook)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if err = dec.Decode(m); err != nil {
+		return false, err
+	}
+	if orig != nil {
+		setExtraFields(orig, val, c.names)
+	}
+	return len(md.Keys) != 0, nil
+}
+
+// structPtrHook decodes maps and documents into pointers to structs. Pointer is left nil if none of struct
+// fields were assigned, so absent embedded documents can be distinguished from empty ones.
+// It also decodes structs that collect extra fields (see OrientTagName).
+func (c *typeConverter) structPtrHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if b, ok := data.([]byte); ok {
+		if v, ok, err := binaryTarget(t, b); ok {
+			if err != nil {
+				return nil, err
+			}
+			return v.Interface(), nil
+		}
+		return data, nil
+	}
+	if v, ok, err := fromDocument(t, data); ok {
+		if err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+	if t.Kind() == reflect.Struct && extraField(t) >= 0 && (f.Kind() == reflect.Map || f == reflDocumentType) {
+		// structs with extra fields are decoded separately, so unmatched fields can be collected
+		v := reflect.New(t)
+		if _, err := c.decodeStruct(data, v.Interface()); err != nil {
+			return nil, err
+		}
+		return v.Elem().Interface(), nil
+	}
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct || t == reflDocumentType || t == reflLazyLinkPtrType {
+		return data, nil
+	} else if f.Kind() != reflect.Map && f != reflDocumentType {
+		return data, nil
+	}
+	v := reflect.New(t.Elem())
+	if ok, err := c.decodeStruct(data, v.Interface()); err != nil {
+		return nil, err
+	} else if !ok {
+		return reflect.Zero(t).Interface(), nil
+	}
+	return v.Interface(), nil
+}
+
+var (
+	reflIOReaderType   = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	reflReaderFromType = reflect.TypeOf((*io.ReaderFrom)(nil)).Elem()
+)
+
+// binaryTarget decodes BINARY data into streaming targets: io.Reader fields get a reader over the record
+// buffer without copying it, and pointers to types that implement io.ReaderFrom (like *bytes.Buffer) read
+// the data with ReadFrom. It returns false if t is not a streaming target.
+func binaryTarget(t reflect.Type, b []byte) (reflect.Value, bool, error) {
+	switch {
+	case t == reflIOReaderType:
+		return reflect.ValueOf(bytes.NewReader(b)).Convert(t), true, nil
+	case t.Kind() == reflect.Ptr && t.Implements(reflReaderFromType):
+		v := reflect.New(t.Elem())
+		if _, err := v.Interface().(io.ReaderFrom).ReadFrom(bytes.NewReader(b)); err != nil {
+			return reflect.Value{}, true, err
+		}
+		return v, true, nil
 	}
-	return dec.Decode(m)
+	return reflect.Value{}, false, nil
+}
+
+// binaryProjection returns BINARY value of a record with a single field, or of a result set with one such record,
+// as returned by queries like "SELECT data FROM Blob WHERE ...".
+func binaryProjection(src reflect.Value) ([]byte, bool) {
+	for src.Kind() == reflect.Interface && !src.IsNil() {
+		src = src.Elem()
+	}
+	if src.Kind() == reflect.Slice && src.Type() != reflect.TypeOf([]byte(nil)) && src.Len() == 1 {
+		return binaryProjection(src.Index(0))
+	}
+	if rec, ok := src.Interface().(*BytesRecord); ok && rec != nil {
+		return rec.Data, true
+	}
+	doc, ok := src.Interface().(*Document)
+	if !ok || doc == nil {
+		return nil, false
+	}
+	fields := doc.Fields()
+	if len(fields) != 1 {
+		return nil, false
+	}
+	for _, fld := range fields {
+		b, ok := fld.Value.([]byte)
+		return b, ok
+	}
+	return nil, false
+}
+
+// recordData returns the content of a schema less record: []byte for blobs and string for flat records.
+// Such records are decoded the same way as their content, so a blob can only be decoded into BINARY
+// targets, and not into a struct, as a document would be. It returns false for documents.
+func recordData(rec ORecord) (interface{}, bool) {
+	switch r := rec.(type) {
+	case *BytesRecord:
+		if r != nil {
+			return r.Data, true
+		}
+	case *FlatRecord:
+		if r != nil {
+			return r.Data, true
+		}
+	}
+	return nil, false
+}
+
+// plainValue converts a value to plain Go types, so it can be encoded to JSON as is: documents become
+// map[string]interface{} (as returned by Document.ToMap), lists and sets become []interface{}, maps with
+// string keys become map[string]interface{}, and embedded RidBags become lists of RIDs. Values are converted
+// recursively, other values (like numbers, time.Time or RID) are returned as is.
+func plainValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil, []byte:
+		return v, nil
+	case *Document:
+		if val == nil {
+			return nil, nil
+		}
+		m, err := val.ToMap()
+		if err != nil {
+			return nil, err
+		}
+		return plainValue(m)
+	case *RidBag:
+		if val == nil || val.IsRemote() {
+			return v, nil
+		}
+		var out []interface{}
+		next := val.Iterate(nil)
+		for rid, ok := next(); ok; rid, ok = next() {
+			out = append(out, rid)
+		}
+		return out, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			item, err := plainValue(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			out[i] = item
+		}
+		return out, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v, nil
+		}
+		out := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			item, err := plainValue(rv.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+			out[k.String()] = item
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+var reflDocDeserializableType = reflect.TypeOf((*DocumentDeserializable)(nil)).Elem()
+
+// fromDocument decodes a document or a map into a struct (or a pointer to struct) of type t by calling
+// its FromDocument method, if the struct implements DocumentDeserializable.
+// It returns false if conversion is not applicable for given type and source.
+func fromDocument(t reflect.Type, src interface{}) (reflect.Value, bool, error) {
+	var ptr reflect.Value
+	switch {
+	case t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && t.Implements(reflDocDeserializableType):
+		ptr = reflect.New(t.Elem())
+	case t.Kind() == reflect.Struct && reflect.PtrTo(t).Implements(reflDocDeserializableType):
+		ptr = reflect.New(t)
+	default:
+		return reflect.Value{}, false, nil
+	}
+	var doc *Document
+	switch d := src.(type) {
+	case *Document:
+		if d == nil {
+			return reflect.Value{}, false, nil
+		}
+		doc = d
+	case map[string]interface{}:
+		doc = NewEmptyDocument()
+		if err := doc.From(d); err != nil {
+			return reflect.Value{}, true, err
+		}
+	default:
+		return reflect.Value{}, false, nil
+	}
+	if err := ptr.Interface().(DocumentDeserializable).FromDocument(doc); err != nil {
+		return reflect.Value{}, true, err
+	}
+	if t.Kind() == reflect.Struct {
+		return ptr.Elem(), true, nil
+	}
+	return ptr, true, nil
 }
 
 const debugTypeConversion = false
 
+// convertLink converts a single link (an element of LINK, LINKLIST or LINKSET field) to one of supported types:
+//
+//		RID       - RID of a link or a fetched record
+//		*Document - fetched record, or a document with only RID set, if link was not fetched
+//		string    - RID of a link or a fetched record in #N:M form
+//
+// It returns false if conversion is not applicable for given types.
+func convertLink(targ, src reflect.Value) (bool, error) {
+	switch {
+	case targ.Type() == reflRIDType:
+		if v, ok := src.Interface().(OIdentifiable); ok {
+			targ.Set(reflect.ValueOf(v.GetIdentity()))
+			return true, nil
+		}
+	case targ.Type() == reflDocumentType:
+		if v, ok := src.Interface().(RID); ok {
+			targ.Set(reflect.ValueOf(NewDocumentFromRID(v)))
+			return true, nil
+		}
+	case targ.Kind() == reflect.String:
+		if v, ok := src.Interface().(OIdentifiable); ok {
+			targ.SetString(v.GetIdentity().String())
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// convertSliceParallel converts slice elements using a few goroutines. Order of elements is preserved.
+// If conversion fails, error for the first failed element is returned, as in serial conversion.
+func (c *typeConverter) convertSliceParallel(targ, src reflect.Value, workers int) error {
+	n := src.Len()
+	if workers > n {
+		workers = n
+	}
+	var (
+		wg    sync.WaitGroup
+		errs  = make([]error, workers)
+		chunk = (n + workers - 1) / workers
+	)
+	for w := 0; w < workers; w++ {
+		from, to := w*chunk, (w+1)*chunk
+		if to > n {
+			to = n
+		}
+		wg.Add(1)
+		go func(w, from, to int) {
+			defer wg.Done()
+			for i := from; i < to; i++ {
+				if err := c.convert(targ.Index(i), src.Index(i)); err != nil {
+					errs[w] = err
+					return
+				}
+			}
+		}(w, from, to)
+	}
+	wg.Wait()
+	for _, err := range errs { // chunks are ordered, so first error is the one with the lowest index
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func convertTypes(targ, src reflect.Value) error {
+	return new(typeConverter).convert(targ, src)
+}
+
+func (c *typeConverter) convert(targ, src reflect.Value) error {
+	if !src.IsValid() { // nil result
+		if targ.Kind() == reflect.Interface {
+			targ.Set(reflect.Zero(targ.Type()))
+			return nil
+		}
+		return ErrNoRecord
+	}
 	if debugTypeConversion {
 		fmt.Printf("conv: %T -> %T, %+v -> %+v\n", src.Interface(), targ.Interface(), src.Interface(), targ.Interface())
 		defer func() {
 			fmt.Printf("conv out: %T -> %T, %+v -> %+v\n", src.Interface(), targ.Interface(), src.Interface(), targ.Interface())
 		}()
 	}
-	if targ.Type() == src.Type() {
+	if targ.Type() == reflInterfaceMapType && src.Type() == reflInterfaceMapType && !src.IsNil() {
+		v, err := plainValue(src.Interface())
+		if err == nil {
+			targ.Set(reflect.ValueOf(v))
+		}
+		return err
+	} else if targ.Type() == src.Type() {
 		targ.Set(src)
 		return nil
+	} else if src.Kind() != reflect.Interface {
+		if v, ok, err := fromStoredValue(targ.Type(), src.Interface()); err != nil {
+			return err
+		} else if ok {
+			return c.convert(targ, reflect.ValueOf(v))
+		}
+	}
+	if isNumberKind(src.Kind()) && isNumberKind(targ.Kind()) {
+		v, err := convertNumber(src, targ.Type())
+		if err != nil {
+			return err
+		}
+		targ.Set(v)
+		return nil
 	} else if src.Type().ConvertibleTo(targ.Type()) {
 		targ.Set(src.Convert(targ.Type()))
 		return nil
 	} else if src.Kind() == reflect.Interface {
-		return convertTypes(targ, src.Elem())
+		if src.IsNil() { // nil link in collection, for example
+			targ.Set(reflect.Zero(targ.Type()))
+			return nil
+		}
+		return c.convert(targ, src.Elem())
+	}
+	if rec, ok := src.Interface().(ORecord); ok {
+		if data, ok := recordData(rec); ok && targ.Type() != reflRIDType {
+			return c.convert(targ, reflect.ValueOf(data))
+		}
+	}
+	if v, ok, err := fromDocument(targ.Type(), src.Interface()); ok {
+		if err == nil {
+			targ.Set(v)
+		}
+		return err
+	}
+	if b, ok := src.Interface().([]byte); ok {
+		if v, ok, err := binaryTarget(targ.Type(), b); ok {
+			if err == nil {
+				targ.Set(v)
+			}
+			return err
+		}
+	}
+	if ok, err := c.convertLink(targ, src); ok {
+		return err
 	}
 	//	if targ.Kind() == reflect.Ptr {
 	//		if targ.IsNil() {
@@ -162,19 +957,37 @@ func convertTypes(targ, src reflect.Value) error {
 	//	}
 
 	if targ.Kind() == reflect.Struct || (targ.Kind() == reflect.Ptr && targ.Type().Elem().Kind() == reflect.Struct) {
-		if targ.Kind() == reflect.Ptr && targ.IsNil() {
-			targ.Set(reflect.New(targ.Type().Elem()))
-		}
-		if src.Kind() == reflect.Map {
-			return mapToStruct(src.Interface(), targ.Addr().Interface())
+		// documents are decoded the same way as maps, whether they are returned alone or in a result set
+		if src.Kind() == reflect.Map || (src.Type() == reflDocumentType && !src.IsNil()) {
+			// allocate only when at least one field is decoded, so errors or unrelated data will not leave
+			// an empty struct behind
+			if targ.Kind() == reflect.Ptr && targ.IsNil() {
+				v := reflect.New(targ.Type().Elem())
+				if ok, err := c.decodeStruct(src.Interface(), v.Interface()); err != nil {
+					return err
+				} else if ok {
+					targ.Set(v)
+				}
+				return nil
+			}
+			return c.mapToStruct(src.Interface(), targ.Addr().Interface())
 		}
 	} else if targ.Kind() == reflect.Slice {
+		if targ.Type().Elem().Kind() == reflect.Uint8 { // BINARY field of a single-field record
+			if b, ok := binaryProjection(src); ok {
+				targ.Set(reflect.ValueOf(b).Convert(targ.Type()))
+				return nil
+			}
+		}
 		if src.Kind() == reflect.Slice { // slice into slice
 			if targ.Len() != src.Len() {
 				targ.Set(reflect.MakeSlice(targ.Type(), src.Len(), src.Len()))
 			}
+			if c.workers > 1 && src.Len() >= c.threshold {
+				return c.convertSliceParallel(targ, src, c.workers)
+			}
 			for i := 0; i < src.Len(); i++ {
-				if err := convertTypes(targ.Index(i), src.Index(i)); err != nil {
+				if err := c.convert(targ.Index(i), src.Index(i)); err != nil {
 					return err
 				}
 			}
@@ -182,21 +995,39 @@ func convertTypes(targ, src reflect.Value) error {
 		}
 		// one value into slice
 		targ.Set(reflect.MakeSlice(targ.Type(), 1, 1))
-		if err := convertTypes(targ.Index(0), src); err != nil {
+		if err := c.convert(targ.Index(0), src); err != nil {
 			targ.Set(reflect.Zero(targ.Type()))
 			return err
 		}
 		return nil
 	} else if targ.Kind() == reflect.Map {
+		if src.Kind() == reflect.Slice && isSetMapType(targ.Type()) {
+			m, err := sliceToSetMap(targ.Type(), src)
+			if err != nil {
+				return err
+			}
+			targ.Set(m)
+			return nil
+		}
+		// values are converted recursively, so maps of documents or nested maps can be decoded into maps of structs
 		if src.Kind() == reflect.Map {
+			plain := targ.Type().Elem() == reflInterfaceType
 			targ.Set(reflect.MakeMap(targ.Type()))
 			for _, k := range src.MapKeys() {
 				nk := reflect.New(targ.Type().Key()).Elem()
-				if err := convertTypes(nk, k); err != nil {
+				if err := c.convert(nk, k); err != nil {
 					return err
 				}
 				nv := reflect.New(targ.Type().Elem()).Elem()
-				if err := convertTypes(nv, src.MapIndex(k)); err != nil {
+				if plain {
+					v, err := plainValue(src.MapIndex(k).Interface())
+					if err != nil {
+						return err
+					}
+					if v != nil {
+						nv.Set(reflect.ValueOf(v))
+					}
+				} else if err := c.convert(nv, src.MapIndex(k)); err != nil {
 					return err
 				}
 				targ.SetMapIndex(nk, nv)
@@ -211,14 +1042,14 @@ func convertTypes(targ, src reflect.Value) error {
 		if err != nil {
 			return err
 		}
-		return convertTypes(targ, reflect.ValueOf(m))
+		return c.convert(targ, reflect.ValueOf(m))
 	case *Document: // Document implements DocumentSerializable for convenience, no need to convert it
 	case DocumentSerializable:
 		doc, err := rec.ToDocument()
 		if err != nil {
 			return err
 		}
-		return convertTypes(targ, reflect.ValueOf(doc))
+		return c.convert(targ, reflect.ValueOf(doc))
 	}
 
 	// Target is now converted, process the result set
@@ -227,7 +1058,7 @@ func convertTypes(targ, src reflect.Value) error {
 		case 0:
 			return ErrNoRecord
 		case 1:
-			return convertTypes(targ, src.Index(0))
+			return c.convert(targ, src.Index(0))
 		default:
 			return ErrMultipleRecords{N: src.Len(), Err: ErrUnsupportedConversion{From: src, To: targ}}
 		}
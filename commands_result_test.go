@@ -1,8 +1,15 @@
 package orient
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func documentFrom(o interface{}) *Document {
@@ -54,3 +61,1056 @@ func TestResultsInnerStruct(t *testing.T) {
 	var dst *Item
 	testResults(t, doc, &dst, &Item{One: one, Inner: []Inner{one, two}})
 }
+
+func TestResultsStructNoRecords(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+	var dst Item
+	if err := newResults([]OIdentifiable{}).All(&dst); err != ErrNoRecord {
+		t.Fatalf("expected ErrNoRecord, got: %v", err)
+	}
+}
+
+func TestResultsNil(t *testing.T) {
+	var dst map[string]interface{}
+	if err := newResults(nil).All(&dst); err != ErrNoRecord {
+		t.Fatalf("expected ErrNoRecord, got: %v", err)
+	}
+	if err := convertTypes(reflect.ValueOf(&dst).Elem(), reflect.Value{}); err != ErrNoRecord {
+		t.Fatalf("expected ErrNoRecord, got: %v", err)
+	}
+	var o interface{} = "old"
+	if err := newResults(nil).All(&o); err != nil {
+		t.Fatal(err)
+	} else if o != nil {
+		t.Fatalf("expected nil, got: %v", o)
+	}
+}
+
+func TestResultsStructOneRecord(t *testing.T) {
+	type Item struct {
+		Name string
+		Age  int
+	}
+	doc := documentFrom(map[string]interface{}{"name": "one", "age": int32(5)})
+	var dst Item
+	testResults(t, []OIdentifiable{doc}, &dst, Item{Name: "one", Age: 5})
+}
+
+func TestResultsStructMultipleRecords(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+	docs := []OIdentifiable{
+		documentFrom(map[string]interface{}{"name": "one"}),
+		documentFrom(map[string]interface{}{"name": "two"}),
+	}
+	var dst Item
+	err := newResults(docs).All(&dst)
+	if e, ok := err.(ErrMultipleRecords); !ok {
+		t.Fatalf("expected ErrMultipleRecords, got: %T(%v)", err, err)
+	} else if e.N != 2 {
+		t.Fatalf("wrong records count: %d", e.N)
+	} else if dst != (Item{}) {
+		t.Fatalf("target should not be changed: %+v", dst)
+	}
+	var pdst *Item
+	if err = newResults(docs).All(&pdst); err == nil {
+		t.Fatal("expected an error")
+	} else if pdst != nil {
+		t.Fatalf("target should not be allocated: %+v", pdst)
+	}
+}
+
+func TestResultsDoubleClose(t *testing.T) {
+	errCmd := fmt.Errorf("command failed")
+	for _, r := range []struct {
+		res Results
+		err error
+	}{
+		{newResults(int32(1)), nil},
+		{&errorResult{err: errCmd}, errCmd},
+	} {
+		if err := r.res.Close(); err != r.err {
+			t.Fatalf("unexpected error on first close: %v", err)
+		}
+		if err := r.res.Close(); err != ErrResultsClosed {
+			t.Fatalf("expected ErrResultsClosed on second close, got: %v", err)
+		}
+	}
+}
+
+func TestResultsUseAfterClose(t *testing.T) {
+	res := newResults([]OIdentifiable{documentFrom(map[string]interface{}{"name": "record"})})
+	if err := res.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if res.Next(&m) {
+		t.Fatal("Next returned true after Close")
+	} else if m != nil {
+		t.Fatal("Next decoded result after Close")
+	}
+	if err := res.All(&m); err != ErrResultsClosed {
+		t.Fatalf("expected ErrResultsClosed, got: %v", err)
+	}
+}
+
+func TestResultsNextDocument(t *testing.T) {
+	a := documentFrom(map[string]interface{}{"name": "a"})
+	b := documentFrom(map[string]interface{}{"name": "b"})
+	b.RID = NewRID(9, 2)
+	var res Results = newLoaderResults([]OIdentifiable{a, b.RID}, PrefetchedLoader([]*Document{b}, nil))
+	var got []*Document
+	for {
+		doc, ok := res.NextDocument()
+		if !ok {
+			break
+		}
+		got = append(got, doc)
+	}
+	if err := res.Err(); err != nil {
+		t.Fatal(err)
+	} else if len(got) != 2 || got[0] != a || got[1] != b {
+		t.Fatalf("wrong documents: %v", got)
+	}
+
+	res = newResults(int32(1))
+	if _, ok := res.NextDocument(); ok {
+		t.Fatal("NextDocument returned a count")
+	} else if res.Err() == nil {
+		t.Fatal("expected an error for non-record result")
+	}
+}
+
+func TestResultsCount(t *testing.T) {
+	for _, c := range []struct {
+		result interface{}
+		count  int64
+		fail   bool
+	}{
+		{int32(3), 3, false},
+		{int64(7), 7, false},
+		{[]OIdentifiable{documentFrom(map[string]interface{}{"count": int64(2)})}, 2, false},
+		{documentFrom(map[string]interface{}{"sum": 5.0}), 5, false},
+		{documentFrom(map[string]interface{}{"sum": 5.5}), 0, true},
+		{documentFrom(map[string]interface{}{"a": 1, "b": 2}), 0, true},
+		{[]OIdentifiable{}, 0, true},
+		{"3", 0, true},
+		{nil, 0, true},
+	} {
+		n, err := newResults(c.result).Count()
+		if c.fail && err == nil {
+			t.Fatalf("expected an error for %v", c.result)
+		} else if !c.fail && err != nil {
+			t.Fatalf("unexpected error for %v: %v", c.result, err)
+		} else if n != c.count {
+			t.Fatalf("wrong count for %v: %d", c.result, n)
+		}
+	}
+}
+
+func TestResultsTake(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+	var recs []OIdentifiable
+	for _, name := range []string{"a", "b", "c"} {
+		recs = append(recs, documentFrom(map[string]interface{}{"name": name}))
+	}
+	for _, c := range []struct {
+		n      int
+		expect []Item
+	}{
+		{0, nil},
+		{2, []Item{{"a"}, {"b"}}},
+		{5, []Item{{"a"}, {"b"}, {"c"}}},
+	} {
+		var items []Item
+		if err := newResults(recs).Take(c.n, &items); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(items, c.expect) {
+			t.Fatalf("wrong items for %d: %v", c.n, items)
+		}
+	}
+	var item Item
+	if err := newResults(recs).Take(1, &item); err == nil {
+		t.Fatal("expected an error for non-slice result")
+	}
+}
+
+func TestResultsDocumentShapes(t *testing.T) {
+	type Item struct {
+		RID   RID    `orient:"@rid"`
+		Class string `orient:"@class"`
+		Name  string
+		Extra map[string]interface{} `orient:",extra"`
+	}
+	doc := NewDocument("Item")
+	doc.RID = NewRID(9, 1)
+	doc.SetField("Name", "x")
+	doc.SetField("other", int32(1))
+	expect := Item{RID: doc.RID, Class: "Item", Name: "x", Extra: map[string]interface{}{"other": int32(1)}}
+	for _, src := range []interface{}{doc, []OIdentifiable{doc}} {
+		var item Item
+		testResults(t, src, &item, expect)
+		var ptr *Item
+		testResults(t, src, &ptr, &expect)
+	}
+}
+
+func TestResultsLinkMapToStruct(t *testing.T) {
+	type Addresses struct {
+		Home  RID
+		Work  *Document `mapstructure:"office"`
+		Other RID
+	}
+	type Person struct {
+		Name  string
+		Addrs Addresses
+	}
+	home, work := NewRID(9, 1), NewRID(9, 2)
+	doc := NewDocument("Person")
+	doc.SetField("Name", "Anna")
+	doc.SetFieldWithType("Addrs", map[string]OIdentifiable{"home": home, "office": work}, LINKMAP)
+
+	buf := bytes.NewBuffer(nil)
+	if err := GetDefaultRecordSerializer().ToStream(buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := GetDefaultRecordSerializer().FromStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p Person
+	if err = newResults(rec).All(&p); err != nil {
+		t.Fatal(err)
+	} else if p.Name != "Anna" || p.Addrs.Home != home {
+		t.Fatalf("wrong data: %+v", p)
+	} else if p.Addrs.Work == nil || p.Addrs.Work.RID != work {
+		t.Fatalf("wrong resolved link: %+v", p.Addrs.Work)
+	} else if p.Addrs.Other != (RID{}) {
+		t.Fatalf("missing key should leave zero value: %v", p.Addrs.Other)
+	}
+}
+
+func TestResultsLinkMapVertices(t *testing.T) {
+	alice, bob := NewRID(9, 1), NewRID(9, 2)
+	doc := NewDocument("Team")
+	doc.SetField("name", "core")
+	doc.SetFieldWithType("members", map[string]RID{"lead": alice, "dev": bob}, LINKMAP)
+
+	buf := bytes.NewBuffer(nil)
+	if err := GetDefaultRecordSerializer().ToStream(buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := GetDefaultRecordSerializer().FromStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids struct {
+		Members map[string]RID `mapstructure:"members"`
+	}
+	if err = newResults(rec).All(&ids); err != nil {
+		t.Fatal(err)
+	} else if len(ids.Members) != 2 || ids.Members["lead"] != alice || ids.Members["dev"] != bob {
+		t.Fatalf("wrong links: %v", ids.Members)
+	}
+	loads := 0
+	loader := LinkLoaderFunc(func(rid RID) (*Document, error) {
+		loads++
+		v := NewDocumentFromRID(rid)
+		v.SetField("name", rid.String())
+		return v, nil
+	})
+	var docs struct {
+		Members map[string]*Document `mapstructure:"members"`
+	}
+	if err = newLoaderResults(rec, loader).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(docs.Members) != 2 || docs.Members["lead"].RID != alice {
+		t.Fatalf("wrong documents: %v", docs.Members)
+	} else if loads != 0 {
+		t.Fatalf("documents were loaded too early: %d", loads)
+	} else if name, _ := docs.Members["dev"].GetField("name").Value.(string); name != bob.String() || loads != 1 {
+		t.Fatalf("document was not loaded lazily: %q (%d loads)", name, loads)
+	}
+}
+
+type benchItem struct {
+	ID    int
+	Name  string
+	Tags  []string
+	Score float64
+}
+
+func benchRecords(n int) []OIdentifiable {
+	recs := make([]OIdentifiable, n)
+	for i := range recs {
+		recs[i] = documentFrom(benchItem{ID: i, Name: fmt.Sprint("item", i), Tags: []string{"a", "b"}, Score: float64(i) / 2})
+	}
+	return recs
+}
+
+// parallelResults returns results with parallel decoding enabled, as set by Database.SetParallelDecode.
+func parallelResults(o interface{}, workers, threshold int) Results {
+	r := newLoaderResults(o, nil)
+	r.workers, r.threshold = workers, threshold
+	return r
+}
+
+func TestResultsParallelDecode(t *testing.T) {
+	recs := benchRecords(1001)
+	var serial, parallel []benchItem
+	if err := newResults(recs).All(&serial); err != nil {
+		t.Fatal(err)
+	}
+	if err := parallelResults(recs, 4, 100).All(&parallel); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Fatal("parallel decode result differs from serial one")
+	}
+	for i, it := range parallel {
+		if it.ID != i {
+			t.Fatalf("wrong order at %d: %+v", i, it)
+		}
+	}
+}
+
+func TestResultsParallelDecodeError(t *testing.T) {
+	recs := benchRecords(300)
+	recs[120] = documentFrom(map[string]interface{}{"ID": "bad"})
+	recs[250] = documentFrom(map[string]interface{}{"ID": "worse"})
+	var serial, parallel []benchItem
+	errSerial := newResults(recs).All(&serial)
+	errParallel := parallelResults(recs, 4, 100).All(&parallel)
+	if errSerial == nil || errParallel == nil {
+		t.Fatal("expected conversion error")
+	} else if errSerial.Error() != errParallel.Error() {
+		t.Fatalf("errors differ:\n%v\n%v", errSerial, errParallel)
+	}
+}
+
+func TestResultsParallelDecodeShared(t *testing.T) {
+	// the first post of each worker's chunk links to the same pre-fetched author
+	data, err := NewEmptyDocument().SetField("Name", "Linus").Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type Author struct {
+		Name string
+	}
+	type Post struct {
+		Title  string
+		Author *Author
+	}
+	for n := 0; n < 50; n++ { // workers must decode the author at the same time to catch a race
+		author := NewDocumentFromRID(NewRID(9, 0))
+		author.Fill(author.RID, 1, data)
+		posts := make([]OIdentifiable, 200)
+		for i := range posts {
+			post := NewEmptyDocument().SetField("Title", fmt.Sprint("post", i))
+			if i%50 == 0 {
+				post.SetField("Author", author.RID)
+			}
+			posts[i] = post
+		}
+		var out []Post
+		err = parallelResults(FetchedResult{Result: posts, Related: []ORecord{author}}, 4, 100).All(&out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < len(out); i += 50 {
+			if p := out[i].Author; p == nil || p.Name != "Linus" {
+				t.Fatalf("wrong author of post %d: %+v", i, p)
+			}
+		}
+	}
+}
+
+func benchmarkResultsAll(b *testing.B, workers int) {
+	recs := benchRecords(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out []benchItem
+		if err := parallelResults(recs, workers, 1000).All(&out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResultsAllSerial(b *testing.B)   { benchmarkResultsAll(b, 0) }
+func BenchmarkResultsAllParallel(b *testing.B) { benchmarkResultsAll(b, 4) }
+
+func TestResultsLinkListConversion(t *testing.T) {
+	r1, r2 := NewRID(9, 1), NewRID(9, 2)
+	doc := NewDocument("Person")
+	doc.SetFieldWithType("Friends", []OIdentifiable{r1, nil, r2}, LINKLIST)
+	buf := bytes.NewBuffer(nil)
+	if err := GetDefaultRecordSerializer().ToStream(buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := GetDefaultRecordSerializer().FromStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rids struct{ Friends []RID }
+	if err = rec.(*Document).ToStruct(&rids); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(rids.Friends, []RID{r1, {}, r2}) {
+		t.Fatalf("wrong RIDs: %v", rids.Friends)
+	}
+	var docs struct{ Friends []*Document }
+	if err = rec.(*Document).ToStruct(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(docs.Friends) != 3 || docs.Friends[0].RID != r1 || docs.Friends[1] != nil || docs.Friends[2].RID != r2 {
+		t.Fatalf("wrong documents: %v", docs.Friends)
+	}
+	var strs struct{ Friends []string }
+	if err = rec.(*Document).ToStruct(&strs); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(strs.Friends, []string{"#9:1", "", "#9:2"}) {
+		t.Fatalf("wrong strings: %v", strs.Friends)
+	}
+}
+
+func TestResultsLinksToSlice(t *testing.T) {
+	fetched := NewDocumentFromRID(NewRID(9, 2))
+	links := []OIdentifiable{NewRID(9, 1), fetched}
+	testResults(t, links, &[]RID{}, []RID{NewRID(9, 1), NewRID(9, 2)})
+	testResults(t, links, &[]string{}, []string{"#9:1", "#9:2"})
+
+	var docs []*Document
+	if err := newResults(links).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if docs[0].RID != NewRID(9, 1) || docs[1] != fetched {
+		t.Fatalf("wrong documents: %v", docs)
+	}
+}
+
+func TestResultsLinkLoader(t *testing.T) {
+	type Person struct {
+		Name   string
+		Friend *Person
+		Boss   LazyLink
+	}
+	alice, bob := NewDocument("Person"), NewDocument("Person")
+	alice.RID, bob.RID = NewRID(9, 1), NewRID(9, 2)
+	alice.SetField("Name", "alice")
+	alice.SetFieldWithType("Friend", bob.RID, LINK)
+	alice.SetFieldWithType("Boss", bob.RID, LINK)
+	bob.SetField("Name", "bob")
+	bob.SetFieldWithType("Friend", alice.RID, LINK)
+
+	loads := 0
+	loader := LinkLoaderFunc(func(rid RID) (*Document, error) {
+		loads++
+		switch rid {
+		case alice.RID:
+			return alice, nil
+		case bob.RID:
+			return bob, nil
+		}
+		return nil, ErrNoRecord
+	})
+	var p Person
+	if err := (&unknownResult{result: alice, loader: loader}).All(&p); err == nil {
+		t.Fatalf("expected an error for a link that is not loaded, got: %+v", p)
+	} else if loads != 0 {
+		t.Fatalf("links are loaded by default: %d", loads)
+	}
+	p = Person{}
+	if err := (&unknownResult{result: alice, loader: loader}).LoadLinks(1).All(&p); err != nil {
+		t.Fatal(err)
+	} else if p.Friend == nil || p.Friend.Name != "bob" {
+		t.Fatalf("link was not loaded: %+v", p)
+	} else if p.Friend.Friend != nil || loads != 1 {
+		t.Fatalf("links of loaded record were followed: %+v, %d loads", p.Friend, loads)
+	}
+	p, loads = Person{}, 0
+	if err := (&unknownResult{result: alice, loader: loader}).LoadLinks(2).All(&p); err != nil {
+		t.Fatal(err)
+	} else if p.Friend == nil || p.Friend.Friend == nil || p.Friend.Friend.Name != "alice" {
+		t.Fatalf("second level link was not loaded: %+v", p.Friend)
+	} else if p.Friend.Friend.Friend != nil || loads != 2 {
+		t.Fatalf("links were loaded deeper than requested: %d loads", loads)
+	}
+	if p.Boss.RID != bob.RID {
+		t.Fatalf("wrong lazy link: %+v", p.Boss)
+	} else if loads != 2 {
+		t.Fatal("lazy link was loaded before use")
+	}
+	var boss Person
+	if err := p.Boss.Decode(&boss); err != nil {
+		t.Fatal(err)
+	} else if boss.Name != "bob" || boss.Friend != nil {
+		t.Fatalf("wrong lazy link content: %+v", boss)
+	}
+}
+
+func TestResultsLinkNoLoader(t *testing.T) {
+	type Person struct {
+		Name   string
+		Friend *Person
+	}
+	doc := NewDocument("Person")
+	doc.SetField("Name", "alice")
+	doc.SetFieldWithType("Friend", NewRID(9, 2), LINK)
+	var p Person
+	if err := newResults(doc).All(&p); err == nil {
+		t.Fatalf("expected an error without loader, got: %+v", p)
+	}
+}
+
+func TestResultsNestedStructPtrLazy(t *testing.T) {
+	type Inner struct {
+		Name string
+	}
+	type Item struct {
+		Name    string
+		Present *Inner
+		Other   *Inner
+		Absent  *Inner
+	}
+	other := NewEmptyDocument()
+	other.SetField("unrelated", "value")
+	doc := NewEmptyDocument()
+	doc.SetField("name", "item")
+	doc.SetFieldWithType("present", map[string]interface{}{"name": "inner"}, EMBEDDEDMAP)
+	doc.SetFieldWithType("other", other, EMBEDDED)
+
+	var item Item
+	if err := newResults(doc).All(&item); err != nil {
+		t.Fatal(err)
+	} else if item.Present == nil || item.Present.Name != "inner" {
+		t.Fatalf("nested struct is not decoded: %+v", item.Present)
+	} else if item.Other != nil || item.Absent != nil {
+		t.Fatalf("nested structs should not be allocated: %+v", item)
+	}
+
+	var p *Inner
+	if err := newResults(other).All(&p); err != nil {
+		t.Fatal(err)
+	} else if p != nil {
+		t.Fatalf("struct should not be allocated: %+v", p)
+	}
+}
+
+func TestResultsEmbeddedMapOfDocuments(t *testing.T) {
+	type Pet struct {
+		Name string
+		Age  int
+	}
+	type Owner struct {
+		Pets   map[string]Pet
+		PetPtr map[string]*Pet
+		Nested map[string]map[string]Pet
+	}
+	pet := func(name string, age int) *Document {
+		doc := NewDocument("Pet")
+		doc.SetField("name", name)
+		doc.SetField("age", int32(age))
+		return doc
+	}
+	pets := map[string]interface{}{"cat": pet("Linus", 15), "dog": pet("Rex", 3)}
+	doc := NewEmptyDocument()
+	doc.SetFieldWithType("pets", pets, EMBEDDEDMAP)
+	doc.SetFieldWithType("petptr", pets, EMBEDDEDMAP)
+	doc.SetFieldWithType("nested", map[string]interface{}{
+		"home": map[string]interface{}{"cat": pet("Keiko", 10)},
+	}, EMBEDDEDMAP)
+
+	expect := map[string]Pet{"cat": {"Linus", 15}, "dog": {"Rex", 3}}
+	testResults(t, pets, &map[string]Pet{}, expect)
+
+	var owner Owner
+	if err := newResults(doc).All(&owner); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(owner.Pets, expect) {
+		t.Fatalf("wrong map: %+v", owner.Pets)
+	} else if p := owner.PetPtr["dog"]; p == nil || *p != expect["dog"] {
+		t.Fatalf("wrong map of pointers: %+v", owner.PetPtr)
+	} else if owner.Nested["home"]["cat"] != (Pet{"Keiko", 10}) {
+		t.Fatalf("wrong nested map: %+v", owner.Nested)
+	}
+}
+
+func TestPrefetchedLoader(t *testing.T) {
+	bob := NewDocument("Person")
+	bob.RID = NewRID(9, 2)
+	fallbacks := 0
+	loader := PrefetchedLoader([]*Document{bob}, LinkLoaderFunc(func(rid RID) (*Document, error) {
+		fallbacks++
+		return nil, ErrNoRecord
+	}))
+	if doc, err := loader.Load(bob.RID); err != nil || doc != bob {
+		t.Fatalf("pre-fetched record was not returned: %v, %v", doc, err)
+	} else if fallbacks != 0 {
+		t.Fatal("fallback loader was called for pre-fetched record")
+	}
+	if _, err := loader.Load(NewRID(9, 3)); err != ErrNoRecord || fallbacks != 1 {
+		t.Fatalf("fallback loader was not called: %v", err)
+	}
+	if _, err := PrefetchedLoader(nil, nil).Load(bob.RID); err == nil {
+		t.Fatal("expected an error without fallback loader")
+	}
+}
+
+func TestResultsNumericOverflow(t *testing.T) {
+	var i16 int16
+	testResults(t, int64(1000), &i16, int16(1000))
+	if err := newResults(int64(1 << 40)).All(&i16); err == nil {
+		t.Fatalf("expected an overflow error, got: %v", i16)
+	} else if _, ok := err.(ErrNumericOverflow); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var u32 uint32
+	if err := newResults(int32(-1)).All(&u32); err == nil {
+		t.Fatalf("expected an error for negative value, got: %v", u32)
+	}
+	var i int
+	if err := newResults(float64(1.5)).All(&i); err == nil {
+		t.Fatalf("expected an error for fractional value, got: %v", i)
+	}
+	testResults(t, float64(3), &i, 3)
+
+	type Counter struct {
+		N int16
+	}
+	doc := NewEmptyDocument()
+	doc.SetField("N", int64(1<<40))
+	var c Counter
+	if err := newResults(doc).All(&c); err == nil {
+		t.Fatalf("expected an overflow error for struct field, got: %+v", c)
+	}
+
+	TruncateNumbers = true
+	defer func() { TruncateNumbers = false }()
+	testResults(t, int64(1<<16+1), &i16, int16(1))
+}
+
+func TestResultsStream(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+	recs := []OIdentifiable{
+		documentFrom(map[string]interface{}{"Name": "one"}),
+		documentFrom(map[string]interface{}{"Name": "two"}),
+		documentFrom(map[string]interface{}{"Name": "three"}),
+	}
+	ch := make(chan Item)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- newResults(recs).Stream(ch, nil)
+	}()
+	var names []string
+	for it := range ch {
+		names = append(names, it.Name)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(names) != "[one two three]" {
+		t.Fatalf("wrong records: %v", names)
+	}
+
+	ch = make(chan Item)
+	done := make(chan struct{})
+	go func() {
+		errc <- newResults(recs).Stream(ch, done)
+	}()
+	if it := <-ch; it.Name != "one" {
+		t.Fatalf("wrong record: %+v", it)
+	}
+	close(done)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	} else if _, ok := <-ch; ok {
+		t.Fatal("channel was not closed")
+	}
+
+	if err := newResults(recs).Stream(make(<-chan Item), nil); err == nil {
+		t.Fatal("expected an error for receive-only channel")
+	}
+}
+
+func TestResultsInterfacePreservesTypes(t *testing.T) {
+	emb := NewEmptyDocument()
+	emb.SetField("name", "inner")
+	doc := NewDocument("V")
+	doc.RID = NewRID(9, 1)
+	doc.SetFieldWithType("emb", emb, EMBEDDED)
+	doc.SetFieldWithType("link", NewRID(9, 2), LINK)
+
+	var out interface{}
+	testResults(t, doc, &out, doc)
+	recs := []OIdentifiable{doc, NewRID(9, 2)}
+	testResults(t, recs, &out, recs)
+
+	type Item struct {
+		Emb  interface{}
+		Link interface{}
+	}
+	var it Item
+	if err := newResults(doc).All(&it); err != nil {
+		t.Fatal(err)
+	} else if it.Emb != emb {
+		t.Fatalf("embedded document was not preserved: %T", it.Emb)
+	} else if it.Link != NewRID(9, 2) {
+		t.Fatalf("link was not preserved: %T", it.Link)
+	}
+}
+
+func TestResultsAffectedCount(t *testing.T) {
+	var n int
+	testResults(t, int32(5), &n, 5)
+	if err := newResults([]OIdentifiable{NewDocument("V"), NewDocument("V")}).All(&n); err == nil {
+		t.Fatalf("expected an error for records, got: %d", n)
+	} else if e, ok := err.(ErrNoCount); !ok || e.Records != 2 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := newResults(NewDocument("V")).All(&n); err == nil {
+		t.Fatalf("expected an error for a record, got: %d", n)
+	} else if _, ok := err.(ErrNoCount); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResultsSubqueryProjections(t *testing.T) {
+	// LET variables are returned as links to temporary records, that are sent along with the result
+	var related []ORecord
+	var links []OIdentifiable
+	for i, name := range []string{"Linus", "Keiko"} {
+		proj := NewEmptyDocument()
+		proj.RID = NewRID(-2, int64(i))
+		proj.SetField("name", name)
+		related = append(related, proj)
+		links = append(links, proj.RID)
+	}
+	doc := NewEmptyDocument()
+	doc.SetField("name", "Linus")
+	doc.SetFieldWithType("cats", links, LINKLIST)
+	res := FetchedResult{Result: []OIdentifiable{doc}, Related: related}
+
+	type Cat struct {
+		Name string
+	}
+	type Row struct {
+		Name string
+		Cats []Cat
+	}
+	var rows []Row
+	if err := newResults(res).All(&rows); err != nil {
+		t.Fatal(err)
+	}
+	expect := []Row{{Name: "Linus", Cats: []Cat{{"Linus"}, {"Keiko"}}}}
+	if !reflect.DeepEqual(rows, expect) {
+		t.Fatalf("wrong data: %+v != %+v", rows, expect)
+	}
+
+	type MapRow struct {
+		Cats []map[string]interface{}
+	}
+	var mrows []MapRow
+	if err := newResults(res).All(&mrows); err != nil {
+		t.Fatal(err)
+	}
+	mexpect := []MapRow{{Cats: []map[string]interface{}{{"name": "Linus"}, {"name": "Keiko"}}}}
+	if !reflect.DeepEqual(mrows, mexpect) {
+		t.Fatalf("wrong data: %+v != %+v", mrows, mexpect)
+	}
+}
+
+func TestResultsMatchAliases(t *testing.T) {
+	people := make(map[RID]*Document)
+	for i, name := range []string{"Anna", "Bob"} {
+		doc := NewDocument("Person")
+		doc.RID = NewRID(9, int64(i))
+		doc.SetField("name", name)
+		people[doc.RID] = doc
+	}
+	loader := LinkLoaderFunc(func(rid RID) (*Document, error) {
+		if doc, ok := people[rid]; ok {
+			return doc, nil
+		}
+		return nil, ErrRecordNotFound{RID: rid}
+	})
+	// MATCH ... RETURN person, friend returns links to matched records under alias names
+	row := NewEmptyDocument()
+	row.SetField("person", NewRID(9, 0))
+	row.SetField("friend", NewRID(9, 1))
+	rows := []OIdentifiable{row}
+
+	type Person struct {
+		Name string
+	}
+	type Match struct {
+		Person Person
+		Friend *Person
+	}
+	var matches []Match
+	if err := newLoaderResults(rows, loader).All(&matches); err == nil {
+		t.Fatalf("expected an error for links that are not loaded, got: %+v", matches)
+	}
+	matches = nil
+	if err := newLoaderResults(rows, loader).LoadLinks(1).All(&matches); err != nil {
+		t.Fatal(err)
+	} else if len(matches) != 1 || matches[0].Person.Name != "Anna" || matches[0].Friend == nil || matches[0].Friend.Name != "Bob" {
+		t.Fatalf("wrong data: %+v", matches)
+	}
+	var docs []map[string]*Document
+	if err := newLoaderResults(rows, loader).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 1 || docs[0]["person"].GetIdentity() != NewRID(9, 0) || docs[0]["friend"].GetIdentity() != NewRID(9, 1) {
+		t.Fatalf("wrong data: %+v", docs)
+	} else if fld := docs[0]["friend"].GetField("name"); fld == nil || fld.Value != "Bob" {
+		t.Fatalf("linked document was not loaded: %v", docs[0]["friend"])
+	}
+}
+
+type deserializableItem struct {
+	Name  string
+	Calls int
+}
+
+func (it *deserializableItem) FromDocument(doc *Document) error {
+	if fld := doc.GetField("title"); fld != nil {
+		it.Name, _ = fld.Value.(string)
+	}
+	it.Calls++
+	return nil
+}
+
+type failingItem struct{}
+
+func (*failingItem) FromDocument(doc *Document) error {
+	return fmt.Errorf("cannot decode %v", doc.RID)
+}
+
+func TestResultsDocumentDeserializable(t *testing.T) {
+	doc := documentFrom(map[string]interface{}{"title": "one"})
+	doc2 := documentFrom(map[string]interface{}{"title": "two"})
+	testResults(t, doc, &deserializableItem{}, deserializableItem{Name: "one", Calls: 1})
+	testResults(t, []OIdentifiable{doc, doc2}, &[]*deserializableItem{},
+		[]*deserializableItem{{Name: "one", Calls: 1}, {Name: "two", Calls: 1}})
+
+	type wrapper struct {
+		Item  deserializableItem
+		Items []*deserializableItem
+	}
+	testResults(t, documentFrom(map[string]interface{}{
+		"Item":  map[string]interface{}{"title": "embedded"},
+		"Items": []interface{}{doc},
+	}), &wrapper{}, wrapper{
+		Item:  deserializableItem{Name: "embedded", Calls: 1},
+		Items: []*deserializableItem{{Name: "one", Calls: 1}},
+	})
+
+	var it failingItem
+	if err := newResults(doc).All(&it); err == nil {
+		t.Fatal("expected FromDocument error")
+	}
+}
+
+func TestResultsIdentifiableToRID(t *testing.T) {
+	doc := NewEmptyDocument()
+	doc.RID = NewRID(9, 2)
+	lazy := &LazyLink{RID: NewRID(9, 3)}
+	if lazy.GetRecord() != nil {
+		t.Fatalf("link should not be loaded: %v", lazy.GetRecord())
+	}
+	testResults(t, []OIdentifiable{NewRID(9, 1), doc, lazy}, &[]RID{}, []RID{NewRID(9, 1), NewRID(9, 2), NewRID(9, 3)})
+	testResults(t, []OIdentifiable{doc, lazy}, &[]string{}, []string{"#9:2", "#9:3"})
+}
+
+func TestResultsLazyDocuments(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		loads = make(map[RID]int)
+	)
+	loader := LinkLoaderFunc(func(rid RID) (*Document, error) {
+		mu.Lock()
+		loads[rid]++
+		mu.Unlock()
+		if rid.ClusterPos > 1 {
+			return nil, ErrRecordNotFound{RID: rid}
+		}
+		doc := NewDocument("Person")
+		doc.RID = rid
+		doc.Vers = 3
+		doc.SetField("pos", rid.ClusterPos)
+		return doc, nil
+	})
+	fetched := NewDocument("Person")
+	fetched.RID = NewRID(9, 5)
+	res := FetchedResult{Result: []OIdentifiable{NewRID(9, 0), NewRID(9, 1), NewRID(9, 2), fetched.RID}, Related: []ORecord{fetched}}
+
+	var docs []*Document
+	if err := newLoaderResults(res, loader).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 4 || len(loads) != 0 {
+		t.Fatalf("records should not be loaded yet: %v, %v", docs, loads)
+	} else if docs[3] != fetched {
+		t.Fatalf("pre-fetched record should be returned as is: %v", docs[3])
+	} else if s := docs[0].Dump(); s != "Document <nil> #9:0 v-1 [not loaded]\n" || len(loads) != 0 {
+		t.Fatalf("dump should not load the document: %q", s)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if fld := docs[1].GetField("pos"); fld == nil || fld.Value != int64(1) {
+				t.Errorf("wrong field: %v", fld)
+			}
+		}()
+	}
+	wg.Wait()
+	if docs[1].ClassName() != "Person" || docs[1].Vers != 3 {
+		t.Fatalf("wrong document: %v", docs[1])
+	} else if loads[NewRID(9, 1)] != 1 || loads[NewRID(9, 0)] != 0 {
+		t.Fatalf("wrong loads: %v", loads)
+	}
+	if _, err := docs[2].ToMap(); err == nil {
+		t.Fatal("expected load error")
+	} else if docs[2].GetField("pos") != nil {
+		t.Fatal("failed document should have no fields")
+	} else if _, ok := docs[2].LoadErr().(ErrRecordNotFound); !ok {
+		t.Fatalf("expected load error, got: %v", docs[2].LoadErr())
+	} else if err = docs[0].LoadErr(); err != nil || docs[0].Vers != 3 {
+		t.Fatalf("document was not loaded: %v, %v", err, docs[0])
+	}
+}
+
+type binaryItem struct {
+	Data   []byte
+	Reader io.Reader
+	Buf    *bytes.Buffer
+}
+
+func TestResultsBinary(t *testing.T) {
+	data := []byte{1, 2, 3}
+	doc := NewEmptyDocument()
+	for _, name := range []string{"Data", "Reader", "Buf"} {
+		doc.SetFieldWithType(name, data, BINARY)
+	}
+	var item binaryItem
+	if err := newResults(doc).All(&item); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(item.Data, data) || item.Buf == nil || !bytes.Equal(item.Buf.Bytes(), data) {
+		t.Fatalf("wrong data: %+v", item)
+	} else if item.Reader == nil {
+		t.Fatal("reader is not set")
+	} else if b, err := ioutil.ReadAll(item.Reader); err != nil || !bytes.Equal(b, data) {
+		t.Fatalf("wrong reader data: %v, %v", b, err)
+	}
+
+	proj := NewEmptyDocument()
+	proj.SetFieldWithType("data", data, BINARY)
+	testResults(t, []OIdentifiable{proj}, &[]byte{}, data)
+	var r io.Reader
+	if err := newResults(data).All(&r); err != nil {
+		t.Fatal(err)
+	} else if b, _ := ioutil.ReadAll(r); !bytes.Equal(b, data) {
+		t.Fatalf("wrong reader data: %v", b)
+	}
+}
+
+func TestResultsRecordTypes(t *testing.T) {
+	blob := &BytesRecord{RID: NewRID(9, 1), Data: []byte{1, 2, 3}}
+	flat := &FlatRecord{RID: NewRID(9, 2), Data: "flat"}
+	doc := NewEmptyDocument()
+	doc.SetField("Name", "doc")
+
+	testResults(t, []OIdentifiable{blob}, &[]byte{}, blob.Data)
+	testResults(t, blob, new(string), "\x01\x02\x03")
+	testResults(t, flat, new(string), "flat")
+	testResults(t, blob, new(RID), blob.RID)
+
+	var recs []ORecord
+	if err := newResults([]OIdentifiable{doc, blob, flat}).All(&recs); err != nil {
+		t.Fatal(err)
+	}
+	var types []RecordType
+	for _, r := range recs {
+		types = append(types, r.RecordType())
+	}
+	if exp := []RecordType{RecordTypeDocument, RecordTypeBytes, RecordTypeFlat}; !reflect.DeepEqual(types, exp) {
+		t.Fatalf("wrong record types: %v", types)
+	} else if s := fmt.Sprint(types); s != "[document bytes flat]" {
+		t.Fatalf("wrong record type names: %s", s)
+	}
+
+	var item struct{ Name string }
+	if err := newResults(blob).All(&item); err == nil {
+		t.Fatalf("blob decoded into struct: %+v", item)
+	}
+	var docs []*Document
+	if err := newResults([]OIdentifiable{doc, blob}).All(&docs); err == nil {
+		t.Fatal("blob decoded into document")
+	}
+}
+
+func TestResultsPlainMaps(t *testing.T) {
+	now := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	inner := NewDocument("Address")
+	inner.SetField("city", "Rome")
+	item := NewEmptyDocument()
+	item.SetField("n", int32(1))
+	doc := NewEmptyDocument()
+	doc.SetField("age", int32(30))
+	doc.SetField("score", float64(1.5))
+	doc.SetField("born", now)
+	doc.SetFieldWithType("friend", NewRID(9, 1), LINK)
+	doc.SetFieldWithType("address", inner, EMBEDDED)
+	doc.SetFieldWithType("items", []interface{}{item, "x"}, EMBEDDEDLIST)
+	doc.SetFieldWithType("links", map[string]OIdentifiable{"a": NewRID(9, 2)}, LINKMAP)
+	doc.SetFieldWithType("in_E", &RidBag{delegate: &embeddedRidBag{links: []OIdentifiable{NewRID(10, 1)}}}, LINKBAG)
+
+	var rows []map[string]interface{}
+	testResults(t, []OIdentifiable{doc}, &rows, []map[string]interface{}{{
+		"age":     int32(30),
+		"score":   float64(1.5),
+		"born":    now,
+		"friend":  NewRID(9, 1),
+		"address": map[string]interface{}{"@class": "Address", "city": "Rome"},
+		"items":   []interface{}{map[string]interface{}{"n": int32(1)}, "x"},
+		"links":   map[string]interface{}{"a": NewRID(9, 2)},
+		"in_E":    []interface{}{NewRID(10, 1)},
+	}})
+	if _, err := json.Marshal(rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResultsFieldMapping(t *testing.T) {
+	doc := NewDocument("User")
+	doc.SetField("first_name", "Anna")
+	doc.SetField("login", "ann")
+	sess := &cmdSession{result: []OIdentifiable{doc}}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	db.SetFieldMapping(FieldMapping{
+		NameMapper: CamelToSnake,
+		Renames:    map[string]map[string]string{"User": {"nick": "login"}},
+	})
+	type user struct {
+		FirstName string
+		Nick      string
+	}
+	var users []user
+	if err := db.Command(NewSQLQuery("SELECT FROM User")).All(&users); err != nil {
+		t.Fatal(err)
+	} else if len(users) != 1 || users[0] != (user{"Anna", "ann"}) {
+		t.Fatalf("fields were not mapped: %+v", users)
+	}
+
+	db.SetFieldMapping(FieldMapping{})
+	users = nil
+	if err := db.Command(NewSQLQuery("SELECT FROM User")).All(&users); err != nil {
+		t.Fatal(err)
+	} else if len(users) != 1 || users[0] != (user{}) {
+		t.Fatalf("fields were mapped after reset: %+v", users)
+	}
+}
@@ -0,0 +1,56 @@
+package orient
+
+import "crypto/tls"
+
+// DialOptions is a set of connection settings passed to protocol implementation.
+type DialOptions struct {
+	// TLS enables encrypted connection with given configuration, if not nil.
+	TLS *tls.Config
+	// RecordFormat is a name of record serializer for this connection. Default format is used if empty.
+	RecordFormat string
+	// TokenSession enables token-based database sessions. Server returns a token when database is opened,
+	// and the token is sent with each request instead of relying on a session bound to the connection.
+	TokenSession bool
+	// NodePolicy selects the order of cluster nodes for new connections (see DialCluster).
+	// FirstAvailable is used if nil.
+	NodePolicy NodePolicy
+}
+
+// DialOption configures a connection to OrientDB server.
+type DialOption func(*DialOptions)
+
+// WithTLS enables TLS for connections to the server. If cfg is nil, default configuration is used.
+//
+// Server certificate is always verified, unless cfg.InsecureSkipVerify is set explicitly
+// (which can be useful for development setups with self-signed certificates).
+func WithTLS(cfg *tls.Config) DialOption {
+	return func(o *DialOptions) {
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		o.TLS = cfg
+	}
+}
+
+// WithRecordFormat sets record serialization format for a connection, overriding the default format
+// set with SetDefaultRecordFormat. Format must be registered with RegisterRecordFormat.
+func WithRecordFormat(name string) DialOption {
+	return func(o *DialOptions) {
+		o.RecordFormat = name
+	}
+}
+
+// WithTokenSession enables token-based (stateless) database sessions. See Database.SessionToken.
+func WithTokenSession() DialOption {
+	return func(o *DialOptions) {
+		o.TokenSession = true
+	}
+}
+
+func newDialOptions(opts []DialOption) DialOptions {
+	var o DialOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -8,6 +8,7 @@ import (
 	//	"database/sql/driver"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 var (
@@ -31,6 +32,12 @@ func (fld *DocEntry) String() string {
 	return fmt.Sprintf("{%s(%s): %v}", fld.Name, fld.Type, fld.Value)
 }
 
+// Document is a record with named fields.
+//
+// Documents decoded from links that were not fetched may be lazy stubs: only RID is set until the record is loaded
+// on the first access to it's fields or class name. Vers of such documents is valid only after loading. Accessors
+// like GetField or Fields return empty values if the record cannot be loaded; use LoadErr to load the document
+// explicitly and check for errors.
 type Document struct {
 	BytesRecord
 	serialized  bool
@@ -39,9 +46,21 @@ type Document struct {
 	classname   string // TODO: probably needs to change *OClass (once that is built)
 	dirty       bool
 	ser         RecordSerializer
+	raw         []byte     // record content as received from the server
+	lazy        *lazyLoad  // loads content of a link stub on first access
+	decodeMu    sync.Mutex // serializes decoding of documents shared between goroutines, like pre-fetched links
+}
+
+// LoadErr loads content of a lazy document stub, or decodes a serialized document, and returns an error
+// if this fails. It returns nil for documents that are already loaded.
+func (doc *Document) LoadErr() error {
+	return doc.ensureDecoded()
 }
 
-func (doc *Document) ClassName() string { return doc.classname }
+func (doc *Document) ClassName() string {
+	doc.ensureLoaded()
+	return doc.classname
+}
 
 // NewDocument should be called to create new Document objects,
 // since some internal data structures need to be initialized
@@ -80,6 +99,11 @@ func (doc *Document) ensureDecoded() error {
 	if doc == nil {
 		return fmt.Errorf("nil document")
 	}
+	if err := doc.ensureLoaded(); err != nil {
+		return err
+	}
+	doc.decodeMu.Lock()
+	defer doc.decodeMu.Unlock()
 	if !doc.serialized {
 		return nil
 	}
@@ -128,7 +152,8 @@ func (doc *Document) GetRecord() interface{} {
 }
 
 // FieldNames returns the names of all the fields currently in this Document
-// in "entry order". These fields may not have already been committed to the database.
+// in "entry order": the order in which fields were set, or the order of fields in serialized record
+// for documents read from the database. These fields may not have already been committed to the database.
 func (doc *Document) FieldNames() []string {
 	doc.ensureDecoded()
 	names := make([]string, len(doc.fieldsOrder))
@@ -162,12 +187,14 @@ func (doc *Document) GetField(fname string) *DocEntry {
 }
 
 // AddField adds a fully created field directly rather than by some of its
-// attributes, as the other "Field" methods do.
+// attributes, as the other "Field" methods do. Replaced fields keep their position in "entry order".
 // The same *Document is returned to allow call chaining.
 func (doc *Document) AddField(name string, field *DocEntry) *Document {
 	doc.ensureDecoded()
+	if _, ok := doc.fields[name]; !ok {
+		doc.fieldsOrder = append(doc.fieldsOrder, name)
+	}
 	doc.fields[name] = field
-	doc.fieldsOrder = append(doc.fieldsOrder, name)
 	doc.dirty = true
 	return doc
 }
@@ -182,6 +209,9 @@ func (doc *Document) SetDirty(b bool) {
 // The same *Document is returned to allow call chaining.
 func (doc *Document) SetField(name string, val interface{}) *Document {
 	doc.ensureDecoded()
+	if rv := reflect.ValueOf(val); rv.IsValid() && isSetMapType(rv.Type()) {
+		val = setFromMap(rv)
+	}
 	return doc.SetFieldWithType(name, val, OTypeForValue(val))
 }
 
@@ -260,7 +290,8 @@ func (doc *Document) String() string {
 		panic(err)
 	}
 
-	for _, fld := range doc.fields {
+	for _, name := range doc.fieldsOrder {
+		fld := doc.fields[name]
 		_, err = buf.WriteString(fmt.Sprintf("  %s,\n", fld.String()))
 		if err != nil {
 			panic(err)
@@ -271,6 +302,8 @@ func (doc *Document) String() string {
 	return buf.String()
 }
 
+// ToMap returns document fields as a map. Metadata is stored under "@class" key and, for persistent records,
+// under "@rid" and "@version" keys, so it can be decoded into struct fields tagged with these names.
 func (doc *Document) ToMap() (map[string]interface{}, error) {
 	if doc == nil {
 		return nil, nil
@@ -287,6 +320,7 @@ func (doc *Document) ToMap() (map[string]interface{}, error) {
 	}
 	if doc.RID.IsPersistent() { // TODO: is this correct?
 		out["@rid"] = doc.RID
+		out["@version"] = doc.Vers
 	}
 	return out, nil
 }
@@ -322,11 +356,22 @@ func (doc *Document) SetSerializer(ser RecordSerializer) {
 	doc.ser = ser
 }
 func (doc *Document) Fill(rid RID, version int, content []byte) error {
+	doc.raw = content
 	doc.serialized = doc.serialized || doc.BytesRecord.Data == nil || bytes.Compare(content, doc.BytesRecord.Data) != 0
 	return doc.BytesRecord.Fill(rid, version, content)
 }
 func (doc *Document) RecordType() RecordType { return RecordTypeDocument }
 
+// RawBytes returns serialized record content exactly as it was received from the server (or sent to it on create
+// and update), which is useful for debugging serialization issues. It returns nil for documents created on the client.
+// Returned slice must not be modified.
+func (doc *Document) RawBytes() []byte {
+	if doc == nil {
+		return nil
+	}
+	return doc.raw
+}
+
 // ToDocument implement DocumentSerializable interface. In this case, Document just returns itself.
 func (doc *Document) ToDocument() (*Document, error) {
 	return doc, nil
@@ -341,7 +386,7 @@ func (doc *Document) ToStruct(o interface{}) error {
 	return mapToStruct(mp, o)
 }
 
-func (doc *Document) setFieldsFrom(rv reflect.Value) error {
+func (doc *Document) setFieldsFrom(rv reflect.Value, fm *FieldMapping) error {
 	switch rv.Kind() {
 	case reflect.Struct:
 		rt := rv.Type()
@@ -351,18 +396,51 @@ func (doc *Document) setFieldsFrom(rv reflect.Value) error {
 				continue
 			}
 			name := fld.Name
+			otags := fld.Tag.Get(OrientTagName)
+			if hasTagOption(otags, "extra") {
+				if extra, ok := rv.Field(i).Interface().(map[string]interface{}); ok {
+					for k, v := range extra {
+						doc.SetField(k, v)
+					}
+					continue
+				}
+			}
 			tags := strings.Split(fld.Tag.Get(TagName), ",")
+			if otag := strings.Split(otags, ",")[0]; otag != "" {
+				if strings.HasPrefix(otag, "@") {
+					doc.setMetadata(otag, rv.Field(i))
+					continue
+				}
+				tags[0] = otag
+			}
 			if tags[0] == "-" {
 				continue
 			}
 			if tags[0] != "" {
 				name = tags[0]
+			} else if mapped, ok := fm.fieldName(fld); ok {
+				name = mapped
 			}
+			name = fm.renamed(doc.classname, name)
 			squash := (len(tags) > 1 && tags[1] == "squash") // TODO: change default behavior to squash if field is anonymous
 			if squash {
-				if err := doc.setFieldsFrom(rv.Field(i)); err != nil {
+				if err := doc.setFieldsFrom(rv.Field(i), fm); err != nil {
+					return fmt.Errorf("field '%s': %s", name, err)
+				}
+			} else if class, ok := tagOptionValue(otags, "class"); ok {
+				val, tp, err := embeddedWithClass(rv.Field(i), class, fm)
+				if err != nil {
 					return fmt.Errorf("field '%s': %s", name, err)
 				}
+				doc.SetFieldWithType(name, val, tp)
+			} else if tp, ok, err := tagFieldType(otags); err != nil {
+				return fmt.Errorf("field '%s': %s", name, err)
+			} else if ok {
+				val, err := pinnedValue(rv.Field(i), tp)
+				if err != nil {
+					return fmt.Errorf("field '%s': %s", name, err)
+				}
+				doc.SetFieldWithType(name, val, tp)
 			} else {
 				doc.SetField(name, rv.Field(i).Interface())
 			}
@@ -378,11 +456,91 @@ func (doc *Document) setFieldsFrom(rv reflect.Value) error {
 	}
 }
 
+// embeddedWithClass converts a struct or a map (or a slice of them) to embedded documents with a given class name.
+// Values that set their own class keep it. Other values are returned as is.
+func embeddedWithClass(v reflect.Value, class string, fm *FieldMapping) (interface{}, OType, error) {
+	if d, ok := v.Interface().(*Document); ok {
+		return d, EMBEDDED, nil
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, EMBEDDED, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		edoc := NewEmptyDocument()
+		if err := edoc.setFieldsFrom(v, fm); err != nil {
+			return nil, UNKNOWN, err
+		}
+		if edoc.classname == "" {
+			edoc.classname = class
+		}
+		return edoc, EMBEDDED, nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 { // binary data
+			break
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			d, _, err := embeddedWithClass(v.Index(i), class, fm)
+			if err != nil {
+				return nil, UNKNOWN, err
+			}
+			out[i] = d
+		}
+		return out, EMBEDDEDLIST, nil
+	}
+	val := v.Interface()
+	return val, OTypeForValue(val), nil
+}
+
+// pinnedValue converts a numeric value to a Go type of OrientDB type pinned by a struct tag.
+func pinnedValue(v reflect.Value, tp OType) (interface{}, error) {
+	if rt := tp.ReflectType(); isNumberKind(v.Kind()) && isNumberKind(rt.Kind()) {
+		out, err := convertNumber(v, rt)
+		if err != nil {
+			return nil, err
+		}
+		return out.Interface(), nil
+	}
+	return v.Interface(), nil
+}
+
+// setMetadata sets document RID, version or class from a struct field with metadata tag.
+// Empty values are ignored, so a document created from a new struct stays non-persistent.
+func (doc *Document) setMetadata(name string, v reflect.Value) {
+	switch name {
+	case "@rid":
+		if rid, ok := v.Interface().(RID); ok && rid.IsValid() {
+			doc.RID = rid
+		}
+	case "@version":
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Int() != 0 {
+				doc.Vers = int(v.Int())
+			}
+		}
+	case "@class":
+		if v.Kind() == reflect.String && v.String() != "" && doc.classname == "" {
+			doc.classname = v.String()
+		}
+	}
+}
+
 // From sets Document fields to values provided in argument (which can be a map or a struct).
 //
 // From uses TagName field tag to determine field name and conversion parameters.
 // For now it supports only one special tag parameter: ",squash" which can be used to inline fields into parent struct.
+// OrientTagName tag overrides field name, and fields tagged with "@rid", "@version" or "@class" set document metadata.
+// The "type" option of OrientTagName tag sets an OrientDB type of the field.
 func (doc *Document) From(o interface{}) error {
+	return doc.from(o, nil)
+}
+
+func (doc *Document) from(o interface{}, fm *FieldMapping) error {
 	// TODO: clear fields and serialized data
 	if o == nil {
 		return nil
@@ -391,7 +549,26 @@ func (doc *Document) From(o interface{}) error {
 	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
 		rv = rv.Elem()
 	}
-	return doc.setFieldsFrom(rv)
+	return doc.setFieldsFrom(rv, fm)
+}
+
+// ToDocument creates a new Document from a struct or a map. See Document.From for supported field tags.
+func ToDocument(v interface{}) (*Document, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("cannot create document from nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if k := rv.Kind(); k != reflect.Struct && k != reflect.Map {
+		return nil, fmt.Errorf("only maps and structs are supported, got: %T", v)
+	}
+	doc := NewEmptyDocument()
+	if err := doc.setFieldsFrom(rv, nil); err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
 /*
@@ -409,17 +586,13 @@ func (doc *Document) Scan(src interface{}) error {
 // Implements database/sql/driver.Valuer interface
 // TODO: haven't detected when this is called yet (probably when serializing Document for insertion into DB??)
 func (doc *Document) Value() (driver.Value, error) {
-	if glog.V(10) {
-		glog.Infoln("** Document.Value")
-	}
+	Logf("** Document.Value")
 	return []byte(`{"b": 2}`), nil // FIXME: bogus
 }
 
 // Implements database/sql/driver.ValueConverter interface
 // TODO: haven't detected when this is called yet
 func (doc *Document) ConvertValue(v interface{}) (driver.Value, error) {
-	if glog.V(10) {
-		glog.Infof("** Document.ConvertValue: %T: %v", v, v)
-	}
+	Logf("** Document.ConvertValue: %T: %v", v, v)
 	return []byte(`{"a": 1}`), nil // FIXME: bogus
 }*/
@@ -0,0 +1,94 @@
+package orient
+
+// DocumentBuilder builds a document field by field, setting field types required for serialization of links
+// and collections:
+//
+//		doc := orient.NewDocumentBuilder("Person").
+//			Set("name", "Bob").
+//			Set("age", int32(30)).
+//			SetLink("manager", rid).
+//			Build()
+type DocumentBuilder struct {
+	doc *Document
+}
+
+// NewDocumentBuilder starts building a new document of a given class. Class can be empty.
+func NewDocumentBuilder(class string) *DocumentBuilder {
+	return &DocumentBuilder{doc: NewDocument(class)}
+}
+
+// Build returns a document with all fields set so far. Builder must not be used after this call.
+func (b *DocumentBuilder) Build() *Document {
+	return b.doc
+}
+
+// Set sets a field with a type detected from the value, as Document.SetField does.
+func (b *DocumentBuilder) Set(name string, val interface{}) *DocumentBuilder {
+	b.doc.SetField(name, val)
+	return b
+}
+
+// SetWithType sets a field with a given type.
+func (b *DocumentBuilder) SetWithType(name string, val interface{}, tp OType) *DocumentBuilder {
+	b.doc.SetFieldWithType(name, val, tp)
+	return b
+}
+
+// SetLink sets a LINK field. Link can be a RID or a document.
+func (b *DocumentBuilder) SetLink(name string, link OIdentifiable) *DocumentBuilder {
+	return b.SetWithType(name, link, LINK)
+}
+
+// SetLinkList sets a LINKLIST field.
+func (b *DocumentBuilder) SetLinkList(name string, links ...OIdentifiable) *DocumentBuilder {
+	return b.SetWithType(name, linksOrEmpty(links), LINKLIST)
+}
+
+// SetLinkSet sets a LINKSET field.
+func (b *DocumentBuilder) SetLinkSet(name string, links ...OIdentifiable) *DocumentBuilder {
+	return b.SetWithType(name, linksOrEmpty(links), LINKSET)
+}
+
+// SetLinkMap sets a LINKMAP field.
+func (b *DocumentBuilder) SetLinkMap(name string, links map[string]OIdentifiable) *DocumentBuilder {
+	if links == nil {
+		links = make(map[string]OIdentifiable)
+	}
+	return b.SetWithType(name, links, LINKMAP)
+}
+
+// SetEmbedded sets an EMBEDDED field. Value can be a document, a struct or a map.
+func (b *DocumentBuilder) SetEmbedded(name string, val interface{}) *DocumentBuilder {
+	return b.SetWithType(name, val, EMBEDDED)
+}
+
+// SetEmbeddedList sets an EMBEDDEDLIST field.
+func (b *DocumentBuilder) SetEmbeddedList(name string, items ...interface{}) *DocumentBuilder {
+	if items == nil {
+		items = []interface{}{}
+	}
+	return b.SetWithType(name, items, EMBEDDEDLIST)
+}
+
+// SetEmbeddedSet sets an EMBEDDEDSET field.
+func (b *DocumentBuilder) SetEmbeddedSet(name string, items ...interface{}) *DocumentBuilder {
+	if items == nil {
+		items = []interface{}{}
+	}
+	return b.SetWithType(name, items, EMBEDDEDSET)
+}
+
+// SetEmbeddedMap sets an EMBEDDEDMAP field.
+func (b *DocumentBuilder) SetEmbeddedMap(name string, m map[string]interface{}) *DocumentBuilder {
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	return b.SetWithType(name, m, EMBEDDEDMAP)
+}
+
+func linksOrEmpty(links []OIdentifiable) []OIdentifiable {
+	if links == nil {
+		return []OIdentifiable{}
+	}
+	return links
+}
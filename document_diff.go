@@ -0,0 +1,157 @@
+package orient
+
+import (
+	"math/big"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// FieldChange describes a difference of a single field between two documents.
+// Old is nil if the field was added, and New is nil if it was removed.
+type FieldChange struct {
+	Name string
+	Old  *DocEntry
+	New  *DocEntry
+}
+
+// Equal checks if two documents have the same fields with equal values, regardless of field order.
+// Document metadata (RID, version and class) is ignored. See Diff for details on values comparison.
+func (doc *Document) Equal(other *Document) bool {
+	return len(doc.Diff(other)) == 0
+}
+
+// Diff returns changes of fields required to get other document from this one, sorted by field name.
+// Document metadata (RID, version and class) is ignored.
+//
+// Values are compared recursively, including embedded documents, collections and maps. Numbers are equal if they
+// have the same value, regardless of Go type, so int32(1) is equal to int64(1) and to float64(1). Field types are
+// not compared.
+func (doc *Document) Diff(other *Document) []FieldChange {
+	a, b := docFields(doc), docFields(other)
+	var out []FieldChange
+	for name, fa := range a {
+		if fb, ok := b[name]; !ok {
+			out = append(out, FieldChange{Name: name, Old: fa})
+		} else if !valuesEqual(fa.Value, fb.Value) {
+			out = append(out, FieldChange{Name: name, Old: fa, New: fb})
+		}
+	}
+	for name, fb := range b {
+		if _, ok := a[name]; !ok {
+			out = append(out, FieldChange{Name: name, New: fb})
+		}
+	}
+	sort.Sort(fieldChangesByName(out))
+	return out
+}
+
+type fieldChangesByName []FieldChange
+
+func (a fieldChangesByName) Len() int           { return len(a) }
+func (a fieldChangesByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a fieldChangesByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+func docFields(doc *Document) map[string]*DocEntry {
+	if doc == nil {
+		return nil
+	}
+	return doc.Fields()
+}
+
+// valuesEqual compares two field values as described in Document.Diff.
+func valuesEqual(a, b interface{}) bool {
+	return reflValuesEqual(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func reflValuesEqual(a, b reflect.Value) bool {
+	for a.IsValid() && (a.Kind() == reflect.Interface || a.Kind() == reflect.Ptr) && !a.IsNil() && a.Type() != reflDocumentType {
+		a = a.Elem()
+	}
+	for b.IsValid() && (b.Kind() == reflect.Interface || b.Kind() == reflect.Ptr) && !b.IsNil() && b.Type() != reflDocumentType {
+		b = b.Elem()
+	}
+	if !a.IsValid() || !b.IsValid() || isNilValue(a) || isNilValue(b) {
+		return isNilValue(a) && isNilValue(b)
+	}
+	switch {
+	case a.Type() == reflDocumentType || b.Type() == reflDocumentType:
+		da, ok1 := a.Interface().(*Document)
+		db, ok2 := b.Interface().(*Document)
+		return ok1 && ok2 && len(da.Diff(db)) == 0
+	case isNumberKind(a.Kind()) && isNumberKind(b.Kind()):
+		return numbersEqual(a, b)
+	case a.Type() == reflDecimalType && b.Type() == reflDecimalType:
+		return decimalsEqual(a.Interface().(Decimal), b.Interface().(Decimal))
+	case a.Type() == reflTimeType && b.Type() == reflTimeType:
+		return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	case isListKind(a.Kind()) && isListKind(b.Kind()):
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !reflValuesEqual(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case a.Kind() == reflect.Map && b.Kind() == reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		kt := b.Type().Key()
+		for _, k := range a.MapKeys() {
+			if !k.Type().ConvertibleTo(kt) {
+				return false
+			}
+			v := b.MapIndex(k.Convert(kt))
+			if !v.IsValid() || !reflValuesEqual(a.MapIndex(k), v) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func isNilValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+func isListKind(k reflect.Kind) bool {
+	return k == reflect.Slice || k == reflect.Array
+}
+
+func numbersEqual(a, b reflect.Value) bool {
+	isFloat := func(k reflect.Kind) bool { return k == reflect.Float32 || k == reflect.Float64 }
+	isInt := func(k reflect.Kind) bool { return k >= reflect.Int && k <= reflect.Int64 }
+	switch ka, kb := a.Kind(), b.Kind(); {
+	case isFloat(ka) || isFloat(kb):
+		return a.Convert(reflect.TypeOf(float64(0))).Float() == b.Convert(reflect.TypeOf(float64(0))).Float()
+	case isInt(ka) && isInt(kb):
+		return a.Int() == b.Int()
+	case isInt(ka):
+		return a.Int() >= 0 && uint64(a.Int()) == b.Uint()
+	case isInt(kb):
+		return b.Int() >= 0 && uint64(b.Int()) == a.Uint()
+	default:
+		return a.Uint() == b.Uint()
+	}
+}
+
+func decimalsEqual(a, b Decimal) bool {
+	if a.Value == nil || b.Value == nil {
+		return a.Value == b.Value
+	}
+	// a.Value / 10^a.Scale == b.Value / 10^b.Scale
+	x := new(big.Int).Mul(a.Value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(b.Scale)), nil))
+	y := new(big.Int).Mul(b.Value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(a.Scale)), nil))
+	return x.Cmp(y) == 0
+}
@@ -0,0 +1,140 @@
+package orient
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dump returns a readable tree representation of the document for debugging. It includes class name, RID
+// and version of the document, and names, types and values of all the fields. Embedded documents, fetched
+// links, collections and maps are printed recursively with indentation:
+//
+//		Document Person #9:1 v3
+//		  name (STRING): "Anna"
+//		  address (EMBEDDED): Document Address #-1:-1 v-1
+//		    city (STRING): "Paris"
+//		  tags (EMBEDDEDLIST): [2]
+//		    [0]: "a"
+//		    [1]: "b"
+//
+// Serialized documents, like ones returned by Load or Command, are decoded first, and decoding errors are printed
+// in place of fields. Lazy links are not loaded. Documents that are already printed higher in the tree are not
+// printed again, so cyclic links are safe to dump.
+func (doc *Document) Dump() string {
+	d := &docDumper{seen: make(map[*Document]bool)}
+	d.document(doc, 0)
+	d.buf.WriteByte('\n')
+	return d.buf.String()
+}
+
+type docDumper struct {
+	buf  bytes.Buffer
+	seen map[*Document]bool // documents on the current path
+}
+
+func (d *docDumper) line(depth int, format string, args ...interface{}) {
+	d.buf.WriteByte('\n')
+	d.buf.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(&d.buf, format, args...)
+}
+
+func (d *docDumper) document(doc *Document, depth int) {
+	if doc == nil {
+		d.buf.WriteString("null")
+		return
+	}
+	var state string
+	switch {
+	case d.seen[doc]:
+		state = " [cycle]"
+	case doc.lazy != nil && !doc.lazy.attempted():
+		state = " [not loaded]"
+	case doc.lazy != nil && doc.lazy.err != nil:
+		state = fmt.Sprintf(" [load error: %v]", doc.lazy.err)
+	case doc.serialized:
+		if err := doc.ensureDecoded(); err != nil {
+			state = fmt.Sprintf(" [serialized, %d bytes, decode error: %v]", len(doc.BytesRecord.Data), err)
+		}
+	}
+	class := doc.classname
+	if class == "" {
+		class = "<nil>"
+	}
+	fmt.Fprintf(&d.buf, "Document %s %s v%d%s", class, doc.RID, doc.Vers, state)
+	if state != "" {
+		return
+	}
+	d.seen[doc] = true
+	defer delete(d.seen, doc)
+	for _, name := range doc.fieldsOrder {
+		fld := doc.fields[name]
+		d.line(depth+1, "%s (%s): ", fld.Name, fld.Type)
+		d.value(fld.Value, depth+1)
+	}
+}
+
+func (d *docDumper) value(v interface{}, depth int) {
+	switch val := v.(type) {
+	case nil:
+		d.buf.WriteString("null")
+		return
+	case *Document:
+		d.document(val, depth)
+		return
+	case RID:
+		d.buf.WriteString(val.String())
+		return
+	case OIdentifiable:
+		fmt.Fprintf(&d.buf, "%s (%T)", val.GetIdentity(), val)
+		return
+	case string:
+		fmt.Fprintf(&d.buf, "%q", val)
+		return
+	case []byte:
+		fmt.Fprintf(&d.buf, "[%d bytes]", len(val))
+		return
+	case time.Time:
+		d.buf.WriteString(val.Format(time.RFC3339Nano))
+		return
+	case *RidBag:
+		if val.IsRemote() {
+			d.buf.WriteString("RidBag [remote]")
+			return
+		}
+		var rids []RID
+		next := val.Iterate(nil)
+		for rid, ok := next(); ok; rid, ok = next() {
+			rids = append(rids, rid)
+		}
+		fmt.Fprintf(&d.buf, "RidBag [%d]", len(rids))
+		for i, rid := range rids {
+			d.line(depth+1, "[%d]: %s", i, rid)
+		}
+		return
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		fmt.Fprintf(&d.buf, "[%d]", rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			d.line(depth+1, "[%d]: ", i)
+			d.value(rv.Index(i).Interface(), depth+1)
+		}
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		fmt.Fprintf(&d.buf, "{%d}", len(keys))
+		for _, k := range keys {
+			d.line(depth+1, "%v: ", k.Interface())
+			d.value(rv.MapIndex(k).Interface(), depth+1)
+		}
+	default:
+		fmt.Fprintf(&d.buf, "%v (%T)", v, v)
+	}
+}
@@ -0,0 +1,689 @@
+package orient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONOption is an option for Document.ToJSON.
+type JSONOption func(*jsonOptions)
+
+type jsonOptions struct {
+	meta   bool // write @type, @rid, @version, @class and @fieldTypes
+	expand bool // write fetched links as nested documents
+}
+
+// JSONMetadata controls whether @type, @rid, @version, @class and @fieldTypes fields are written. Enabled by default.
+func JSONMetadata(on bool) JSONOption {
+	return func(o *jsonOptions) { o.meta = on }
+}
+
+// JSONExpandLinks controls whether linked documents that were fetched are written inline instead of #N:M strings.
+// Disabled by default. Links that form a cycle are always written as strings.
+func JSONExpandLinks(on bool) JSONOption {
+	return func(o *jsonOptions) { o.expand = on }
+}
+
+// jsonFieldTypes maps field types to @fieldTypes codes used by OrientDB to restore types that cannot be inferred from JSON.
+var jsonFieldTypes = map[OType]byte{
+	FLOAT:       'f',
+	DECIMAL:     'c',
+	LONG:        'l',
+	DOUBLE:      'd',
+	BYTE:        'b',
+	DATE:        'a',
+	DATETIME:    't',
+	SHORT:       's',
+	EMBEDDEDSET: 'e',
+	LINKBAG:     'g',
+	LINKLIST:    'z',
+	LINKMAP:     'm',
+	LINK:        'x',
+	LINKSET:     'n',
+}
+
+// ToJSON encodes document to OrientDB JSON format, as returned by REST API and ODocument.toJSON in Java:
+//
+//		{"@type":"d","@rid":"#9:1","@version":1,"@class":"Person","name":"Bob","friend":"#9:2","@fieldTypes":"friend=x"}
+//
+// Links are written as #N:M strings, dates as milliseconds since epoch, and binary data as base64 strings.
+// Embedded documents are encoded recursively.
+func (doc *Document) ToJSON(opts ...JSONOption) ([]byte, error) {
+	o := jsonOptions{meta: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	w := &jsonWriter{opts: o, expanding: make(map[RID]bool)}
+	if doc != nil && doc.RID.IsPersistent() {
+		w.expanding[doc.RID] = true
+	}
+	if err := w.writeDoc(doc, false); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+type jsonWriter struct {
+	opts      jsonOptions
+	buf       bytes.Buffer
+	expanding map[RID]bool // linked documents that are being written, to break cycles
+	content   bool         // write only fields and @fieldTypes of the next document
+}
+
+// contentJSON encodes document fields for INSERT ... CONTENT and UPDATE ... CONTENT commands.
+// Record identity and class are not written.
+func contentJSON(doc *Document) ([]byte, error) {
+	w := &jsonWriter{opts: jsonOptions{meta: true}, expanding: make(map[RID]bool), content: true}
+	if err := w.writeDoc(doc, false); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+func (w *jsonWriter) writeString(s string) {
+	data, _ := json.Marshal(s)
+	w.buf.Write(data)
+}
+
+func (w *jsonWriter) writeDoc(doc *Document, embedded bool) error {
+	if doc == nil {
+		w.buf.WriteString("null")
+		return nil
+	}
+	if err := doc.ensureDecoded(); err != nil {
+		return err
+	}
+	content := w.content
+	w.content = false
+	first := true
+	key := func(name string) {
+		if !first {
+			w.buf.WriteByte(',')
+		}
+		first = false
+		w.writeString(name)
+		w.buf.WriteByte(':')
+	}
+	w.buf.WriteByte('{')
+	if w.opts.meta && !content {
+		key("@type")
+		w.writeString("d")
+		if !embedded {
+			key("@rid")
+			w.writeString(doc.RID.String())
+			key("@version")
+			fmt.Fprint(&w.buf, doc.Vers)
+		}
+		if doc.classname != "" {
+			key("@class")
+			w.writeString(doc.classname)
+		}
+	}
+	var types []string
+	for _, name := range doc.fieldsOrder {
+		fld := doc.fields[name]
+		if fld == nil {
+			continue
+		}
+		key(name)
+		if err := w.writeField(fld.Type, fld.Value); err != nil {
+			return fmt.Errorf("field %q: %v", name, err)
+		}
+		if c, ok := jsonFieldTypes[fld.Type]; ok && fld.Value != nil {
+			types = append(types, name+"="+string(c))
+		}
+	}
+	if w.opts.meta && len(types) != 0 {
+		key("@fieldTypes")
+		w.writeString(strings.Join(types, ","))
+	}
+	w.buf.WriteByte('}')
+	return nil
+}
+
+// writeLink writes a link as #N:M string, or as a nested document if it was fetched and links expansion is enabled.
+func (w *jsonWriter) writeLink(v OIdentifiable) error {
+	if v == nil {
+		w.buf.WriteString("null")
+		return nil
+	}
+	rid := v.GetIdentity()
+	if doc, ok := v.(*Document); ok && doc != nil && w.opts.expand && !w.expanding[rid] &&
+		(doc.serialized || len(doc.fields) != 0) {
+		w.expanding[rid] = true
+		defer delete(w.expanding, rid)
+		return w.writeDoc(doc, false)
+	}
+	w.writeString(rid.String())
+	return nil
+}
+
+func (w *jsonWriter) writeField(tp OType, v interface{}) error {
+	if v == nil {
+		w.buf.WriteString("null")
+		return nil
+	}
+	switch tp {
+	case LINK:
+		if id, ok := v.(OIdentifiable); ok {
+			return w.writeLink(id)
+		}
+	case LINKLIST, LINKSET:
+		if ids, ok := v.([]OIdentifiable); ok {
+			w.buf.WriteByte('[')
+			for i, id := range ids {
+				if i != 0 {
+					w.buf.WriteByte(',')
+				}
+				if err := w.writeLink(id); err != nil {
+					return err
+				}
+			}
+			w.buf.WriteByte(']')
+			return nil
+		}
+	case LINKMAP:
+		if ids, ok := v.(map[string]OIdentifiable); ok {
+			keys := make([]string, 0, len(ids))
+			for k := range ids {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			w.buf.WriteByte('{')
+			for i, k := range keys {
+				if i != 0 {
+					w.buf.WriteByte(',')
+				}
+				w.writeString(k)
+				w.buf.WriteByte(':')
+				if err := w.writeLink(ids[k]); err != nil {
+					return err
+				}
+			}
+			w.buf.WriteByte('}')
+			return nil
+		}
+	case LINKBAG:
+		if bag, ok := v.(*RidBag); ok {
+			emb, ok := bag.delegate.(*embeddedRidBag)
+			if !ok {
+				return fmt.Errorf("remote link bag cannot be encoded to JSON")
+			}
+			return w.writeField(LINKLIST, emb.links)
+		}
+	case DATE, DATETIME:
+		if t, ok := v.(time.Time); ok {
+			fmt.Fprint(&w.buf, t.UnixNano()/int64(time.Millisecond))
+			return nil
+		}
+	}
+	return w.writeValue(v)
+}
+
+// writeValue writes a value of unknown type, such as an item of embedded collection.
+func (w *jsonWriter) writeValue(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		w.buf.WriteString("null")
+		return nil
+	case *Document:
+		if v != nil && !v.serialized && len(v.fields) == 0 && v.RID.IsPersistent() {
+			return w.writeLink(v)
+		}
+		return w.writeDoc(v, true)
+	case RID:
+		w.writeString(v.String())
+		return nil
+	case time.Time:
+		fmt.Fprint(&w.buf, v.UnixNano()/int64(time.Millisecond))
+		return nil
+	case Decimal:
+		w.buf.WriteString(decimalString(v))
+		return nil
+	case *big.Int:
+		w.buf.WriteString(v.String())
+		return nil
+	case []byte:
+		data, _ := json.Marshal(v)
+		w.buf.Write(data)
+		return nil
+	case DocumentSerializable:
+		doc, err := v.ToDocument()
+		if err != nil {
+			return err
+		}
+		return w.writeDoc(doc, true)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		w.buf.WriteByte('[')
+		for i := 0; i < rv.Len(); i++ {
+			if i != 0 {
+				w.buf.WriteByte(',')
+			}
+			if err := w.writeValue(rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte(']')
+		return nil
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		vals := make(map[string]reflect.Value, rv.Len())
+		for _, k := range rv.MapKeys() {
+			sk := fmt.Sprint(k.Interface())
+			keys = append(keys, sk)
+			vals[sk] = rv.MapIndex(k)
+		}
+		sort.Strings(keys)
+		w.buf.WriteByte('{')
+		for i, k := range keys {
+			if i != 0 {
+				w.buf.WriteByte(',')
+			}
+			w.writeString(k)
+			w.buf.WriteByte(':')
+			if err := w.writeValue(vals[k].Interface()); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte('}')
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.buf.Write(data)
+	return nil
+}
+
+// decimalString formats decimal value as a plain JSON number.
+func decimalString(d Decimal) string {
+	if d.Value == nil {
+		return "0"
+	}
+	s := new(big.Int).Abs(d.Value).String()
+	if d.Scale > 0 {
+		if len(s) <= d.Scale {
+			s = strings.Repeat("0", d.Scale-len(s)+1) + s
+		}
+		s = s[:len(s)-d.Scale] + "." + s[len(s)-d.Scale:]
+	} else if d.Scale < 0 {
+		s += strings.Repeat("0", -d.Scale)
+	}
+	if d.Value.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// jsonObject is a JSON object with keys in the original order.
+type jsonObject struct {
+	keys []string
+	vals map[string]interface{}
+}
+
+// readJSONValue reads a single JSON value. Objects are returned as *jsonObject, arrays as []interface{}
+// and numbers as json.Number.
+func readJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &jsonObject{vals: make(map[string]interface{})}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := tok.(string)
+			val, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := obj.vals[key]; !ok {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.vals[key] = val
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			val, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// ParseDocumentJSON creates a document from OrientDB JSON, as produced by Document.ToJSON or REST API.
+//
+// Metadata fields (@class, @rid, @version) are restored, and @fieldTypes is used to restore field types.
+// Other fields are typed according to JSON values: strings in #N:M form become links, integer numbers become
+// INTEGER or LONG, other numbers are DOUBLE, objects with metadata fields are embedded documents (or links,
+// if they have a persistent RID), other objects are embedded maps, and arrays are embedded lists, or link lists
+// if all items are links.
+func ParseDocumentJSON(data []byte) (*Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	val, err := readJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := val.(*jsonObject)
+	if !ok {
+		return nil, fmt.Errorf("expected JSON object, got %T", val)
+	}
+	return jsonToDocument(obj)
+}
+
+func jsonToDocument(obj *jsonObject) (*Document, error) {
+	doc := NewEmptyDocument()
+	if s, ok := obj.vals["@class"].(string); ok {
+		doc.classname = s
+	}
+	if s, ok := obj.vals["@rid"].(string); ok {
+		rid, err := ParseRID(s)
+		if err != nil {
+			return nil, err
+		}
+		doc.RID = rid
+	}
+	if n, ok := obj.vals["@version"].(json.Number); ok {
+		v, err := n.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid record version: %v", n)
+		}
+		doc.Vers = int(v)
+	}
+	types := make(map[string]byte)
+	if s, ok := obj.vals["@fieldTypes"].(string); ok {
+		for _, ft := range strings.Split(s, ",") {
+			if i := strings.IndexByte(ft, '='); i > 0 && i == len(ft)-2 {
+				types[ft[:i]] = ft[i+1]
+			}
+		}
+	}
+	for _, name := range obj.keys {
+		if strings.HasPrefix(name, "@") {
+			continue
+		}
+		val, tp, err := jsonToField(obj.vals[name], types[name])
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", name, err)
+		}
+		doc.SetFieldWithType(name, val, tp)
+	}
+	doc.SetDirty(false)
+	return doc, nil
+}
+
+// isJSONDocument checks if object is a document, rather than embedded map.
+func isJSONDocument(obj *jsonObject) bool {
+	for _, k := range []string{"@type", "@class", "@rid"} {
+		if _, ok := obj.vals[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonToLink converts #N:M string or linked document to OIdentifiable.
+func jsonToLink(v interface{}) (OIdentifiable, bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, true, nil
+	case string:
+		if !strings.HasPrefix(v, string(ridPrefix)) {
+			return nil, false, nil
+		}
+		rid, err := ParseRID(v)
+		if err != nil {
+			return nil, false, nil
+		}
+		return rid, true, nil
+	case *jsonObject:
+		if !isJSONDocument(v) {
+			return nil, false, nil
+		}
+		doc, err := jsonToDocument(v)
+		if err != nil {
+			return nil, false, err
+		}
+		return doc, doc.RID.IsPersistent(), nil
+	}
+	return nil, false, nil
+}
+
+func jsonToLinks(v interface{}) ([]OIdentifiable, bool, error) {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, false, nil
+	}
+	links := make([]OIdentifiable, len(arr))
+	for i, item := range arr {
+		id, ok, err := jsonToLink(item)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		links[i] = id
+	}
+	return links, true, nil
+}
+
+func jsonToTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case json.Number:
+		ms, err := v.Int64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02", time.RFC3339Nano} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %v", v)
+}
+
+func jsonToDecimal(v interface{}) (Decimal, error) {
+	s := fmt.Sprint(v)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		return Decimal{}, fmt.Errorf("unsupported decimal format: %s", s)
+	}
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = len(s) - i - 1
+		s = s[:i] + s[i+1:]
+	}
+	val, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal: %v", v)
+	}
+	return Decimal{Scale: scale, Value: val}, nil
+}
+
+// jsonToField converts JSON value to field value with a given @fieldTypes code. If code is zero, type is inferred.
+func jsonToField(v interface{}, code byte) (interface{}, OType, error) {
+	if v == nil {
+		return nil, UNKNOWN, nil
+	}
+	num, isNum := v.(json.Number)
+	var err error
+	switch code {
+	case 'l':
+		if isNum {
+			var n int64
+			n, err = num.Int64()
+			return n, LONG, err
+		}
+	case 's':
+		if isNum {
+			var n int64
+			n, err = num.Int64()
+			return int16(n), SHORT, err
+		}
+	case 'b':
+		if isNum {
+			var n int64
+			n, err = num.Int64()
+			return byte(n), BYTE, err
+		}
+	case 'f':
+		if isNum {
+			var f float64
+			f, err = num.Float64()
+			return float32(f), FLOAT, err
+		}
+	case 'd':
+		if isNum {
+			var f float64
+			f, err = num.Float64()
+			return f, DOUBLE, err
+		}
+	case 'c':
+		d, err := jsonToDecimal(v)
+		return d, DECIMAL, err
+	case 't':
+		t, err := jsonToTime(v)
+		return t, DATETIME, err
+	case 'a':
+		t, err := jsonToTime(v)
+		return t, DATE, err
+	case 'x':
+		if id, ok, err := jsonToLink(v); err != nil || ok {
+			return id, LINK, err
+		} else if obj, ok := v.(*jsonObject); ok && isJSONDocument(obj) {
+			doc, err := jsonToDocument(obj)
+			return doc, LINK, err
+		}
+	case 'z', 'n', 'g':
+		links, ok, err := jsonToLinks(v)
+		if err != nil {
+			return nil, UNKNOWN, err
+		} else if ok {
+			switch code {
+			case 'z':
+				return links, LINKLIST, nil
+			case 'n':
+				return links, LINKSET, nil
+			}
+			return &RidBag{delegate: &embeddedRidBag{links: links}}, LINKBAG, nil
+		}
+	case 'm':
+		if obj, ok := v.(*jsonObject); ok {
+			links := make(map[string]OIdentifiable, len(obj.keys))
+			for _, k := range obj.keys {
+				id, ok, err := jsonToLink(obj.vals[k])
+				if err != nil {
+					return nil, UNKNOWN, err
+				} else if !ok {
+					return nil, UNKNOWN, fmt.Errorf("invalid link in map: %v", obj.vals[k])
+				}
+				links[k] = id
+			}
+			return links, LINKMAP, nil
+		}
+	case 'e':
+		if arr, ok := v.([]interface{}); ok {
+			out, err := jsonToValue(arr)
+			return out, EMBEDDEDSET, err
+		}
+	}
+	if code != 0 {
+		return nil, UNKNOWN, fmt.Errorf("value %v cannot be used as %q field type", v, code)
+	}
+	switch v := v.(type) {
+	case bool:
+		return v, BOOLEAN, nil
+	case string:
+		if id, ok, _ := jsonToLink(v); ok {
+			return id, LINK, nil
+		}
+		return v, STRING, nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			if int64(int32(n)) == n {
+				return int32(n), INTEGER, nil
+			}
+			return n, LONG, nil
+		}
+		f, err := v.Float64()
+		return f, DOUBLE, err
+	case *jsonObject:
+		if isJSONDocument(v) {
+			doc, err := jsonToDocument(v)
+			if err != nil {
+				return nil, UNKNOWN, err
+			} else if doc.RID.IsPersistent() {
+				return doc, LINK, nil
+			}
+			return doc, EMBEDDED, nil
+		}
+		out, err := jsonToValue(v)
+		return out, EMBEDDEDMAP, err
+	case []interface{}:
+		if links, ok, err := jsonToLinks(v); err != nil {
+			return nil, UNKNOWN, err
+		} else if ok && len(links) != 0 {
+			return links, LINKLIST, nil
+		}
+		out, err := jsonToValue(v)
+		return out, EMBEDDEDLIST, err
+	}
+	return nil, UNKNOWN, fmt.Errorf("unsupported JSON value: %T", v)
+}
+
+// jsonToValue converts an item of embedded collection. Objects become embedded documents or maps,
+// numbers are converted the same way as for fields.
+func jsonToValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		out, _, err := jsonToField(v, 0)
+		return out, err
+	case *jsonObject:
+		if isJSONDocument(v) {
+			return jsonToDocument(v)
+		}
+		out := make(map[string]interface{}, len(v.keys))
+		for _, k := range v.keys {
+			val, err := jsonToValue(v.vals[k])
+			if err != nil {
+				return nil, err
+			}
+			out[k] = val
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			val, err := jsonToValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = val
+		}
+		return out, nil
+	}
+	return v, nil
+}
@@ -1,8 +1,10 @@
 package orient_test
 
 import (
+	"bytes"
 	"gopkg.in/istreamdata/orientgo.v2"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -88,3 +90,546 @@ func TestDocumentFromMap(t *testing.T) {
 		t.Fatal("data differs")
 	}
 }
+
+func TestDocumentFieldRename(t *testing.T) {
+	fm := &orient.FieldMapping{Renames: map[string]map[string]string{"RenamedItem": {"Name": "title"}}}
+	type item struct {
+		Ind  int
+		Name string
+	}
+
+	doc := orient.NewDocument("RenamedItem")
+	doc.SetField("Ind", 3)
+	doc.SetField("title", "renamed")
+	var a item
+	if err := fm.ToStruct(doc, &a); err != nil {
+		t.Fatal(err)
+	} else if a != (item{3, "renamed"}) {
+		t.Fatalf("renamed field was not decoded: %+v", a)
+	}
+	if m, err := doc.ToMap(); err != nil {
+		t.Fatal(err)
+	} else if _, ok := m["Name"]; ok {
+		t.Fatal("old field name should not appear in maps")
+	}
+	var b item
+	if err := doc.ToStruct(&b); err != nil {
+		t.Fatal(err)
+	} else if b.Name != "" {
+		t.Fatalf("field was renamed without a mapping: %+v", b)
+	}
+
+	doc = orient.NewDocument("RenamedItem")
+	if err := fm.From(doc, a); err != nil {
+		t.Fatal(err)
+	} else if fld := doc.GetField("title"); fld == nil || fld.Value != "renamed" {
+		t.Fatalf("field was not renamed on encode: %+v", fld)
+	} else if doc.GetField("Name") != nil {
+		t.Fatal("old field name was encoded")
+	}
+}
+
+func TestDocumentFieldNameMapper(t *testing.T) {
+	fm := &orient.FieldMapping{NameMapper: orient.CamelToSnake}
+	type user struct {
+		UserID    int
+		FirstName string
+		Nick      string                 `mapstructure:"nickname"`
+		Extra     map[string]interface{} `orient:",extra"`
+	}
+
+	doc := orient.NewDocument("User")
+	doc.SetField("user_id", 7)
+	doc.SetField("first_name", "Anna")
+	doc.SetField("nickname", "ann")
+	doc.SetField("last_login", "yesterday")
+	var u user
+	if err := fm.ToStruct(doc, &u); err != nil {
+		t.Fatal(err)
+	} else if u.UserID != 7 || u.FirstName != "Anna" || u.Nick != "ann" {
+		t.Fatalf("fields were not mapped: %+v", u)
+	} else if len(u.Extra) != 1 || u.Extra["last_login"] != "yesterday" {
+		t.Fatalf("wrong extra fields: %v", u.Extra)
+	}
+
+	doc = orient.NewDocument("User")
+	if err := fm.From(doc, user{UserID: 8, FirstName: "Bob", Nick: "bobby"}); err != nil {
+		t.Fatal(err)
+	}
+	if names := doc.FieldNames(); !reflect.DeepEqual(names, []string{"user_id", "first_name", "nickname"}) {
+		t.Fatalf("wrong field names: %v", names)
+	}
+}
+
+func TestDocumentFieldMappingSquash(t *testing.T) {
+	fm := &orient.FieldMapping{
+		NameMapper: orient.CamelToSnake,
+		Renames:    map[string]map[string]string{"Post": {"title": "headline"}},
+	}
+	type Base struct {
+		CreatedBy string
+	}
+	type post struct {
+		Base  `mapstructure:",squash"`
+		Title string
+	}
+	in := post{Base: Base{CreatedBy: "anna"}, Title: "hello"}
+	doc := orient.NewDocument("Post")
+	if err := fm.From(doc, in); err != nil {
+		t.Fatal(err)
+	} else if names := doc.FieldNames(); !reflect.DeepEqual(names, []string{"created_by", "headline"}) {
+		t.Fatalf("wrong field names: %v", names)
+	}
+	var out post
+	if err := fm.ToStruct(doc, &out); err != nil {
+		t.Fatal(err)
+	} else if out != in {
+		t.Fatalf("squashed fields were not decoded: %+v", out)
+	}
+}
+
+func TestCamelToSnake(t *testing.T) {
+	for camel, snake := range map[string]string{
+		"Name":       "name",
+		"FirstName":  "first_name",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"Address2":   "address2",
+		"Line2Text":  "line2_text",
+	} {
+		if s := orient.CamelToSnake(camel); s != snake {
+			t.Errorf("CamelToSnake(%q) = %q, expected %q", camel, s, snake)
+		}
+	}
+	if s := orient.SnakeToCamel("first_name"); s != "FirstName" {
+		t.Errorf("SnakeToCamel: %q", s)
+	}
+}
+
+func TestDocumentToJSON(t *testing.T) {
+	friend := orient.NewDocument("Person")
+	friend.RID = orient.NewRID(9, 2)
+	friend.SetField("name", "alice")
+
+	addr := orient.NewDocument("Address")
+	addr.SetField("city", "Rome")
+
+	doc := orient.NewDocument("Person")
+	doc.RID, doc.Vers = orient.NewRID(9, 1), 3
+	doc.SetField("name", "bob")
+	doc.SetFieldWithType("age", int64(30), orient.LONG)
+	doc.SetFieldWithType("addr", addr, orient.EMBEDDED)
+	doc.SetFieldWithType("friend", friend, orient.LINK)
+	doc.SetFieldWithType("likes", []orient.OIdentifiable{orient.NewRID(9, 3)}, orient.LINKLIST)
+
+	for _, c := range []struct {
+		opts   []orient.JSONOption
+		expect string
+	}{
+		{nil, `{"@type":"d","@rid":"#9:1","@version":3,"@class":"Person","name":"bob","age":30,` +
+			`"addr":{"@type":"d","@class":"Address","city":"Rome"},"friend":"#9:2","likes":["#9:3"],` +
+			`"@fieldTypes":"age=l,friend=x,likes=z"}`},
+		{[]orient.JSONOption{orient.JSONMetadata(false)},
+			`{"name":"bob","age":30,"addr":{"city":"Rome"},"friend":"#9:2","likes":["#9:3"]}`},
+		{[]orient.JSONOption{orient.JSONMetadata(false), orient.JSONExpandLinks(true)},
+			`{"name":"bob","age":30,"addr":{"city":"Rome"},"friend":{"name":"alice"},"likes":["#9:3"]}`},
+	} {
+		data, err := doc.ToJSON(c.opts...)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(data) != c.expect {
+			t.Fatalf("wrong json:\n%s\nexpected:\n%s", data, c.expect)
+		}
+	}
+}
+
+func TestParseDocumentJSON(t *testing.T) {
+	data := `{"@type":"d","@rid":"#9:1","@version":3,"@class":"Person","name":"bob","age":30,"score":1.5,` +
+		`"big":5000000000,"id":7,"addr":{"@type":"d","@class":"Address","city":"Rome"},"tags":["a","b"],` +
+		`"props":{"k":1},"friend":"#9:2","likes":["#9:3"],"@fieldTypes":"id=l"}`
+	doc, err := orient.ParseDocumentJSON([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ClassName() != "Person" || doc.RID != orient.NewRID(9, 1) || doc.Vers != 3 {
+		t.Fatalf("wrong metadata: %v", doc)
+	}
+	for _, c := range []struct {
+		name  string
+		tp    orient.OType
+		value interface{}
+	}{
+		{"name", orient.STRING, "bob"},
+		{"age", orient.INTEGER, int32(30)},
+		{"score", orient.DOUBLE, 1.5},
+		{"big", orient.LONG, int64(5000000000)},
+		{"id", orient.LONG, int64(7)},
+		{"tags", orient.EMBEDDEDLIST, []interface{}{"a", "b"}},
+		{"props", orient.EMBEDDEDMAP, map[string]interface{}{"k": int32(1)}},
+		{"friend", orient.LINK, orient.NewRID(9, 2)},
+		{"likes", orient.LINKLIST, []orient.OIdentifiable{orient.NewRID(9, 3)}},
+	} {
+		fld := doc.GetField(c.name)
+		if fld == nil {
+			t.Fatalf("no field %q", c.name)
+		} else if fld.Type != c.tp || !reflect.DeepEqual(fld.Value, c.value) {
+			t.Fatalf("wrong field %q: %v(%T), expected: %v(%v)", c.name, fld, fld.Value, c.value, c.tp)
+		}
+	}
+	if addr, ok := doc.GetField("addr").Value.(*orient.Document); !ok || doc.GetField("addr").Type != orient.EMBEDDED {
+		t.Fatalf("expected embedded document, got: %v", doc.GetField("addr"))
+	} else if addr.ClassName() != "Address" || addr.GetField("city").Value != "Rome" {
+		t.Fatalf("wrong embedded document: %v", addr)
+	}
+	out, err := doc.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc2, err := orient.ParseDocumentJSON(out)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(doc.FieldNames(), doc2.FieldNames()) {
+		t.Fatalf("fields order is not preserved: %v", doc2.FieldNames())
+	}
+	for _, name := range doc.FieldNames() {
+		if a, b := doc.GetField(name), doc2.GetField(name); a.Type != b.Type {
+			t.Fatalf("round trip changed type of %q: %v -> %v", name, a.Type, b.Type)
+		}
+	}
+}
+
+func TestDocumentToStructMetadata(t *testing.T) {
+	type item struct {
+		RID     orient.RID `mapstructure:"@rid"`
+		Version int32      `mapstructure:"@version"`
+		Class   string     `mapstructure:"@class"`
+		Name    string
+	}
+	doc := orient.NewDocument("Item")
+	doc.RID = orient.NewRID(9, 1)
+	doc.Vers = 3
+	doc.SetField("Name", "bob")
+	var a item
+	if err := doc.ToStruct(&a); err != nil {
+		t.Fatal(err)
+	} else if a != (item{RID: doc.RID, Version: 3, Class: "Item", Name: "bob"}) {
+		t.Fatalf("wrong metadata: %+v", a)
+	}
+}
+
+func TestDocumentOrientTags(t *testing.T) {
+	type address struct {
+		City string `orient:"city"`
+	}
+	type person struct {
+		RID     orient.RID `orient:"@rid"`
+		Version int        `orient:"@version"`
+		Class   string     `orient:"@class"`
+		Name    string     `orient:"name" mapstructure:"full_name"`
+		Address address    `orient:"address"`
+		Home    *address   `orient:"home"`
+	}
+	addr := orient.NewEmptyDocument()
+	addr.SetField("city", "Kyiv")
+	doc := orient.NewDocument("Person")
+	doc.RID = orient.NewRID(9, 1)
+	doc.Vers = 2
+	doc.SetField("name", "bob")
+	doc.SetFieldWithType("address", addr, orient.EMBEDDED)
+	doc.SetFieldWithType("home", addr, orient.EMBEDDED)
+	var p person
+	if err := doc.ToStruct(&p); err != nil {
+		t.Fatal(err)
+	} else if p.RID != doc.RID || p.Version != 2 || p.Class != "Person" || p.Name != "bob" {
+		t.Fatalf("wrong struct: %+v", p)
+	} else if p.Address.City != "Kyiv" || p.Home == nil || p.Home.City != "Kyiv" {
+		t.Fatalf("wrong embedded struct: %+v", p)
+	}
+
+	doc = orient.NewEmptyDocument()
+	if err := doc.From(p); err != nil {
+		t.Fatal(err)
+	} else if doc.RID != p.RID || doc.Vers != 2 || doc.ClassName() != "Person" {
+		t.Fatalf("wrong metadata: %v", doc)
+	} else if fld := doc.GetField("name"); fld == nil || fld.Value != "bob" {
+		t.Fatalf("wrong field: %v", fld)
+	} else if doc.GetField("@rid") != nil || doc.GetField("RID") != nil {
+		t.Fatal("metadata was stored as a field")
+	}
+}
+
+func TestDocumentExtraFields(t *testing.T) {
+	type address struct {
+		City  string
+		Extra map[string]interface{} `orient:",extra"`
+	}
+	type person struct {
+		Name    string                 `orient:"name"`
+		Address address                `orient:"address"`
+		Extra   map[string]interface{} `orient:",extra"`
+	}
+	addr := orient.NewEmptyDocument()
+	addr.SetField("City", "Kyiv")
+	addr.SetField("zip", "01001")
+	doc := orient.NewDocument("Person")
+	doc.SetField("name", "bob")
+	doc.SetField("age", int32(42))
+	doc.SetFieldWithType("address", addr, orient.EMBEDDED)
+	var p person
+	if err := doc.ToStruct(&p); err != nil {
+		t.Fatal(err)
+	} else if p.Name != "bob" || !reflect.DeepEqual(p.Extra, map[string]interface{}{"age": int32(42)}) {
+		t.Fatalf("wrong struct: %+v", p)
+	} else if p.Address.City != "Kyiv" || !reflect.DeepEqual(p.Address.Extra, map[string]interface{}{"zip": "01001"}) {
+		t.Fatalf("wrong embedded struct: %+v", p.Address)
+	}
+
+	doc = orient.NewEmptyDocument()
+	if err := doc.From(p); err != nil {
+		t.Fatal(err)
+	} else if fld := doc.GetField("age"); fld == nil || fld.Value != int32(42) {
+		t.Fatalf("extra field was not stored: %v", doc)
+	} else if doc.GetField("Extra") != nil {
+		t.Fatalf("extra map was stored as a field: %v", doc)
+	}
+}
+
+func TestToDocument(t *testing.T) {
+	type person struct {
+		Class string  `orient:"@class"`
+		Name  string  `orient:"name"`
+		Age   int     `orient:"age,type=SHORT"`
+		Score float64 `orient:"score,type=float"`
+		Tags  []string
+	}
+	doc, err := orient.ToDocument(&person{Class: "Person", Name: "bob", Age: 42, Score: 0.5, Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	} else if doc.ClassName() != "Person" {
+		t.Fatalf("wrong class: %q", doc.ClassName())
+	}
+	for _, c := range []struct {
+		name string
+		val  interface{}
+		tp   orient.OType
+	}{
+		{"name", "bob", orient.STRING},
+		{"age", int16(42), orient.SHORT},
+		{"score", float32(0.5), orient.FLOAT},
+		{"Tags", []string{"a"}, orient.EMBEDDEDLIST},
+	} {
+		if fld := doc.GetField(c.name); fld == nil {
+			t.Errorf("no field %q", c.name)
+		} else if fld.Type != c.tp || !reflect.DeepEqual(fld.Value, c.val) {
+			t.Errorf("wrong field %q: %v (%T) %v", c.name, fld.Value, fld.Value, fld.Type)
+		}
+	}
+
+	if _, err = orient.ToDocument(struct {
+		Age int `orient:"age,type=SHORT"`
+	}{1 << 20}); err == nil {
+		t.Error("expected overflow error")
+	}
+	if _, err = orient.ToDocument(struct {
+		Age int `orient:"age,type=NUMBER"`
+	}{1}); err == nil {
+		t.Error("expected unknown type error")
+	}
+	if _, err = orient.ToDocument((*person)(nil)); err == nil {
+		t.Error("expected error for nil pointer")
+	}
+	if _, err = orient.ToDocument(42); err == nil {
+		t.Error("expected error for non-struct value")
+	}
+}
+
+func TestDocumentEmbeddedClass(t *testing.T) {
+	type address struct {
+		Class string `orient:"@class"`
+		City  string `orient:"city"`
+	}
+	type person struct {
+		Home   address   `orient:"home,class=Address"`
+		Work   *address  `orient:"work,class=Address"`
+		Places []address `orient:"places,class=Address"`
+		Other  *address  `orient:"other,class=Address"`
+	}
+	p := person{
+		Home:   address{City: "Kyiv"},
+		Work:   &address{Class: "Office", City: "Lviv"},
+		Places: []address{{City: "Odesa"}},
+	}
+	doc, err := orient.ToDocument(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, class := range map[string]string{"home": "Address", "work": "Office"} {
+		fld := doc.GetField(name)
+		if fld == nil || fld.Type != orient.EMBEDDED {
+			t.Fatalf("wrong field %q: %v", name, fld)
+		} else if edoc, ok := fld.Value.(*orient.Document); !ok || edoc.ClassName() != class {
+			t.Fatalf("wrong embedded document %q: %v", name, fld.Value)
+		}
+	}
+	if fld := doc.GetField("places"); fld == nil || fld.Type != orient.EMBEDDEDLIST {
+		t.Fatalf("wrong field: %v", fld)
+	} else if list, ok := fld.Value.([]interface{}); !ok || len(list) != 1 {
+		t.Fatalf("wrong embedded list: %v", fld.Value)
+	} else if edoc, ok := list[0].(*orient.Document); !ok || edoc.ClassName() != "Address" {
+		t.Fatalf("wrong embedded list item: %v", list[0])
+	}
+	if fld := doc.GetField("other"); fld == nil || fld.Value != nil {
+		t.Fatalf("wrong nil field: %v", fld)
+	}
+
+	var out person
+	if err = doc.ToStruct(&out); err != nil {
+		t.Fatal(err)
+	}
+	exp := p
+	exp.Home.Class = "Address"
+	exp.Places = []address{{Class: "Address", City: "Odesa"}}
+	if !reflect.DeepEqual(out, exp) {
+		t.Fatalf("wrong struct: %+v != %+v", out, exp)
+	}
+}
+
+func TestDocumentDiff(t *testing.T) {
+	newDoc := func(fields ...interface{}) *orient.Document {
+		doc := orient.NewEmptyDocument()
+		for i := 0; i < len(fields); i += 2 {
+			doc.SetField(fields[i].(string), fields[i+1])
+		}
+		return doc
+	}
+	a := newDoc(
+		"name", "bob",
+		"age", int32(42),
+		"tags", []string{"a", "b"},
+		"home", newDoc("city", "Kyiv", "zip", 1001),
+		"props", map[string]interface{}{"x": int64(1)},
+	)
+	b := newDoc(
+		"props", map[string]interface{}{"x": 1.0},
+		"home", newDoc("zip", int64(1001), "city", "Kyiv"),
+		"tags", []interface{}{"a", "b"},
+		"age", int64(42),
+		"name", "bob",
+	)
+	b.RID = orient.NewRID(10, 1)
+	if !a.Equal(b) {
+		t.Fatalf("expected documents to be equal: %v", a.Diff(b))
+	}
+
+	c := newDoc(
+		"name", "alice",
+		"age", int32(42),
+		"tags", []string{"a"},
+		"home", newDoc("city", "Lviv", "zip", 1001),
+		"email", "alice@example.com",
+	)
+	changes := a.Diff(c)
+	var names []string
+	for _, ch := range changes {
+		names = append(names, ch.Name)
+	}
+	if exp := []string{"email", "home", "name", "props", "tags"}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("wrong changes: %v != %v", names, exp)
+	}
+	if ch := changes[0]; ch.Old != nil || ch.New == nil || ch.New.Value != "alice@example.com" {
+		t.Fatalf("wrong added field: %+v", ch)
+	} else if ch = changes[3]; ch.Old == nil || ch.New != nil {
+		t.Fatalf("wrong removed field: %+v", ch)
+	} else if ch = changes[2]; ch.Old.Value != "bob" || ch.New.Value != "alice" {
+		t.Fatalf("wrong changed field: %+v", ch)
+	}
+}
+
+func TestDocumentBuilder(t *testing.T) {
+	manager, friend := orient.NewRID(9, 1), orient.NewRID(9, 2)
+	doc := orient.NewDocumentBuilder("Person").
+		Set("name", "Bob").
+		Set("age", int32(30)).
+		SetLink("manager", manager).
+		SetLinkList("friends", friend, manager).
+		SetLinkMap("roles", map[string]orient.OIdentifiable{"lead": manager}).
+		SetEmbeddedList("tags", "a", "b").
+		Build()
+	data, err := doc.Content()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := orient.GetDefaultRecordSerializer().FromStream(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := rec.(*orient.Document)
+	if out.ClassName() != "Person" {
+		t.Fatalf("wrong class: %q", out.ClassName())
+	}
+	for name, tp := range map[string]orient.OType{
+		"name": orient.STRING, "age": orient.INTEGER, "manager": orient.LINK,
+		"friends": orient.LINKLIST, "roles": orient.LINKMAP, "tags": orient.EMBEDDEDLIST,
+	} {
+		if fld := out.GetField(name); fld == nil || fld.Type != tp {
+			t.Fatalf("wrong field %q: %v", name, fld)
+		}
+	}
+	if fld := out.GetField("manager"); fld.Value != manager {
+		t.Fatalf("wrong link: %v", fld.Value)
+	} else if fld = out.GetField("friends"); !reflect.DeepEqual(fld.Value, []orient.OIdentifiable{friend, manager}) {
+		t.Fatalf("wrong links: %v", fld.Value)
+	}
+}
+
+func TestDocumentDump(t *testing.T) {
+	addr := orient.NewDocument("Address")
+	addr.SetField("city", "Paris")
+	doc := orient.NewDocument("Person")
+	doc.RID = orient.NewRID(9, 1)
+	doc.Vers = 3
+	doc.SetField("name", "Anna")
+	doc.SetField("age", int32(30))
+	doc.SetFieldWithType("address", addr, orient.EMBEDDED)
+	doc.SetFieldWithType("tags", []interface{}{"a", "b"}, orient.EMBEDDEDLIST)
+	doc.SetFieldWithType("roles", map[string]orient.OIdentifiable{"admin": orient.NewRID(9, 2)}, orient.LINKMAP)
+	doc.SetFieldWithType("self", doc, orient.LINK)
+
+	exp := `Document Person #9:1 v3
+  name (STRING): "Anna"
+  age (INTEGER): 30 (int32)
+  address (EMBEDDED): Document Address #-1:-1 v-1
+    city (STRING): "Paris"
+  tags (EMBEDDEDLIST): [2]
+    [0]: "a"
+    [1]: "b"
+  roles (LINKMAP): {1}
+    admin: #9:2
+  self (LINK): Document Person #9:1 v3 [cycle]
+`
+	if s := doc.Dump(); s != exp {
+		t.Fatalf("unexpected dump:\n%s\nexpected:\n%s", s, exp)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := orient.GetDefaultRecordSerializer().ToStream(buf, addr); err != nil {
+		t.Fatal(err)
+	}
+	loaded := orient.NewEmptyDocument()
+	if err := loaded.Fill(orient.NewRID(9, 3), 2, buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	exp = `Document Address #9:3 v2
+  city (STRING): "Paris"
+`
+	if s := loaded.Dump(); s != exp {
+		t.Fatalf("serialized document was not decoded:\n%s\nexpected:\n%s", s, exp)
+	}
+	broken := orient.NewEmptyDocument()
+	if err := broken.Fill(orient.NewRID(9, 4), 1, []byte{0, 42}); err != nil {
+		t.Fatal(err)
+	}
+	if s := broken.Dump(); !strings.Contains(s, "decode error") {
+		t.Fatalf("decode error was not reported:\n%s", s)
+	}
+}
@@ -3,10 +3,18 @@ package orient
 import (
 	"bytes"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
 	exceptions = make(map[string]func(e Exception) Exception)
+
+	_ ServerError = OServerException{}
+	_ ServerError = ErrConcurrentModification{}
 )
 
 // RegException registers a function to convert server exception based on it's class.
@@ -16,7 +24,7 @@ func RegException(class string, fnc func(e Exception) Exception) {
 
 func init() {
 	RegException("com.orientechnologies.orient.core.exception.OConcurrentModificationException", func(e Exception) Exception {
-		return ErrConcurrentModification{e}
+		return newErrConcurrentModification(e)
 	})
 }
 
@@ -49,12 +57,68 @@ func (e UnknownException) Error() string {
 	return e.Class + ": " + e.Message
 }
 
+// ServerError is implemented by errors returned from OrientDB server. It allows to check Java exception classes
+// without knowing a concrete error type:
+//
+//		if e, ok := err.(orient.ServerError); ok && e.IsClass("ORecordDuplicatedException") {
+//			...
+//		}
+type ServerError interface {
+	error
+	// ExceptionClasses returns Java classes of the exceptions chain, starting from the outermost one.
+	ExceptionClasses() []string
+	// Messages returns messages of the exceptions chain.
+	Messages() []string
+	// IsClass checks if any exception in the chain has a given class. Class can be either a fully qualified
+	// name or a simple one, such as "OConcurrentModificationException".
+	IsClass(class string) bool
+}
+
+// isExcClass checks if Java class name matches a fully qualified or a simple class name.
+func isExcClass(excClass, class string) bool {
+	if excClass == class {
+		return true
+	}
+	i := strings.LastIndex(excClass, ".")
+	return i >= 0 && excClass[i+1:] == class
+}
+
 // OServerException encapsulates Java-based Exceptions from
 // the OrientDB server. OrientDB can return multiple exceptions
 // for a single query/command, so they are all encapsulated in
 // one OServerException object.
 type OServerException struct {
 	Exceptions []Exception
+	// Serialized is a Java-serialized exception with a stack trace, if it was sent by the server.
+	Serialized []byte
+}
+
+// ExceptionClasses returns Java classes of all exceptions.
+func (e OServerException) ExceptionClasses() []string {
+	out := make([]string, len(e.Exceptions))
+	for i, ex := range e.Exceptions {
+		out[i] = ex.ExcClass()
+	}
+	return out
+}
+
+// Messages returns messages of all exceptions.
+func (e OServerException) Messages() []string {
+	out := make([]string, len(e.Exceptions))
+	for i, ex := range e.Exceptions {
+		out[i] = ex.ExcMessage()
+	}
+	return out
+}
+
+// IsClass checks if any of exceptions has a given Java class.
+func (e OServerException) IsClass(class string) bool {
+	for _, ex := range e.Exceptions {
+		if isExcClass(ex.ExcClass(), class) {
+			return true
+		}
+	}
+	return false
 }
 
 func (e OServerException) Error() string {
@@ -76,9 +140,94 @@ func (e ErrInvalidConn) Error() string {
 	return "Invalid Connection: %s" + e.Msg
 }
 
+// ErrCommandTimeout is returned when server does not respond to a command in time. Connection that timed out
+// is closed, since a late response would corrupt the stream.
+type ErrCommandTimeout struct {
+	Timeout time.Duration
+}
+
+func (e ErrCommandTimeout) Error() string {
+	return fmt.Sprintf("command timed out after %v", e.Timeout)
+}
+
 // ErrNoRecord is returned when trying to deserialize an empty result set into a single value.
 var ErrNoRecord = fmt.Errorf("no records returned, while expecting one")
 
+// ErrRecordNotFound is returned when deleting or updating a record that does not exist.
+type ErrRecordNotFound struct {
+	RID RID
+}
+
+func (e ErrRecordNotFound) Error() string {
+	return fmt.Sprintf("record %v not found", e.RID)
+}
+
+// ErrUnknownGlobalProperty is returned when a record references a global property that is not defined
+// in database schema, even after the schema was reloaded.
+type ErrUnknownGlobalProperty struct {
+	ID int
+}
+
+func (e ErrUnknownGlobalProperty) Error() string {
+	return fmt.Sprintf("unknown global property id %d, even after schema reload", e.ID)
+}
+
+// ErrClassExists is returned by CreateClass when a class with the same name is already defined.
+type ErrClassExists struct {
+	Name string
+}
+
+func (e ErrClassExists) Error() string {
+	return fmt.Sprintf("class %s already exists", e.Name)
+}
+
+// ErrDatabaseExists is returned by Admin.CreateDatabase when a database with the same name already exists.
+type ErrDatabaseExists struct {
+	Name string
+}
+
+func (e ErrDatabaseExists) Error() string {
+	return fmt.Sprintf("database %s already exists", e.Name)
+}
+
+// ErrDatabaseNotFound is returned by Admin.DropDatabase when a database does not exist.
+type ErrDatabaseNotFound struct {
+	Name string
+}
+
+func (e ErrDatabaseNotFound) Error() string {
+	return fmt.Sprintf("database %s does not exist", e.Name)
+}
+
+// ErrFunctionNotFound is returned by CallFunction when server has no function with a given name.
+type ErrFunctionNotFound struct {
+	Name string
+	Err  error // original server error
+}
+
+func (e ErrFunctionNotFound) Error() string {
+	return fmt.Sprintf("function %s not found", e.Name)
+}
+
+// serverErrorContains checks if a message of any exception in server error contains one of given strings.
+func serverErrorContains(err error, subs ...string) bool {
+	e, ok := err.(ServerError)
+	if !ok {
+		return false
+	}
+	for _, msg := range e.Messages() {
+		for _, sub := range subs {
+			if strings.Contains(msg, sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrResultsClosed is returned when results are used after Close.
+var ErrResultsClosed = fmt.Errorf("results are already closed")
+
 // ErrMultipleRecords is returned when trying to deserialize a result set with multiple records into a single value.
 type ErrMultipleRecords struct {
 	N   int
@@ -103,10 +252,66 @@ func convertError(err error) error {
 	return err
 }
 
+// ErrConcurrentModification is returned when a record was changed by someone else since it was read,
+// so it's version in database differs from the version of updated record. Use IsConcurrentModification to check for it.
 type ErrConcurrentModification struct {
 	Exception
+	RID      RID // record that was modified concurrently, if reported by server
+	Expected int // record version sent by client, or -1 if unknown
+	Actual   int // current record version in database, or -1 if unknown
+}
+
+// reConcurrentModification matches OConcurrentModificationException message, for example:
+// "Cannot UPDATE the record #12:0 because the version is not the latest. ... (db=v2 your=v1)"
+var reConcurrentModification = regexp.MustCompile(`(#-?\d+:-?\d+).*\(db=v?(-?\d+) your=v?(-?\d+)\)`)
+
+func newErrConcurrentModification(e Exception) ErrConcurrentModification {
+	err := ErrConcurrentModification{Exception: e, RID: NewEmptyRID(), Expected: -1, Actual: -1}
+	if m := reConcurrentModification.FindStringSubmatch(e.ExcMessage()); m != nil {
+		if rid, perr := ParseRID(m[1]); perr == nil {
+			err.RID = rid
+		}
+		err.Actual, _ = strconv.Atoi(m[2])
+		err.Expected, _ = strconv.Atoi(m[3])
+	}
+	return err
+}
+
+// IsConcurrentModification checks if error was caused by a concurrent modification of a record,
+// so the record can be reloaded and the operation can be retried.
+func IsConcurrentModification(err error) bool {
+	switch e := err.(type) {
+	case ErrConcurrentModification:
+		return true
+	case ServerError:
+		return e.IsClass("OConcurrentModificationException")
+	}
+	return false
 }
 
 func (e ErrConcurrentModification) Error() string {
 	return fmt.Sprintf("concurrent modification: %v", e.Exception)
 }
+
+// ExceptionClasses returns Java class of the exception.
+func (e ErrConcurrentModification) ExceptionClasses() []string { return []string{e.ExcClass()} }
+
+// Messages returns exception message.
+func (e ErrConcurrentModification) Messages() []string { return []string{e.ExcMessage()} }
+
+// IsClass checks if exception has a given Java class.
+func (e ErrConcurrentModification) IsClass(class string) bool { return isExcClass(e.ExcClass(), class) }
+
+// ErrNoNodes is returned when connection cannot be opened to any of cluster nodes.
+type ErrNoNodes struct {
+	Errs map[string]error // errors by node address
+}
+
+func (e ErrNoNodes) Error() string {
+	var s []string
+	for addr, err := range e.Errs {
+		s = append(s, addr+": "+err.Error())
+	}
+	sort.Strings(s)
+	return fmt.Sprintf("cannot connect to any of cluster nodes: %s", strings.Join(s, "; "))
+}
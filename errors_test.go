@@ -0,0 +1,100 @@
+package orient
+
+import "testing"
+
+func TestConcurrentModificationError(t *testing.T) {
+	srvErr := OServerException{Exceptions: []Exception{UnknownException{
+		Class: "com.orientechnologies.orient.core.exception.OConcurrentModificationException",
+		Message: "Cannot UPDATE the record #12:3 because the version is not the latest. Probably you are updating " +
+			"an old record or it has been modified by another user (db=v5 your=v4)",
+	}}}
+	if !IsConcurrentModification(srvErr) {
+		t.Fatal("server exception is not detected")
+	}
+	err := convertError(srvErr)
+	e, ok := err.(ErrConcurrentModification)
+	if !ok {
+		t.Fatalf("unexpected error type: %T", err)
+	} else if !IsConcurrentModification(err) {
+		t.Fatal("error is not detected")
+	} else if e.RID != NewRID(12, 3) || e.Expected != 4 || e.Actual != 5 {
+		t.Fatalf("wrong versions: %+v", e)
+	}
+	if IsConcurrentModification(ErrNoRecord) {
+		t.Fatal("unexpected error detected")
+	}
+}
+
+func TestClassExistsError(t *testing.T) {
+	srvErr := OServerException{Exceptions: []Exception{UnknownException{
+		Class:   "com.orientechnologies.orient.core.exception.OSchemaException",
+		Message: "Class Cat already exists in current database",
+	}}}
+	if !isClassExists(srvErr) {
+		t.Fatal("server exception is not detected")
+	}
+	srvErr.Exceptions[0] = UnknownException{
+		Class:   "com.orientechnologies.orient.core.exception.OSchemaException",
+		Message: "Super-class Animal not exists",
+	}
+	if isClassExists(srvErr) {
+		t.Fatal("wrong exception detected")
+	}
+	if sql, err := createClassSQL("Cat", ClassOptions{Extends: "V", ClusterCount: 3, Abstract: true}); err != nil {
+		t.Fatal(err)
+	} else if sql != `CREATE CLASS Cat EXTENDS V CLUSTERS 3 ABSTRACT` {
+		t.Fatalf("wrong sql: %s", sql)
+	}
+	if _, err := createClassSQL("Cat; DROP CLASS V", ClassOptions{}); err == nil {
+		t.Fatal("expected an error for invalid name")
+	}
+}
+
+func TestServerErrorContains(t *testing.T) {
+	srvErr := OServerException{Exceptions: []Exception{
+		UnknownException{Class: "com.orientechnologies.orient.core.exception.OStorageException", Message: "Cannot create database"},
+		UnknownException{Class: "com.orientechnologies.orient.core.exception.ODatabaseException", Message: "Database named 'test' already exists"},
+	}}
+	if !serverErrorContains(srvErr, "does not exist", "already exists") {
+		t.Fatal("message is not found")
+	} else if serverErrorContains(srvErr, "does not exist") {
+		t.Fatal("wrong message found")
+	} else if serverErrorContains(ErrNoRecord, "records") {
+		t.Fatal("client errors should not match")
+	}
+}
+
+type funcSession struct {
+	fakeSession
+	texts []string
+	err   error
+}
+
+func (s *funcSession) Command(cmd CustomSerializable) (interface{}, error) {
+	s.texts = append(s.texts, cmd.(OCommandRequestText).GetText())
+	return []OIdentifiable{NewEmptyDocument()}, s.err
+}
+
+func TestCallFunction(t *testing.T) {
+	sess := &funcSession{}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	if _, err := db.CallFunction("sum", 1, 2); err != nil {
+		t.Fatal(err)
+	} else if _, err = db.CallFunction("now"); err != nil {
+		t.Fatal(err)
+	} else if sess.texts[0] != `SELECT sum(?, ?)` || sess.texts[1] != `SELECT now()` {
+		t.Fatalf("wrong sql: %q", sess.texts)
+	}
+	if _, err := db.CallFunction("f(); DELETE FROM V"); err == nil {
+		t.Fatal("expected an error for invalid name")
+	}
+	sess.err = OServerException{Exceptions: []Exception{UnknownException{
+		Class:   "com.orientechnologies.orient.core.sql.OCommandSQLParsingException",
+		Message: "No function with name 'nope', available names are : [sum, now]",
+	}}}
+	if _, err := db.CallFunction("nope"); err == nil {
+		t.Fatal("expected an error")
+	} else if e, ok := err.(ErrFunctionNotFound); !ok || e.Name != "nope" {
+		t.Fatalf("unexpected error: %T %v", err, err)
+	}
+}
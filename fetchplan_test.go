@@ -0,0 +1,57 @@
+package orient
+
+import (
+	"fmt"
+	"testing"
+)
+
+// planSession records fetch plans of queries and loaded records.
+type planSession struct {
+	fakeSession
+	plans []string
+}
+
+func (s *planSession) Command(cmd CustomSerializable) (interface{}, error) {
+	if q, ok := cmd.(SQLQuery); ok {
+		s.plans = append(s.plans, q.plan)
+	}
+	return nil, nil
+}
+
+func (s *planSession) LoadRecord(rid RID, fetchPlan FetchPlan, ignoreCache bool) (ORecord, []ORecord, error) {
+	s.plans = append(s.plans, string(fetchPlan))
+	doc := NewEmptyDocument()
+	doc.RID = rid
+	return doc, nil, nil
+}
+
+func TestDefaultFetchPlan(t *testing.T) {
+	sess := &planSession{}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	db.SetDefaultFetchPlan("out_*:1")
+	db.Command(NewSQLQuery("SELECT FROM V")).Err()
+	db.Command(NewSQLQuery("SELECT FROM V").FetchPlan(NoFollow)).Err()
+	db.Command(NewSQLCommand("DELETE VERTEX V")).Err()
+	if _, _, err := db.Load(NewRID(9, 1), ""); err != nil {
+		t.Fatal(err)
+	} else if _, _, err = db.Load(NewRID(9, 1), FollowAll); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"out_*:1", "*:0", "out_*:1", "*:-1"}; fmt.Sprint(sess.plans) != fmt.Sprint(exp) {
+		t.Fatalf("wrong fetch plans: %q != %q", sess.plans, exp)
+	}
+}
+
+func TestStmtFetchPlan(t *testing.T) {
+	sess := &planSession{}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	stmt, err := db.Prepare("SELECT FROM V WHERE id = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt.FetchPlan(FollowAll).Query(1).Err()
+	stmt.Query(2).Err()
+	if exp := []string{"*:-1", ""}; fmt.Sprint(sess.plans) != fmt.Sprint(exp) {
+		t.Fatalf("wrong plans: %q, expected: %q", sess.plans, exp)
+	}
+}
@@ -1,10 +1,16 @@
 package orient
 
+import (
+	"fmt"
+	"strings"
+)
+
 // List of supported server-side script languages
 const (
-	LangSQL    = ScriptLang("sql")
-	LangJS     = ScriptLang("javascript")
-	LangGroovy = ScriptLang("groovy")
+	LangSQL     = ScriptLang("sql")
+	LangJS      = ScriptLang("javascript")
+	LangGroovy  = ScriptLang("groovy")
+	LangGremlin = ScriptLang("gremlin")
 )
 
 // ScriptLang is a type for supported server-side script languages
@@ -18,3 +24,30 @@ type Function struct {
 	Idemp  bool // is idempotent
 	Code   string
 }
+
+// CallFunction calls a server-side function with given arguments and returns it's results. Function can be
+// an SQL function or a function stored in database in any language (SQL, JavaScript, ...). Arguments are
+// bound as query parameters. Result is returned as a single document with a field named after the function.
+//
+// If function is not defined on the server, ErrFunctionNotFound is returned.
+func (db *Database) CallFunction(name string, args ...interface{}) (Results, error) {
+	if !isSQLName(name) {
+		return nil, fmt.Errorf("invalid function name: %q", name)
+	}
+	marks := make([]string, len(args))
+	for i := range marks {
+		marks[i] = "?"
+	}
+	sql := `SELECT ` + name + `(` + strings.Join(marks, ", ") + `)`
+	res := db.Command(NewSQLCommand(sql, args...).Idempotent(false)) // functions may modify data
+	if err := res.Err(); isFunctionNotFound(err) {
+		return nil, ErrFunctionNotFound{Name: name, Err: err}
+	} else if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func isFunctionNotFound(err error) bool {
+	return serverErrorContains(err, "No function with name", "Unknown function name")
+}
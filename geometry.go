@@ -0,0 +1,219 @@
+package orient
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ Geometry = Point{}
+	_ Geometry = LineString{}
+	_ Geometry = Polygon{}
+)
+
+// Geometry is an interface for spatial types that can be represented as WKT (Well-known text) strings.
+//
+// Spatial module may return geometry fields as WKT strings. Such fields are converted to Point,
+// LineString or Polygon automatically when decoded into struct fields of the corresponding type
+// (or into a field of Geometry interface type). Geometry values are stored as WKT strings.
+type Geometry interface {
+	// WKT returns Well-known text representation of geometry
+	WKT() string
+}
+
+// Point is a single location in coordinate space.
+type Point struct {
+	X, Y float64
+}
+
+// WKT returns Well-known text representation of a point
+func (p Point) WKT() string {
+	return "POINT (" + p.coords() + ")"
+}
+func (p Point) String() string { return p.WKT() }
+func (p Point) coords() string {
+	return strconv.FormatFloat(p.X, 'f', -1, 64) + " " + strconv.FormatFloat(p.Y, 'f', -1, 64)
+}
+
+// LineString is a curve with linear interpolation between points.
+type LineString struct {
+	Points []Point
+}
+
+// WKT returns Well-known text representation of a line string
+func (l LineString) WKT() string {
+	if len(l.Points) == 0 {
+		return "LINESTRING EMPTY"
+	}
+	return "LINESTRING " + wktPointList(l.Points)
+}
+func (l LineString) String() string { return l.WKT() }
+
+// Polygon is a planar surface defined by one exterior boundary and zero or more interior boundaries (holes).
+type Polygon struct {
+	Rings [][]Point
+}
+
+// WKT returns Well-known text representation of a polygon
+func (p Polygon) WKT() string {
+	if len(p.Rings) == 0 {
+		return "POLYGON EMPTY"
+	}
+	rings := make([]string, len(p.Rings))
+	for i, r := range p.Rings {
+		rings[i] = wktPointList(r)
+	}
+	return "POLYGON (" + strings.Join(rings, ", ") + ")"
+}
+func (p Polygon) String() string { return p.WKT() }
+
+func wktPointList(pts []Point) string {
+	arr := make([]string, len(pts))
+	for i, p := range pts {
+		arr[i] = p.coords()
+	}
+	return "(" + strings.Join(arr, ", ") + ")"
+}
+
+// ErrInvalidWKT is returned when WKT string cannot be parsed.
+type ErrInvalidWKT struct {
+	WKT    string
+	Reason string
+}
+
+func (e ErrInvalidWKT) Error() string {
+	return fmt.Sprintf("invalid WKT %q: %s", e.WKT, e.Reason)
+}
+
+// ParseWKT parses Well-known text representation of geometry. Only POINT, LINESTRING and POLYGON types are supported.
+func ParseWKT(s string) (Geometry, error) {
+	bad := func(format string, args ...interface{}) error {
+		return ErrInvalidWKT{WKT: s, Reason: fmt.Sprintf(format, args...)}
+	}
+	str := strings.TrimSpace(s)
+	i := strings.IndexAny(str, " (")
+	if i < 0 {
+		return nil, bad("no coordinates")
+	}
+	tp, body := strings.ToUpper(str[:i]), strings.TrimSpace(str[i:])
+	empty := strings.ToUpper(body) == "EMPTY"
+	switch tp {
+	case "POINT":
+		if empty {
+			return nil, bad("empty point is not supported")
+		}
+		pts, err := parseWKTPointList(body)
+		if err != nil {
+			return nil, bad("%v", err)
+		} else if len(pts) != 1 {
+			return nil, bad("point must have exactly one coordinate pair, got %d", len(pts))
+		}
+		return pts[0], nil
+	case "LINESTRING":
+		if empty {
+			return LineString{}, nil
+		}
+		pts, err := parseWKTPointList(body)
+		if err != nil {
+			return nil, bad("%v", err)
+		} else if len(pts) < 2 {
+			return nil, bad("line string must have at least 2 points, got %d", len(pts))
+		}
+		return LineString{Points: pts}, nil
+	case "POLYGON":
+		if empty {
+			return Polygon{}, nil
+		}
+		if !strings.HasPrefix(body, "(") || !strings.HasSuffix(body, ")") {
+			return nil, bad("rings must be enclosed in parentheses")
+		}
+		body = strings.TrimSpace(body[1 : len(body)-1])
+		var poly Polygon
+		for body != "" {
+			end := strings.Index(body, ")")
+			if end < 0 {
+				return nil, bad("unterminated ring")
+			}
+			pts, err := parseWKTPointList(body[:end+1])
+			if err != nil {
+				return nil, bad("ring %d: %v", len(poly.Rings), err)
+			} else if len(pts) < 4 {
+				return nil, bad("ring %d must have at least 4 points, got %d", len(poly.Rings), len(pts))
+			} else if pts[0] != pts[len(pts)-1] {
+				return nil, bad("ring %d is not closed", len(poly.Rings))
+			}
+			poly.Rings = append(poly.Rings, pts)
+			body = strings.TrimSpace(body[end+1:])
+			if strings.HasPrefix(body, ",") {
+				body = strings.TrimSpace(body[1:])
+				if body == "" {
+					return nil, bad("trailing comma")
+				}
+			} else if body != "" {
+				return nil, bad("unexpected %q after ring", body)
+			}
+		}
+		if len(poly.Rings) == 0 {
+			return nil, bad("no rings")
+		}
+		return poly, nil
+	default:
+		return nil, bad("unsupported geometry type %q", tp)
+	}
+}
+
+// parseWKTPointList parses coordinates list in form of "(x y, x y, ...)"
+func parseWKTPointList(s string) ([]Point, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("coordinates must be enclosed in parentheses")
+	}
+	s = s[1 : len(s)-1]
+	if strings.ContainsAny(s, "()") {
+		return nil, fmt.Errorf("unexpected parentheses")
+	}
+	parts := strings.Split(s, ",")
+	pts := make([]Point, len(parts))
+	for i, part := range parts {
+		xy := strings.Fields(part)
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("expected 2 coordinates, got %q", strings.TrimSpace(part))
+		}
+		var err error
+		if pts[i].X, err = strconv.ParseFloat(xy[0], 64); err != nil {
+			return nil, fmt.Errorf("wrong coordinate %q", xy[0])
+		}
+		if pts[i].Y, err = strconv.ParseFloat(xy[1], 64); err != nil {
+			return nil, fmt.Errorf("wrong coordinate %q", xy[1])
+		}
+	}
+	return pts, nil
+}
+
+var (
+	reflGeometryType   = reflect.TypeOf((*Geometry)(nil)).Elem()
+	reflPointType      = reflect.TypeOf(Point{})
+	reflLineStringType = reflect.TypeOf(LineString{})
+	reflPolygonType    = reflect.TypeOf(Polygon{})
+)
+
+// stringToGeometryHookFunc returns a DecodeHookFunc that converts WKT strings to geometry types.
+func stringToGeometryHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if f.Kind() != reflect.String {
+		return data, nil
+	}
+	switch t {
+	case reflGeometryType, reflPointType, reflLineStringType, reflPolygonType:
+	default:
+		return data, nil
+	}
+	g, err := ParseWKT(reflect.ValueOf(data).String())
+	if err != nil {
+		return nil, err
+	} else if t != reflGeometryType && reflect.TypeOf(g) != t {
+		return nil, fmt.Errorf("cannot decode %T into %v", g, t)
+	}
+	return g, nil
+}
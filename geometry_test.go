@@ -0,0 +1,99 @@
+package orient_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+)
+
+func TestWKTPoint(t *testing.T) {
+	g, err := orient.ParseWKT(" point(30.5  -10) ")
+	if err != nil {
+		t.Fatal(err)
+	} else if g != (orient.Point{X: 30.5, Y: -10}) {
+		t.Fatalf("wrong point: %+v", g)
+	} else if s := g.WKT(); s != "POINT (30.5 -10)" {
+		t.Fatalf("wrong WKT: %s", s)
+	}
+	if g2, err := orient.ParseWKT(g.WKT()); err != nil {
+		t.Fatal(err)
+	} else if g2 != g {
+		t.Fatalf("round-trip failed: %+v vs %+v", g, g2)
+	}
+}
+
+func TestWKTPolygon(t *testing.T) {
+	const s = "POLYGON ((35 10, 45 45, 15 40, 10 20, 35 10), (20 30, 35 35, 30 20, 20 30))"
+	g, err := orient.ParseWKT(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poly, ok := g.(orient.Polygon)
+	if !ok {
+		t.Fatalf("expected polygon, got %T", g)
+	} else if len(poly.Rings) != 2 || len(poly.Rings[0]) != 5 || len(poly.Rings[1]) != 4 {
+		t.Fatalf("wrong polygon: %+v", poly)
+	} else if poly.Rings[1][1] != (orient.Point{X: 35, Y: 35}) {
+		t.Fatalf("wrong point: %+v", poly.Rings[1][1])
+	} else if poly.WKT() != s {
+		t.Fatalf("round-trip failed:\n%s\n%s", s, poly.WKT())
+	}
+}
+
+func TestWKTMalformed(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"POINT",
+		"POINT (1)",
+		"POINT (1 2, 3 4)",
+		"POINT (a b)",
+		"LINESTRING (1 2)",
+		"POLYGON ((0 0, 1 0, 1 1, 0 1))",
+		"POLYGON ((0 0, 1 0, 1 1, 0 0)",
+		"CIRCLE (1 2)",
+	} {
+		if _, err := orient.ParseWKT(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		} else if _, ok := err.(orient.ErrInvalidWKT); !ok {
+			t.Errorf("wrong error type for %q: %T", s, err)
+		}
+	}
+}
+
+func TestGeometryDocumentRoundTrip(t *testing.T) {
+	type Place struct {
+		Name     string
+		Location orient.Point
+		Area     *orient.Polygon
+		Shape    orient.Geometry
+	}
+	area := orient.Polygon{Rings: [][]orient.Point{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}}
+	a := Place{Name: "home", Location: orient.Point{X: 1, Y: 2}, Area: &area, Shape: orient.LineString{Points: []orient.Point{{0, 0}, {2, 2}}}}
+
+	doc := orient.NewEmptyDocument()
+	if err := doc.From(a); err != nil {
+		t.Fatal(err)
+	} else if fld := doc.GetField("Location"); fld.Type != orient.STRING {
+		t.Fatalf("geometry should be stored as string, got: %v", fld.Type)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := orient.GetDefaultRecordSerializer().ToStream(buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := orient.GetDefaultRecordSerializer().FromStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = rec.(*orient.Document)
+	if s := doc.GetField("Location").Value; s != "POINT (1 2)" {
+		t.Fatalf("wrong WKT stored: %v", s)
+	}
+	var b Place
+	if err := doc.ToStruct(&b); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(a, b) {
+		t.Fatalf("data differs: %+v vs %+v", a, b)
+	}
+}
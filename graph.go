@@ -0,0 +1,196 @@
+package orient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default base classes for graph elements
+const (
+	ClassVertex = "V"
+	ClassEdge   = "E"
+)
+
+// setClause builds a SET clause for CREATE VERTEX/EDGE commands with positional parameters.
+// Fields are sorted to keep command text stable for server-side command cache.
+func setClause(props map[string]interface{}) (string, []interface{}) {
+	if len(props) == 0 {
+		return "", nil
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sets := make([]string, len(names))
+	params := make([]interface{}, len(names))
+	for i, name := range names {
+		sets[i] = name + ` = ?`
+		params[i] = props[name]
+	}
+	return ` SET ` + strings.Join(sets, ", "), params
+}
+
+// checkPropNames checks property names before they are used in SQL text.
+func checkPropNames(props map[string]interface{}) error {
+	for name := range props {
+		if !isSQLName(name) {
+			return fmt.Errorf("invalid property name: %q", name)
+		}
+	}
+	return nil
+}
+
+// CreateVertex creates a new vertex of a given class with specified properties. If class is empty, ClassVertex is used.
+func (db *Database) CreateVertex(class string, props map[string]interface{}) (*Document, error) {
+	if class == "" {
+		class = ClassVertex
+	} else if !isSQLName(class) {
+		return nil, fmt.Errorf("invalid class name: %q", class)
+	}
+	if err := checkPropNames(props); err != nil {
+		return nil, err
+	}
+	set, params := setClause(props)
+	var doc *Document
+	if err := db.Command(NewSQLCommand(`CREATE VERTEX `+class+set, params...)).All(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// CreateEdge creates a new edge of a given class between two vertexes. If class is empty, ClassEdge is used.
+//
+// Both vertexes must exist in database, otherwise the server error is returned.
+func (db *Database) CreateEdge(class string, from, to RID, props map[string]interface{}) (*Document, error) {
+	if class == "" {
+		class = ClassEdge
+	} else if !isSQLName(class) {
+		return nil, fmt.Errorf("invalid class name: %q", class)
+	}
+	if !from.IsPersistent() {
+		return nil, fmt.Errorf("invalid source vertex: %v", from)
+	} else if !to.IsPersistent() {
+		return nil, fmt.Errorf("invalid destination vertex: %v", to)
+	}
+	if err := checkPropNames(props); err != nil {
+		return nil, err
+	}
+	set, params := setClause(props)
+	var doc *Document
+	sql := `CREATE EDGE ` + class + ` FROM ` + from.String() + ` TO ` + to.String() + set
+	if err := db.Command(NewSQLCommand(sql, params...)).All(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Direction selects edges of a vertex by their direction.
+type Direction int
+
+const (
+	Out  Direction = iota // outgoing edges, stored in "out_*" fields of a vertex
+	In                    // incoming edges, stored in "in_*" fields of a vertex
+	Both                  // both outgoing and incoming edges
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Out:
+		return "out"
+	case In:
+		return "in"
+	case Both:
+		return "both"
+	}
+	return fmt.Sprintf("Direction(%d)", int(d))
+}
+
+// fieldPrefixes returns prefixes of vertex fields that store edges of this direction.
+func (d Direction) fieldPrefixes() []string {
+	switch d {
+	case Out:
+		return []string{"out_"}
+	case In:
+		return []string{"in_"}
+	case Both:
+		return []string{"out_", "in_"}
+	}
+	return nil
+}
+
+// Edges returns edges of a vertex in a given direction. If edgeClass is not empty, only edges stored under this
+// class name are returned (edges of subclasses are stored separately and are not included).
+//
+// Edge records are fetched by the server together with the vertex, when possible, and other edges (for example,
+// from tree-based RidBags) are loaded one by one. Lightweight edges are links to vertexes, so vertex records are
+// returned for them.
+func (db *Database) Edges(vertex RID, dir Direction, edgeClass string) ([]*Document, error) {
+	prefixes := dir.fieldPrefixes()
+	if prefixes == nil {
+		return nil, fmt.Errorf("invalid direction: %v", dir)
+	}
+	plan := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		if edgeClass == "" {
+			plan[i] = p + "*:1"
+		} else {
+			plan[i] = p + edgeClass + ":1"
+		}
+	}
+	doc, related, err := db.Load(vertex, FetchPlan(strings.Join(plan, " ")))
+	if err != nil {
+		return nil, err
+	}
+	loader := PrefetchedLoader(related, db.LinkLoader())
+	var out []*Document
+	for _, name := range doc.FieldNames() {
+		if !isEdgeField(name, prefixes, edgeClass) {
+			continue
+		}
+		links, err := edgeLinks(db, doc.GetField(name).Value)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %v", name, err)
+		}
+		for _, l := range links {
+			edge, ok := l.(*Document)
+			if !ok {
+				if edge, err = loader.Load(l.GetIdentity()); err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, edge)
+		}
+	}
+	return out, nil
+}
+
+func isEdgeField(name string, prefixes []string, edgeClass string) bool {
+	for _, p := range prefixes {
+		if edgeClass == "" && strings.HasPrefix(name, p) || name == p+edgeClass {
+			return true
+		}
+	}
+	return false
+}
+
+// edgeLinks returns links stored in an edge field of a vertex: a RidBag, a collection or a single link.
+func edgeLinks(db RidBagTreeReader, v interface{}) ([]OIdentifiable, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case *RidBag:
+		var out []OIdentifiable
+		next := v.Iterate(db)
+		for rid, ok := next(); ok; rid, ok = next() {
+			out = append(out, rid)
+		}
+		return out, v.Err()
+	case []OIdentifiable:
+		return v, nil
+	case OIdentifiable:
+		return []OIdentifiable{v}, nil
+	}
+	return nil, fmt.Errorf("unexpected edges type: %T", v)
+}
@@ -499,3 +499,217 @@ func doCircularLinkExample(t *testing.T, db *obinary.Client) {
 	Equals(t, "AAA", inLinkFromAAA.Record.GetField("firstName").Value)
 }
 */
+
+func TestCreateEdgeInvalidRID(t *testing.T) {
+	var db orient.Database // endpoints are checked before any request is made
+	if _, err := db.CreateEdge("", orient.NewEmptyRID(), orient.NewRID(9, 0), nil); err == nil {
+		t.Fatal("expected error for empty source vertex")
+	}
+	if _, err := db.CreateEdge("", orient.NewRID(9, 0), orient.NewEmptyRID(), nil); err == nil {
+		t.Fatal("expected error for empty destination vertex")
+	}
+	for _, name := range []string{"a = 1, b", "a/*", "a-b", "a.b", "a[0]", "#9:0", "a:b", ""} {
+		props := map[string]interface{}{name: 2}
+		if _, err := db.CreateEdge("", orient.NewRID(9, 0), orient.NewRID(9, 1), props); err == nil {
+			t.Fatalf("expected error for invalid property name %q", name)
+		}
+		if _, err := db.CreateVertex("", props); err == nil {
+			t.Fatalf("expected error for invalid property name %q", name)
+		}
+	}
+	if _, err := db.CreateEdge("E FROM #9:0 TO #9:1; DELETE VERTEX V;", orient.NewRID(9, 0), orient.NewRID(9, 1), nil); err == nil {
+		t.Fatal("expected error for invalid class name")
+	}
+	if _, err := db.CreateVertex("V; DELETE VERTEX V", nil); err == nil {
+		t.Fatal("expected error for invalid class name")
+	}
+}
+
+func TestCreateVertexEdge(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, true)
+	defer closer()
+	defer catch(t)
+
+	for _, cmd := range []string{
+		"CREATE CLASS Person EXTENDS V",
+		"CREATE CLASS Friend EXTENDS E",
+	} {
+		if err := db.Command(orient.NewSQLCommand(cmd)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, err := db.CreateVertex("Person", map[string]interface{}{"name": "Anna", "age": 28})
+	if err != nil {
+		t.Fatal(err)
+	} else if a.ClassName() != "Person" || !a.RID.IsPersistent() {
+		t.Fatalf("wrong vertex: %+v", a)
+	} else if name := a.GetField("name"); name == nil || name.Value != "Anna" {
+		t.Fatalf("wrong vertex field: %+v", name)
+	}
+	b, err := db.CreateVertex("Person", map[string]interface{}{"name": "Bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := db.CreateEdge("Friend", a.RID, b.RID, map[string]interface{}{"since": 2010})
+	if err != nil {
+		t.Fatal(err)
+	} else if e.ClassName() != "Friend" {
+		t.Fatalf("wrong edge class: %q", e.ClassName())
+	} else if out := e.GetField("out"); out == nil || out.Value.(orient.OIdentifiable).GetIdentity() != a.RID {
+		t.Fatalf("wrong edge source: %+v", out)
+	}
+	missing := b.RID
+	missing.ClusterPos += 100
+	if _, err = db.CreateEdge("Friend", a.RID, missing, nil); err == nil {
+		t.Fatal("expected error for missing vertex")
+	}
+}
+
+func TestTraverse(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, true)
+	defer closer()
+	defer catch(t)
+
+	for _, cmd := range []string{
+		"CREATE CLASS Person EXTENDS V",
+		"CREATE CLASS Friend EXTENDS E",
+	} {
+		if err := db.Command(orient.NewSQLCommand(cmd)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var rids []orient.RID
+	for _, name := range []string{"Anna", "Bob", "Carl"} {
+		v, err := db.CreateVertex("Person", map[string]interface{}{"name": name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rids = append(rids, v.RID)
+	}
+	for _, e := range [][2]int{{0, 1}, {1, 2}, {2, 0}} {
+		if _, err := db.CreateEdge("Friend", rids[e[0]], rids[e[1]], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths, err := db.Traverse(rids[0], []string{"Friend"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(paths) != 2 {
+		t.Fatalf("wrong number of paths: %d", len(paths))
+	} else if p := paths[1]; p.Len() != 2 || p.Start().RID != rids[0] || p.End().RID != rids[2] {
+		t.Fatalf("wrong path: %+v", p)
+	}
+}
+
+func TestMatchAliases(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, true)
+	defer closer()
+	defer catch(t)
+
+	for _, cmd := range []string{
+		"CREATE CLASS Person EXTENDS V",
+		"CREATE CLASS Friend EXTENDS E",
+	} {
+		if err := db.Command(orient.NewSQLCommand(cmd)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var rids []orient.RID
+	for _, name := range []string{"Anna", "Bob", "Carl"} {
+		v, err := db.CreateVertex("Person", map[string]interface{}{"name": name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rids = append(rids, v.RID)
+	}
+	for _, e := range [][2]int{{0, 1}, {1, 2}} {
+		if _, err := db.CreateEdge("Friend", rids[e[0]], rids[e[1]], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	type Person struct {
+		Name string
+	}
+	var rows []struct {
+		Person Person
+		Friend *Person
+	}
+	const sql = `MATCH {class: Person, as: person, where: (name = 'Anna')}-Friend->{}-Friend->{as: friend} RETURN person, friend`
+	if err := db.Command(orient.NewSQLQuery(sql)).LoadLinks(1).All(&rows); err != nil {
+		t.Fatal(err)
+	} else if len(rows) != 1 || rows[0].Person.Name != "Anna" || rows[0].Friend == nil || rows[0].Friend.Name != "Carl" {
+		t.Fatalf("wrong rows: %+v", rows)
+	}
+	var docs []map[string]*orient.Document
+	if err := db.Command(orient.NewSQLQuery(sql)).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 1 || docs[0]["friend"] == nil || docs[0]["friend"].GetField("name").Value != "Carl" {
+		t.Fatalf("wrong rows: %+v", docs)
+	}
+}
+
+func TestEdgesInvalidDirection(t *testing.T) {
+	var db orient.Database // direction is checked before any request is made
+	if _, err := db.Edges(orient.NewRID(9, 0), orient.Direction(5), ""); err == nil {
+		t.Fatal("expected error for invalid direction")
+	}
+}
+
+func TestEdges(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, true)
+	defer closer()
+	defer catch(t)
+
+	for _, cmd := range []string{
+		"CREATE CLASS Person EXTENDS V",
+		"CREATE CLASS Friend EXTENDS E",
+		"CREATE CLASS Likes EXTENDS E",
+	} {
+		if err := db.Command(orient.NewSQLCommand(cmd)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var rids []orient.RID
+	for _, name := range []string{"Anna", "Bob", "Carl"} {
+		v, err := db.CreateVertex("Person", map[string]interface{}{"name": name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rids = append(rids, v.RID)
+	}
+	for _, e := range []struct {
+		class    string
+		from, to int
+	}{{"Friend", 0, 1}, {"Friend", 0, 2}, {"Likes", 0, 1}, {"Friend", 1, 0}} {
+		if _, err := db.CreateEdge(e.class, rids[e.from], rids[e.to], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		dir   orient.Direction
+		class string
+		n     int
+	}{
+		{orient.Out, "", 3},
+		{orient.Out, "Friend", 2},
+		{orient.In, "", 1},
+		{orient.In, "Likes", 0},
+		{orient.Both, "Friend", 3},
+	} {
+		edges, err := db.Edges(rids[0], c.dir, c.class)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(edges) != c.n {
+			t.Fatalf("wrong number of %v edges of class %q: %d", c.dir, c.class, len(edges))
+		}
+		for _, e := range edges {
+			if c.class != "" && e.ClassName() != c.class {
+				t.Fatalf("wrong edge class: %q", e.ClassName())
+			}
+		}
+	}
+}
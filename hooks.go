@@ -0,0 +1,63 @@
+package orient
+
+import (
+	"reflect"
+	"time"
+)
+
+// Hooks are callbacks for monitoring database commands, for example to collect metrics. All hooks are optional.
+// Hooks are called synchronously, so they should return quickly.
+type Hooks struct {
+	// OnCommandStart is called before a command is sent to the server.
+	OnCommandStart func(sql string)
+	// OnCommandEnd is called when a command is completed, including failed ones.
+	OnCommandEnd func(sql string, dur time.Duration, err error)
+	// OnError is called when a command fails, after OnCommandEnd.
+	OnError func(sql string, err error)
+	// OnDecode is called when command results are decoded, with the number of decoded records.
+	OnDecode func(records int)
+}
+
+// SetHooks sets callbacks for commands executed with Command. Zero Hooks value removes all hooks.
+func (db *Database) SetHooks(h Hooks) {
+	db.mu.Lock()
+	db.hooks = h
+	db.mu.Unlock()
+}
+
+func (db *Database) getHooks() Hooks {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.hooks
+}
+
+func (h Hooks) commandStart(sql string) {
+	if h.OnCommandStart != nil {
+		h.OnCommandStart(sql)
+	}
+}
+
+func (h Hooks) commandEnd(sql string, start time.Time, err error) {
+	if h.OnCommandEnd != nil {
+		h.OnCommandEnd(sql, time.Since(start), err)
+	}
+	if err != nil && h.OnError != nil {
+		h.OnError(sql, err)
+	}
+}
+
+// decoded reports a number of records in a decoded result.
+func (h Hooks) decoded(result interface{}) {
+	if h.OnDecode == nil {
+		return
+	}
+	n := 0
+	if v := reflect.ValueOf(result); !v.IsValid() {
+		// no records
+	} else if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		n = v.Len()
+	} else {
+		n = 1
+	}
+	h.OnDecode(n)
+}
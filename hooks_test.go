@@ -0,0 +1,51 @@
+package orient
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type cmdSession struct {
+	fakeSession
+	result interface{}
+	err    error
+}
+
+func (s *cmdSession) Command(cmd CustomSerializable) (interface{}, error) { return s.result, s.err }
+
+func TestDatabaseHooks(t *testing.T) {
+	sess := &cmdSession{result: []OIdentifiable{NewEmptyDocument(), NewEmptyDocument()}}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	var events []string
+	db.SetHooks(Hooks{
+		OnCommandStart: func(sql string) { events = append(events, "start "+sql) },
+		OnCommandEnd: func(sql string, dur time.Duration, err error) {
+			events = append(events, fmt.Sprintf("end %s %v", sql, err))
+		},
+		OnError:  func(sql string, err error) { events = append(events, fmt.Sprintf("error %s %v", sql, err)) },
+		OnDecode: func(n int) { events = append(events, fmt.Sprintf("decode %d", n)) },
+	})
+	var docs []*Document
+	if err := db.Command(NewSQLQuery("SELECT FROM V")).All(&docs); err != nil {
+		t.Fatal(err)
+	}
+	sess.err = fmt.Errorf("failed")
+	db.Command(NewSQLCommand("DELETE VERTEX V")).Err()
+	expect := []string{
+		"start SELECT FROM V", "end SELECT FROM V <nil>", "decode 2",
+		"start DELETE VERTEX V", "end DELETE VERTEX V failed", "error DELETE VERTEX V failed",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expect) {
+		t.Fatalf("wrong events:\n%q\nexpected:\n%q", events, expect)
+	}
+
+	db.SetHooks(Hooks{})
+	events = nil
+	sess.err = nil
+	if err := db.Command(NewSQLQuery("SELECT FROM V")).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(events) != 0 {
+		t.Fatalf("hooks were called after removal: %q", events)
+	}
+}
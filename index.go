@@ -0,0 +1,85 @@
+package orient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Common index types for CreateIndex
+const (
+	IndexUnique        = "UNIQUE"
+	IndexNotUnique     = "NOTUNIQUE"
+	IndexFullText      = "FULLTEXT"
+	IndexDictionary    = "DICTIONARY"
+	IndexUniqueHash    = "UNIQUE_HASH_INDEX"
+	IndexNotUniqueHash = "NOTUNIQUE_HASH_INDEX"
+)
+
+func checkIndexName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n=,;`'\"()[]") {
+		return fmt.Errorf("invalid index name: %q", name)
+	}
+	return nil
+}
+
+// createIndexSQL builds CREATE INDEX command. Index on a single property can have a name in Class.property form,
+// other indexes are created with class and fields specified explicitly.
+func createIndexSQL(name, class, kind string, fields []string) (string, error) {
+	if err := checkIndexName(name); err != nil {
+		return "", err
+	} else if kind == "" || strings.ContainsAny(kind, " \t\r\n;`'\"()") {
+		return "", fmt.Errorf("invalid index type: %q", kind)
+	}
+	if class == "" {
+		if len(fields) != 0 {
+			return "", fmt.Errorf("class name is required for index on fields")
+		}
+		return `CREATE INDEX ` + name + ` ` + kind, nil
+	} else if !isSQLName(class) {
+		return "", fmt.Errorf("invalid class name: %q", class)
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("index fields are required")
+	}
+	props := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		props[f] = nil
+	}
+	if err := checkPropNames(props); err != nil {
+		return "", err
+	}
+	return `CREATE INDEX ` + name + ` ON ` + class + ` (` + strings.Join(fields, ", ") + `) ` + kind, nil
+}
+
+// CreateIndex creates an index of a given type (see Index* constants) on class fields.
+// If class is empty, a manual index without fields is created.
+func (db *Database) CreateIndex(name, class, kind string, fields ...string) error {
+	sql, err := createIndexSQL(name, class, kind, fields)
+	if err != nil {
+		return err
+	}
+	return db.Command(NewSQLCommand(sql)).Err()
+}
+
+// DropIndex removes an index with a given name.
+func (db *Database) DropIndex(name string) error {
+	if err := checkIndexName(name); err != nil {
+		return err
+	}
+	return db.Command(NewSQLCommand(`DROP INDEX ` + name)).Err()
+}
+
+// IndexGet returns records that are stored in index under a given key. Keys of composite indexes
+// must be passed as slices with values in the order of indexed fields.
+// ErrNoRecord is not returned, empty slice means that there is no such key in index.
+func (db *Database) IndexGet(name string, key interface{}) ([]*Document, error) {
+	if err := checkIndexName(name); err != nil {
+		return nil, err
+	}
+	var docs []*Document
+	err := db.Command(NewSQLQuery(`SELECT expand(rid) FROM index:`+name+` WHERE key = ?`, key)).All(&docs)
+	if err != nil && err != ErrNoRecord {
+		return nil, err
+	}
+	return docs, nil
+}
@@ -0,0 +1,32 @@
+package orient
+
+import "testing"
+
+func TestCreateIndexSQL(t *testing.T) {
+	for _, c := range []struct {
+		name, class, kind string
+		fields            []string
+		sql               string
+	}{
+		{"Cat.name", "Cat", IndexUnique, []string{"name"}, `CREATE INDEX Cat.name ON Cat (name) UNIQUE`},
+		{"CatNameAge", "Cat", IndexNotUnique, []string{"name", "age"}, `CREATE INDEX CatNameAge ON Cat (name, age) NOTUNIQUE`},
+		{"dict", "", IndexDictionary, nil, `CREATE INDEX dict DICTIONARY`},
+		{"bad name", "Cat", IndexUnique, []string{"name"}, ""},
+		{"Cat.name", "Cat", "", []string{"name"}, ""},
+		{"Cat.name", "Cat", IndexUnique, nil, ""},
+		{"Cat.name", "Cat", IndexUnique, []string{"name) UNIQUE; DROP CLASS Cat"}, ""},
+		{"idx", "", IndexUnique, []string{"name"}, ""},
+		{"Cat.name", "Cat (name) UNIQUE; DROP CLASS Cat;", IndexUnique, []string{"name"}, ""},
+	} {
+		sql, err := createIndexSQL(c.name, c.class, c.kind, c.fields)
+		if c.sql == "" {
+			if err == nil {
+				t.Fatalf("expected an error for %+v, got: %s", c, sql)
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		} else if sql != c.sql {
+			t.Fatalf("wrong sql:\n%s\nexpected:\n%s", sql, c.sql)
+		}
+	}
+}
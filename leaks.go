@@ -0,0 +1,50 @@
+package orient
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// DetectResultLeaks enables diagnostics for Results that are not closed. If set, results returned by Database.Command
+// remember the command text and the caller, and a message is written to the Logger (see SetLogger) when such results
+// are garbage-collected while still in use. Results are in use if they were iterated with Next or NextDocument,
+// or if they were not read with All, Take, Count, Stream or Err at all.
+//
+// Detection relies on finalizers, so it's disabled by default. It should be set before executing any commands.
+var DetectResultLeaks = false
+
+// leakCheck holds a state of results tracked for leaks.
+type leakCheck struct {
+	text      string
+	caller    string
+	used      bool // results were read at once
+	iterating bool // results were read one by one and must be closed
+}
+
+// trackLeaks enables leak detection for results of a command with given text.
+// Skip is the number of stack frames to skip to find a caller of the public method.
+func (r *unknownResult) trackLeaks(text string, skip int) {
+	r.leak = &leakCheck{text: text, caller: "unknown location"}
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		r.leak.caller = fmt.Sprintf("%s:%d", file, line)
+	}
+	runtime.SetFinalizer(r, (*unknownResult).reportLeak)
+}
+
+func (r *unknownResult) reportLeak() {
+	if l := r.leak; l != nil && !r.closed && (!l.used || l.iterating) {
+		Logf("orient: results of command %q created at %s were not closed", l.text, l.caller)
+	}
+}
+
+func (r *unknownResult) markUsed() {
+	if r.leak != nil {
+		r.leak.used = true
+	}
+}
+
+func (r *unknownResult) markIterating() {
+	if r.leak != nil {
+		r.leak.iterating = true
+	}
+}
@@ -0,0 +1,62 @@
+package orient
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncLogger struct {
+	sync.Mutex
+	msgs []string
+}
+
+func (l *syncLogger) Printf(format string, args ...interface{}) {
+	l.Lock()
+	l.msgs = append(l.msgs, fmt.Sprintf(format, args...))
+	l.Unlock()
+}
+
+func (l *syncLogger) messages() []string {
+	l.Lock()
+	defer l.Unlock()
+	return append([]string(nil), l.msgs...)
+}
+
+func TestDetectResultLeaks(t *testing.T) {
+	prev := logger.l
+	defer SetLogger(prev)
+	var l syncLogger
+	SetLogger(&l)
+	DetectResultLeaks = true
+	defer func() { DetectResultLeaks = false }()
+
+	sess := &cmdSession{result: []OIdentifiable{NewEmptyDocument()}}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	func() {
+		db.Command(NewSQLQuery("SELECT FROM Leaked"))
+		var docs []*Document
+		db.Command(NewSQLQuery("SELECT FROM Read")).All(&docs)
+		res := db.Command(NewSQLQuery("SELECT FROM Closed"))
+		res.Next(&docs)
+		res.Close()
+		db.Command(NewSQLQuery("SELECT FROM Iterated")).NextDocument()
+	}()
+	var msgs []string
+	for i := 0; i < 50 && len(msgs) < 2; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+		msgs = l.messages()
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("unexpected log messages: %q", msgs)
+	}
+	for _, m := range msgs {
+		if !strings.Contains(m, "leaks_test.go") || !(strings.Contains(m, "Leaked") || strings.Contains(m, "Iterated")) {
+			t.Fatalf("unexpected log message: %q", m)
+		}
+	}
+}
@@ -31,6 +31,7 @@ type RidBag struct {
 	id       uuid.UUID
 	delegate ridBagDelegate
 	owner    *Document
+	err      error // error of the last iteration
 }
 
 func (bag *RidBag) SetOwner(doc *Document) {
@@ -113,6 +114,22 @@ func (bag *embeddedRidBag) serializeDelegate(bw *rw.Writer) error {
 	return bw.Err()
 }
 
+// replaceLinks rewrites links stored in an embedded bag, according to the map. It reports if any link was changed.
+func (bag *RidBag) replaceLinks(rids map[RID]RID) bool {
+	eb, ok := bag.delegate.(*embeddedRidBag)
+	if !ok {
+		return false // tree-based bags are stored on the server
+	}
+	changed := false
+	for i, l := range eb.links {
+		if rid, ok := rids[l.GetIdentity()]; ok {
+			eb.links[i] = rid
+			changed = true
+		}
+	}
+	return changed
+}
+
 func newSBTreeRidBag() ridBagDelegate { return &sbTreeRidBag{} }
 func newBonsaiCollectionPtr(fileId int64, pageIndex int64, pageOffset int) *bonsaiCollectionPtr {
 	return &bonsaiCollectionPtr{
@@ -193,3 +210,132 @@ func (bag *sbTreeRidBag) deserializeChanges(r *rw.Reader) (err error) {
 	bag.changes = changes
 	return r.Err()
 }
+
+// RidBagPointer points to a content of a tree-based RidBag (OSBTreeBonsai collection) on the server.
+type RidBagPointer struct {
+	FileID     int64
+	PageIndex  int64
+	PageOffset int32
+}
+
+// RidBagEntry is an entry of a tree-based RidBag: a link and a number of times it's stored in the bag.
+type RidBagEntry struct {
+	RID   RID
+	Count int
+}
+
+// RidBagTreeReader reads content of tree-based RidBags from the server. It is implemented by Database.
+type RidBagTreeReader interface {
+	// RidBagFirstKey returns the first link of a tree, or false if the tree is empty.
+	RidBagFirstKey(ptr RidBagPointer) (RID, bool, error)
+	// RidBagEntriesMajor returns up to pageSize entries with links greater than key (or equal, if inclusive is set).
+	RidBagEntriesMajor(ptr RidBagPointer, key RID, inclusive bool, pageSize int) ([]RidBagEntry, error)
+}
+
+// RidBagPageSize is a number of entries requested at once while iterating over tree-based RidBags.
+var RidBagPageSize = 128
+
+// TreePointer returns a pointer to the content of a tree-based bag. It returns false for embedded bags.
+func (bag *RidBag) TreePointer() (RidBagPointer, bool) {
+	tree, ok := bag.delegate.(*sbTreeRidBag)
+	if !ok || tree.collectionPtr == nil {
+		return RidBagPointer{}, false
+	}
+	p := tree.collectionPtr
+	return RidBagPointer{FileID: p.fileId, PageIndex: p.pageIndex, PageOffset: int32(p.pageOffset)}, true
+}
+
+// Iterate returns a function that enumerates links stored in the bag. A link is returned as many times
+// as it was added to the bag. Content of tree-based bags is read from the server page by page, with db;
+// it's not used for embedded bags. Local changes of tree-based bags are not applied.
+//
+// Iteration stops on the first error, which is returned by Err.
+//
+//		next := bag.Iterate(db)
+//		for rid, ok := next(); ok; rid, ok = next() {
+//			...
+//		}
+//		if err := bag.Err(); err != nil {
+//			...
+//		}
+func (bag *RidBag) Iterate(db RidBagTreeReader) func() (RID, bool) {
+	bag.err = nil
+	var (
+		buf   []RID
+		done  = true
+		fetch func() error
+	)
+	switch d := bag.delegate.(type) {
+	case *embeddedRidBag:
+		buf = make([]RID, 0, len(d.links))
+		for _, l := range d.links {
+			buf = append(buf, l.GetIdentity())
+		}
+	case *sbTreeRidBag:
+		ptr, ok := bag.TreePointer()
+		if !ok {
+			break
+		} else if db == nil {
+			bag.err = fmt.Errorf("cannot read tree-based RidBag without database")
+			break
+		}
+		done = false
+		var (
+			last    RID
+			started bool
+		)
+		fetch = func() error {
+			var (
+				entries []RidBagEntry
+				err     error
+			)
+			if !started {
+				var (
+					first RID
+					ok    bool
+				)
+				if first, ok, err = db.RidBagFirstKey(ptr); err != nil {
+					return err
+				} else if !ok {
+					done = true
+					return nil
+				}
+				started = true
+				entries, err = db.RidBagEntriesMajor(ptr, first, true, RidBagPageSize)
+			} else {
+				entries, err = db.RidBagEntriesMajor(ptr, last, false, RidBagPageSize)
+			}
+			if err != nil {
+				return err
+			} else if len(entries) == 0 {
+				done = true
+			}
+			for _, e := range entries {
+				for i := 0; i < e.Count; i++ {
+					buf = append(buf, e.RID)
+				}
+				last = e.RID
+			}
+			return nil
+		}
+	}
+	return func() (RID, bool) {
+		for len(buf) == 0 {
+			if done {
+				return NewEmptyRID(), false
+			} else if err := fetch(); err != nil {
+				bag.err = err
+				done = true
+				return NewEmptyRID(), false
+			}
+		}
+		rid := buf[0]
+		buf = buf[1:]
+		return rid, true
+	}
+}
+
+// Err returns an error that stopped the last iteration started with Iterate.
+func (bag *RidBag) Err() error {
+	return bag.err
+}
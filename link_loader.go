@@ -0,0 +1,302 @@
+package orient
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// LinkLoader loads linked records on demand while decoding results into Go types.
+type LinkLoader interface {
+	Load(rid RID) (*Document, error)
+}
+
+// LinkLoaderFunc is an adapter to use ordinary functions as LinkLoader.
+type LinkLoaderFunc func(rid RID) (*Document, error)
+
+// Load calls f(rid).
+func (f LinkLoaderFunc) Load(rid RID) (*Document, error) { return f(rid) }
+
+// LinkLoader returns a loader that reads linked records from this database. It is used by Command results,
+// so links that were not fetched can be decoded into documents and LazyLink fields, which are loaded lazily,
+// on first access to their content, and into structs if Results.LoadLinks is used.
+func (db *Database) LinkLoader() LinkLoader {
+	return LinkLoaderFunc(func(rid RID) (*Document, error) {
+		rec, err := db.GetRecordByRID(rid, "", false)
+		if err != nil {
+			return nil, err
+		}
+		return loadedDocument(rid, rec)
+	})
+}
+
+// PrefetchedLoader returns a loader that serves documents from a pre-fetched set (see Database.Load)
+// without requests to the server. Other records are loaded by fallback loader, which can be nil.
+func PrefetchedLoader(docs []*Document, fallback LinkLoader) LinkLoader {
+	byRID := make(map[RID]*Document, len(docs))
+	for _, doc := range docs {
+		byRID[doc.GetIdentity()] = doc
+	}
+	return &prefetchedLoader{byRID: byRID, fallback: fallback}
+}
+
+type prefetchedLoader struct {
+	byRID    map[RID]*Document
+	fallback LinkLoader
+}
+
+func (l *prefetchedLoader) Load(rid RID) (*Document, error) {
+	if doc, ok := l.byRID[rid]; ok {
+		return doc, nil
+	} else if l.fallback == nil {
+		return nil, fmt.Errorf("record %v was not pre-fetched", rid)
+	}
+	return l.fallback.Load(rid)
+}
+
+// prefetched returns a pre-fetched document without calling fallback loader.
+func (l *prefetchedLoader) prefetched(rid RID) (*Document, bool) {
+	doc, ok := l.byRID[rid]
+	return doc, ok
+}
+
+// lazyLoad loads content of a link stub on first access. It is safe to access the stub from multiple goroutines:
+// the record is loaded only once, and other goroutines wait for it.
+type lazyLoad struct {
+	once   sync.Once
+	loader LinkLoader
+	err    error
+	done   uint32 // set atomically after the load attempt
+}
+
+// attempted checks if the record was already loaded, or failed to load.
+func (l *lazyLoad) attempted() bool {
+	return atomic.LoadUint32(&l.done) != 0
+}
+
+// newLazyDocument returns a document stub for a link. Record content is loaded with a given loader on first
+// access to document fields or class name. Load errors are returned by methods that return errors, like ToMap.
+// Pre-fetched records are returned as is.
+func newLazyDocument(rid RID, loader LinkLoader) *Document {
+	if p, ok := loader.(*prefetchedLoader); ok {
+		if doc, ok := p.prefetched(rid); ok {
+			return doc
+		}
+	}
+	doc := NewDocumentFromRID(rid)
+	doc.lazy = &lazyLoad{loader: loader}
+	return doc
+}
+
+// ensureLoaded loads content of a link stub, if it was not loaded yet.
+func (doc *Document) ensureLoaded() error {
+	l := doc.lazy
+	if l == nil {
+		return nil
+	}
+	l.once.Do(func() {
+		defer atomic.StoreUint32(&l.done, 1)
+		src, err := l.loader.Load(doc.RID)
+		if err == nil {
+			err = src.ensureDecoded()
+		}
+		if err != nil {
+			l.err = err
+			return
+		}
+		doc.Vers = src.Vers
+		doc.classname = src.classname
+		doc.fields = src.fields
+		doc.fieldsOrder = src.fieldsOrder
+		doc.raw = src.raw
+	})
+	return l.err
+}
+
+// LazyLink is a link that is loaded only when it's content is requested. It can be used as a struct field type
+// to delay reading of linked records:
+//
+//		type Post struct {
+//			Title  string
+//			Author orient.LazyLink
+//		}
+//		...
+//		var user User
+//		err := post.Author.Decode(&user)
+type LazyLink struct {
+	RID    RID
+	loader LinkLoader
+	names  *FieldMapping
+	doc    *Document
+}
+
+// Document returns linked document, loading it if necessary.
+func (l *LazyLink) Document() (*Document, error) {
+	if l.doc != nil {
+		return l.doc, nil
+	} else if l.loader == nil {
+		return nil, fmt.Errorf("no loader for link %v", l.RID)
+	}
+	doc, err := l.loader.Load(l.RID)
+	if err != nil {
+		return nil, err
+	}
+	l.doc = doc
+	return doc, nil
+}
+
+// GetIdentity implements OIdentifiable interface on LazyLink.
+func (l *LazyLink) GetIdentity() RID {
+	return l.RID
+}
+
+// GetRecord returns linked document if it was already loaded, or nil otherwise. It never loads the link.
+func (l *LazyLink) GetRecord() interface{} {
+	if l.doc == nil {
+		return nil
+	}
+	return l.doc
+}
+
+// Decode loads linked document and decodes it into out. Links of the document that were not fetched are not
+// followed: pointers to structs are left nil, use LazyLink fields for links that should be loaded later.
+func (l *LazyLink) Decode(out interface{}) error {
+	doc, err := l.Document()
+	if err != nil {
+		return err
+	}
+	c := &typeConverter{loader: l.loader, names: l.names, depth: -1}
+	return c.mapToStruct(doc, out)
+}
+
+var (
+	reflLazyLinkType    = reflect.TypeOf(LazyLink{})
+	reflLazyLinkPtrType = reflect.TypeOf((*LazyLink)(nil))
+)
+
+// typeConverter converts results into Go types. If loader is set, links that were not fetched and are decoded
+// into documents are loaded lazily, on first access to document content. Links decoded into structs, pointers
+// to structs and maps are loaded only up to depth levels, unless they were pre-fetched. If names is set,
+// it's applied to decoded structs.
+type typeConverter struct {
+	loader LinkLoader
+	names  *FieldMapping
+	// depth is a number of link levels that are loaded into structs and maps (see Results.LoadLinks).
+	// Zero means that such links are not loaded, and negative value means that the last level was loaded
+	// and deeper links are left nil.
+	depth int
+	// workers is a number of goroutines used to decode slices with at least threshold elements.
+	workers, threshold int
+}
+
+// next returns a converter for records loaded by c.
+func (c *typeConverter) next() *typeConverter {
+	n := *c
+	if n.depth == 1 {
+		n.depth = -1
+	} else if n.depth > 1 {
+		n.depth--
+	}
+	return &n
+}
+
+// convertLink is like convertLink function, but also handles LazyLink targets and loads links into documents,
+// structs and maps.
+func (c *typeConverter) convertLink(targ, src reflect.Value) (bool, error) {
+	id, ok := src.Interface().(OIdentifiable)
+	if !ok {
+		return false, nil
+	}
+	switch {
+	case targ.Type() == reflLazyLinkType || targ.Type() == reflLazyLinkPtrType:
+		l := &LazyLink{RID: id.GetIdentity(), loader: c.loader, names: c.names}
+		if doc, ok := id.(*Document); ok {
+			l.doc = doc
+		}
+		if targ.Kind() == reflect.Ptr {
+			targ.Set(reflect.ValueOf(l))
+		} else {
+			targ.Set(reflect.ValueOf(*l))
+		}
+		return true, nil
+	case c.loader != nil && targ.Type() == reflDocumentType:
+		rid, ok := id.(RID)
+		if !ok {
+			return false, nil
+		}
+		targ.Set(reflect.ValueOf(newLazyDocument(rid, c.loader)))
+		return true, nil
+	case c.loader != nil && isLoadTarget(targ.Type()):
+		rid, ok := id.(RID)
+		if !ok { // fetched records are decoded as usual
+			return false, nil
+		}
+		if p, ok := c.loader.(*prefetchedLoader); ok {
+			if doc, ok := p.prefetched(rid); ok {
+				return true, c.convert(targ, reflect.ValueOf(doc))
+			}
+		}
+		if c.depth == 0 {
+			break
+		} else if c.depth < 0 { // links of the last loaded level are not followed
+			targ.Set(reflect.Zero(targ.Type()))
+			return true, nil
+		}
+		if targ.Kind() == reflect.Ptr {
+			v, err := c.load(targ.Type(), rid)
+			if err != nil {
+				return true, err
+			}
+			targ.Set(v)
+			return true, nil
+		}
+		doc, err := c.loader.Load(rid)
+		if err != nil {
+			return true, err
+		}
+		return true, c.next().convert(targ, reflect.ValueOf(doc))
+	}
+	return convertLink(targ, src)
+}
+
+// isLoadTarget checks if links decoded into values of type t are loaded: t must be a struct, a pointer to struct
+// or a map with string keys.
+func isLoadTarget(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return t.Elem().Kind() == reflect.Struct && t != reflDocumentType && t != reflLazyLinkPtrType
+	case reflect.Struct:
+		return t != reflRIDType && t != reflLazyLinkType
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String
+	}
+	return false
+}
+
+// load reads a linked record and decodes it into a new value of type typ (a pointer to struct).
+func (c *typeConverter) load(typ reflect.Type, rid RID) (reflect.Value, error) {
+	doc, err := c.loader.Load(rid)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	v := reflect.New(typ.Elem())
+	if err = c.next().mapToStruct(doc, v.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return v, nil
+}
+
+// linkHook is a map decoder hook that applies convertLink to struct fields.
+func (c *typeConverter) linkHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if _, ok := data.(OIdentifiable); !ok {
+		return data, nil
+	}
+	out := reflect.New(t).Elem()
+	if ok, err := c.convertLink(out, reflect.ValueOf(data)); err != nil {
+		return nil, err
+	} else if ok {
+		return out.Interface(), nil
+	}
+	return data, nil
+}
@@ -0,0 +1,91 @@
+package orient
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+type fakeRidBagTree struct {
+	ptr     RidBagPointer
+	entries []RidBagEntry
+	calls   int
+}
+
+func (t *fakeRidBagTree) RidBagFirstKey(ptr RidBagPointer) (RID, bool, error) {
+	if ptr != t.ptr || len(t.entries) == 0 {
+		return RID{}, false, nil
+	}
+	return t.entries[0].RID, true, nil
+}
+
+func (t *fakeRidBagTree) RidBagEntriesMajor(ptr RidBagPointer, key RID, inclusive bool, pageSize int) ([]RidBagEntry, error) {
+	t.calls++
+	var out []RidBagEntry
+	for _, e := range t.entries {
+		if len(out) < pageSize && (e.RID.ClusterPos > key.ClusterPos || inclusive && e.RID == key) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func TestRidBagIterateTree(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	w := rw.NewWriter(buf)
+	w.WriteByte(0) // tree-based, no UUID
+	w.WriteLong(3)
+	w.WriteLong(7)
+	w.WriteInt(64)
+	w.WriteInt(-1) // cached size
+	w.WriteInt(0)  // changes
+	bag := new(RidBag)
+	if err := bag.FromStream(buf); err != nil {
+		t.Fatal(err)
+	} else if ptr, ok := bag.TreePointer(); !ok || ptr != (RidBagPointer{FileID: 3, PageIndex: 7, PageOffset: 64}) {
+		t.Fatalf("wrong pointer: %v", ptr)
+	}
+	tree := &fakeRidBagTree{ptr: RidBagPointer{FileID: 3, PageIndex: 7, PageOffset: 64}}
+	var exp []RID
+	for i := 0; i < 5; i++ {
+		rid := NewRID(10, int64(i))
+		tree.entries = append(tree.entries, RidBagEntry{RID: rid, Count: 1 + i%2})
+		for j := 0; j < 1+i%2; j++ {
+			exp = append(exp, rid)
+		}
+	}
+	defer func(n int) { RidBagPageSize = n }(RidBagPageSize)
+	RidBagPageSize = 2
+
+	var got []RID
+	next := bag.Iterate(tree)
+	for rid, ok := next(); ok; rid, ok = next() {
+		got = append(got, rid)
+	}
+	if err := bag.Err(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("wrong links: %v != %v", got, exp)
+	} else if tree.calls != 4 { // 3 pages and an empty one
+		t.Fatalf("unexpected number of requests: %d", tree.calls)
+	}
+
+	if _, ok := bag.Iterate(nil)(); ok || bag.Err() == nil {
+		t.Fatal("expected an error without database")
+	}
+}
+
+func TestRidBagIterateEmbedded(t *testing.T) {
+	bag := NewRidBag()
+	bag.delegate.(*embeddedRidBag).links = []OIdentifiable{NewRID(9, 1), NewRID(9, 2)}
+	var got []RID
+	next := bag.Iterate(nil)
+	for rid, ok := next(); ok; rid, ok = next() {
+		got = append(got, rid)
+	}
+	if exp := []RID{NewRID(9, 1), NewRID(9, 2)}; !reflect.DeepEqual(got, exp) || bag.Err() != nil {
+		t.Fatalf("wrong links: %v != %v (%v)", got, exp, bag.Err())
+	}
+}
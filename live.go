@@ -0,0 +1,122 @@
+package orient
+
+import (
+	"fmt"
+	"io"
+)
+
+// LiveOperation is a type of record change reported by live query.
+type LiveOperation byte
+
+// List of record changes reported by live queries. Values are defined by OrientDB binary protocol.
+const (
+	LiveUpdate LiveOperation = 1
+	LiveDelete LiveOperation = 2
+	LiveCreate LiveOperation = 3
+)
+
+func (op LiveOperation) String() string {
+	switch op {
+	case LiveUpdate:
+		return "update"
+	case LiveDelete:
+		return "delete"
+	case LiveCreate:
+		return "create"
+	}
+	return fmt.Sprintf("LiveOperation(%d)", byte(op))
+}
+
+// LiveResult is a single notification pushed by server for live query subscription.
+// If record content cannot be decoded, Err is set and Doc is nil.
+type LiveResult struct {
+	Op  LiveOperation
+	Doc *Document
+	Err error
+}
+
+// LiveQuery is a LIVE SELECT SQL command. Supported since OrientDB 2.1.
+//
+// OLiveQuery in Java world.
+type LiveQuery struct {
+	SQLQuery
+}
+
+// NewLiveQuery creates a new live query with given params. Query text must not start with LIVE keyword.
+func NewLiveQuery(sql string, params ...interface{}) LiveQuery {
+	return LiveQuery{SQLQuery: NewSQLQuery(sql, params...)}
+}
+
+// GetText returns query text
+func (rq LiveQuery) GetText() string { return "LIVE " + rq.text }
+
+// GetClassName returns Java class name
+func (rq LiveQuery) GetClassName() string {
+	return "com.orientechnologies.orient.core.sql.query.OLiveQuery"
+}
+
+// ToStream serializes command to specified Writer
+func (rq LiveQuery) ToStream(w io.Writer) error {
+	q := rq.SQLQuery
+	q.text = rq.GetText()
+	return q.ToStream(w)
+}
+
+// LiveQuery subscribes to changes of records matched by a given SELECT query. Example:
+//
+//		token, ch, err := db.LiveQuery("SELECT FROM V WHERE type = ?", "user")
+//		...
+//		for r := range ch {
+//			// r.Op is LiveCreate, LiveUpdate or LiveDelete
+//		}
+//
+// Each subscription uses a dedicated connection, which is closed by Unsubscribe. Channel is closed
+// after Unsubscribe call or when the connection is lost.
+func (db *Database) LiveQuery(sql string, params ...interface{}) (token int, ch <-chan LiveResult, err error) {
+	if db.pool.dial == nil {
+		return 0, nil, ErrInvalidConn{Msg: "database is not opened"}
+	}
+	conn, err := db.pool.dial()
+	if err != nil {
+		return 0, nil, err
+	}
+	conn.SetTimeout(db.commandTimeout())
+	token, ch, err = conn.LiveQuery(NewLiveQuery(sql, params...))
+	if err != nil {
+		conn.Close()
+		return 0, nil, convertError(err)
+	}
+	db.livemu.Lock()
+	if db.live == nil {
+		db.live = make(map[int]DBSession)
+	}
+	db.live[token] = conn
+	db.livemu.Unlock()
+	return token, ch, nil
+}
+
+// Unsubscribe stops live query with a given token and closes it's results channel.
+func (db *Database) Unsubscribe(token int) error {
+	db.livemu.Lock()
+	conn, ok := db.live[token]
+	delete(db.live, token)
+	db.livemu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown live query token: %d", token)
+	}
+	err := conn.Unsubscribe(token)
+	if err1 := conn.Close(); err == nil {
+		err = err1
+	}
+	return convertError(err)
+}
+
+func (db *Database) closeLive() {
+	db.livemu.Lock()
+	live := db.live
+	db.live = nil
+	db.livemu.Unlock()
+	for _, conn := range live {
+		conn.Close()
+	}
+}
@@ -0,0 +1,36 @@
+package orient
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// Logger receives diagnostic messages of the driver, like unsupported server versions or unknown value types.
+// *log.Logger implements this interface.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+var logger = struct {
+	sync.RWMutex
+	l Logger
+}{l: log.New(os.Stderr, "", log.LstdFlags)}
+
+// SetLogger sets a logger for driver diagnostics. By default, messages are written to stderr, the same way
+// as with the standard log package. Passing nil logger disables logging.
+func SetLogger(l Logger) {
+	logger.Lock()
+	logger.l = l
+	logger.Unlock()
+}
+
+// Logf writes a message to the logger set with SetLogger. It's used by protocol implementations.
+func Logf(format string, args ...interface{}) {
+	logger.RLock()
+	l := logger.l
+	logger.RUnlock()
+	if l != nil {
+		l.Printf(format, args...)
+	}
+}
@@ -0,0 +1,30 @@
+package orient
+
+import (
+	"fmt"
+	"testing"
+)
+
+type testLogger []string
+
+func (l *testLogger) Printf(format string, args ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, args...))
+}
+
+func TestSetLogger(t *testing.T) {
+	prev := logger.l
+	defer SetLogger(prev)
+	var l testLogger
+	SetLogger(&l)
+	doc := NewEmptyDocument()
+	doc.SetField("customFields", 1)
+	NewOClassFromDocument(doc)
+	if len(l) != 1 || l[0] != "unknown type for customFields: int" {
+		t.Fatalf("unexpected log messages: %q", []string(l))
+	}
+	SetLogger(nil)
+	Logf("silenced")
+	if len(l) != 1 {
+		t.Fatal("message was logged after logger was removed")
+	}
+}
@@ -1,18 +1,251 @@
 package orient
 
 import (
+	"fmt"
 	"github.com/mitchellh/mapstructure"
 	"reflect"
+	"strings"
 	"time"
+	"unicode"
 )
 
 // TagName is a name for a struct tag used for types conversion using reflect
 var TagName = "mapstructure"
 
+// OrientTagName is a name for a struct tag that binds fields to document fields or metadata, regardless of TagName:
+//
+//		type Person struct {
+//			RID     orient.RID `orient:"@rid"`
+//			Version int        `orient:"@version"`
+//			Class   string     `orient:"@class"`
+//			Name    string     `orient:"name"`
+//		}
+//
+// Metadata fields are not stored as document fields when a document is created from a struct.
+//
+// A field of type map[string]interface{} with "extra" option collects document fields that have no matching
+// struct fields. These fields are stored back when a document is created from the struct:
+//
+//		type Person struct {
+//			Name  string
+//			Extra map[string]interface{} `orient:",extra"`
+//		}
+//
+// Option "type" pins the OrientDB type of a document field created from a struct field:
+//
+//		type Person struct {
+//			Age int `orient:"age,type=SHORT"`
+//		}
+//
+// Option "class" sets a class name of embedded documents created from a struct field (a struct, a map or a slice
+// of them), unless the embedded value sets its own class with "@class" field. Class of embedded documents is
+// decoded into a nested struct field tagged with "@class":
+//
+//		type Person struct {
+//			Home Address `orient:"home,class=Address"`
+//		}
+const OrientTagName = "orient"
+
+// FieldMapping controls how struct fields are matched with document fields when documents are decoded into structs
+// and created from structs. It's set for command results with Database.SetFieldMapping, and can be applied
+// to a single document with ToStruct and From methods. Both settings also apply to fields of squashed structs.
+//
+// For example, CamelToSnake maps CamelCase struct fields to snake_case document fields:
+//
+//		db.SetFieldMapping(orient.FieldMapping{NameMapper: orient.CamelToSnake})
+type FieldMapping struct {
+	// NameMapper translates names of struct fields into names of document fields, for fields that have no name
+	// set with TagName or OrientTagName tags. Nil means that struct field names are used as is (decoding is
+	// case-insensitive).
+	NameMapper func(goFieldName string) string
+	// Renames allows to decode documents into structs that still use an old field name, after the field was renamed
+	// on the server (or vice versa). It maps class names to old field names to new ones. When decoding into a struct,
+	// both names resolve to the same value. When encoding a document of this class from a struct, the old name
+	// is replaced with the new one.
+	Renames map[string]map[string]string
+}
+
+// ToStruct fills provided struct with content of a Document, like Document.ToStruct, using the field mapping.
+func (fm *FieldMapping) ToStruct(doc *Document, o interface{}) error {
+	return (&typeConverter{names: fm}).mapToStruct(doc, o)
+}
+
+// From sets document fields from a struct or a map, like Document.From, using the field mapping.
+func (fm *FieldMapping) From(doc *Document, o interface{}) error {
+	return doc.from(o, fm)
+}
+
+// SetFieldMapping sets field mapping that is used to decode results of commands into structs. Zero value
+// restores default matching of fields.
+func (db *Database) SetFieldMapping(fm FieldMapping) {
+	renames := make(map[string]map[string]string, len(fm.Renames))
+	for class, m := range fm.Renames { // copy, so changes made by a caller will not race with decoding
+		renames[class] = make(map[string]string, len(m))
+		for k, v := range m {
+			renames[class][k] = v
+		}
+	}
+	fm.Renames = renames
+	db.mu.Lock()
+	db.names = &fm
+	db.mu.Unlock()
+}
+
+func (db *Database) fieldMapping() *FieldMapping {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.names
+}
+
+// fieldName returns a document field name for struct field that has no name tags, if NameMapper is set.
+func (fm *FieldMapping) fieldName(fld reflect.StructField) (string, bool) {
+	if fm == nil || fm.NameMapper == nil {
+		return "", false
+	} else if name := strings.Split(fld.Tag.Get(TagName), ",")[0]; name != "" {
+		return "", false
+	} else if name = strings.Split(fld.Tag.Get(OrientTagName), ",")[0]; name != "" {
+		return "", false
+	} else if hasTagOption(fld.Tag.Get(OrientTagName), "extra") {
+		return "", false
+	}
+	return fm.NameMapper(fld.Name), true
+}
+
+// renamed returns a current name of a given field for specified class.
+func (fm *FieldMapping) renamed(class, name string) string {
+	if fm == nil || class == "" {
+		return name
+	}
+	if nm, ok := fm.Renames[class][name]; ok {
+		return nm
+	}
+	return name
+}
+
+// applyRenames returns a copy of document map where each renamed field is available under both old and new names.
+func (fm *FieldMapping) applyRenames(m map[string]interface{}) map[string]interface{} {
+	class, _ := m["@class"].(string)
+	if fm == nil || class == "" {
+		return m
+	}
+	renames := fm.Renames[class]
+	if len(renames) == 0 {
+		return m
+	}
+	out := make(map[string]interface{}, len(m)+len(renames))
+	for k, v := range m {
+		out[k] = v
+	}
+	for oldName, newName := range renames {
+		if v, ok := m[newName]; ok {
+			if _, ok = m[oldName]; !ok {
+				out[oldName] = v
+			}
+		} else if v, ok := m[oldName]; ok {
+			out[newName] = v
+		}
+	}
+	return out
+}
+
+// mappedKeys copies map values of document fields named by NameMapper to keys expected by the decoder for struct
+// fields of t, including fields of squashed structs. Map is copied before the first change.
+func (fm *FieldMapping) mappedKeys(t reflect.Type, m, out map[string]interface{}) map[string]interface{} {
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !isExported(fld.Name) {
+			continue
+		}
+		if tags := strings.Split(fld.Tag.Get(TagName), ","); len(tags) > 1 && tags[1] == "squash" && fld.Type.Kind() == reflect.Struct {
+			out = fm.mappedKeys(fld.Type, m, out)
+			continue
+		}
+		name, ok := fm.fieldName(fld)
+		if !ok {
+			continue
+		}
+		v, ok := m[name]
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+		out[fld.Name] = v
+	}
+	return out
+}
+
+// decodeHook converts documents decoded into structs to maps and applies the field mapping to them.
+func (fm *FieldMapping) decodeHook(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if fm == nil || st.Kind() != reflect.Struct || t == reflDocumentType || st == reflDocumentType.Elem() {
+		return data, nil
+	}
+	if doc, ok := data.(*Document); ok && doc != nil {
+		mp, err := doc.ToMap()
+		if err != nil {
+			return nil, err
+		}
+		data = mp
+	}
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+	m = fm.applyRenames(m)
+	if out := fm.mappedKeys(st, m, nil); out != nil {
+		m = out
+	}
+	return m, nil
+}
+
+// CamelToSnake converts CamelCase names to snake_case, keeping abbreviations together: "UserID" becomes "user_id",
+// and "HTTPServer" becomes "http_server". It can be used as FieldMapping.NameMapper.
+func CamelToSnake(name string) string {
+	rs := []rune(name)
+	buf := make([]rune, 0, len(rs)+4)
+	for i, r := range rs {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+				buf = append(buf, '_')
+			}
+		}
+		buf = append(buf, unicode.ToLower(r))
+	}
+	return string(buf)
+}
+
+// SnakeToCamel converts snake_case names to CamelCase: "user_name" becomes "UserName". It's an inverse
+// of CamelToSnake for names without abbreviations.
+func SnakeToCamel(name string) string {
+	parts := strings.Split(name, "_")
+	for i, p := range parts {
+		if rs := []rune(p); len(rs) > 0 {
+			rs[0] = unicode.ToUpper(rs[0])
+			parts[i] = string(rs)
+		}
+	}
+	return strings.Join(parts, "")
+}
+
 var mapDecoderHooks = []mapstructure.DecodeHookFunc{
+	valueSerializerHookFunc,
 	stringToTimeHookFunc,
 	stringToByteSliceHookFunc,
+	linkHookFunc,
 	documentToMapHookFunc,
+	orientTagHookFunc,
+	stringToGeometryHookFunc,
+	numberHookFunc,
+	setHookFunc,
 }
 
 // RegisterMapDecoderHook allows to register additional hook for map decoder
@@ -21,10 +254,12 @@ func RegisterMapDecoderHook(hook mapstructure.DecodeHookFunc) {
 }
 
 // NewMapDecoder returns decoder configured for decoding data into result with all registered hooks.
-func newMapDecoder(result interface{}) (*mapstructure.Decoder, error) {
+// Additional hooks are called before registered ones. If md is not nil, it will be filled with decoded keys.
+func newMapDecoder(result interface{}, md *mapstructure.Metadata, hooks ...mapstructure.DecodeHookFunc) (*mapstructure.Decoder, error) {
+	hooks = append(hooks, mapDecoderHooks...)
 	return mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-		DecodeHook: mapstructure.ComposeDecodeHookFunc(mapDecoderHooks...),
-		Metadata:   nil,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(hooks...),
+		Metadata:   md,
 		Result:     result,
 		TagName:    TagName,
 	})
@@ -51,11 +286,185 @@ func stringToByteSliceHookFunc(f reflect.Type, t reflect.Type, data interface{})
 	return []byte(data.(string)), nil
 }
 
-var reflDocumentType = reflect.TypeOf((*Document)(nil))
+// numberHookFunc checks numeric conversions for overflows, unless TruncateNumbers is set.
+func numberHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if f == t || !isNumberKind(f.Kind()) || !isNumberKind(t.Kind()) {
+		return data, nil
+	}
+	v, err := convertNumber(reflect.ValueOf(data), t)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
 
+var (
+	reflDocumentType = reflect.TypeOf((*Document)(nil))
+	reflRIDType      = reflect.TypeOf(RID{})
+)
+
+// linkHookFunc converts links to RID, *Document and string fields, as described in convertLink. It allows to decode
+// LINKMAP fields into structs with one field per map key.
+func linkHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if t != reflRIDType && t != reflDocumentType && t.Kind() != reflect.String {
+		return data, nil
+	}
+	out := reflect.New(t).Elem()
+	if ok, err := convertLink(out, reflect.ValueOf(data)); err != nil {
+		return nil, err
+	} else if ok {
+		return out.Interface(), nil
+	}
+	return data, nil
+}
+
+// documentToMapHookFunc converts documents to maps, unless they are decoded into *Document or interface{} values.
 func documentToMapHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
-	if f != reflDocumentType {
+	if f != reflDocumentType || t == reflDocumentType || t.Kind() == reflect.Interface {
 		return data, nil
 	}
 	return data.(*Document).ToMap()
 }
+
+// orientTagHookFunc copies map values to keys expected by the decoder for struct fields with OrientTagName tag.
+func orientTagHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	m, ok := data.(map[string]interface{})
+	if !ok || t.Kind() != reflect.Struct {
+		return data, nil
+	}
+	var out map[string]interface{}
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		name := strings.Split(fld.Tag.Get(OrientTagName), ",")[0]
+		if name == "" || name == "-" || !isExported(fld.Name) {
+			continue
+		}
+		v, ok := m[name]
+		if !ok {
+			continue
+		}
+		key := fld.Name
+		if tag := strings.Split(fld.Tag.Get(TagName), ",")[0]; tag != "" && tag != "-" {
+			key = tag
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+		out[key] = v
+	}
+	if out == nil {
+		return data, nil
+	}
+	return out, nil
+}
+
+// hasTagOption checks if a comma-separated tag value has a given option (after the name).
+func hasTagOption(tag, opt string) bool {
+	parts := strings.Split(tag, ",")
+	for _, p := range parts[1:] {
+		if p == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// tagOptionValue returns a value of "key=value" option of a comma-separated tag value.
+func tagOptionValue(tag, key string) (string, bool) {
+	parts := strings.Split(tag, ",")
+	for _, p := range parts[1:] {
+		if strings.HasPrefix(p, key+"=") {
+			return strings.TrimPrefix(p, key+"="), true
+		}
+	}
+	return "", false
+}
+
+// tagFieldType returns a type pinned by "type" option of OrientTagName tag value.
+func tagFieldType(tag string) (OType, bool, error) {
+	name, ok := tagOptionValue(tag, "type")
+	if !ok {
+		return UNKNOWN, false, nil
+	}
+	name = strings.ToUpper(name)
+	tp, ok := lookupOType(name)
+	if !ok {
+		return UNKNOWN, true, fmt.Errorf("unknown type in tag: %q", name)
+	}
+	return tp, true, nil
+}
+
+// extraField returns an index of a struct field tagged with `orient:",extra"`, or -1 if there is none.
+// Such field must be of type map[string]interface{}.
+func extraField(t reflect.Type) int {
+	for i := 0; i < t.NumField(); i++ {
+		if fld := t.Field(i); isExported(fld.Name) && hasTagOption(fld.Tag.Get(OrientTagName), "extra") &&
+			fld.Type == reflInterfaceMapType {
+			return i
+		}
+	}
+	return -1
+}
+
+// knownFieldNames collects lower-cased names of document fields that are decoded into struct fields,
+// including fields of squashed structs.
+func knownFieldNames(t reflect.Type, class string, fm *FieldMapping, names map[string]struct{}) {
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+		if !isExported(fld.Name) {
+			continue
+		}
+		otag := fld.Tag.Get(OrientTagName)
+		if hasTagOption(otag, "extra") {
+			continue
+		}
+		tags := strings.Split(fld.Tag.Get(TagName), ",")
+		if len(tags) > 1 && tags[1] == "squash" && fld.Type.Kind() == reflect.Struct {
+			knownFieldNames(fld.Type, class, fm, names)
+			continue
+		}
+		mapped, _ := fm.fieldName(fld)
+		for _, name := range []string{fld.Name, tags[0], strings.Split(otag, ",")[0], mapped} {
+			if name != "" && name != "-" {
+				names[strings.ToLower(name)] = struct{}{}
+				names[strings.ToLower(fm.renamed(class, name))] = struct{}{}
+			}
+		}
+	}
+}
+
+// setExtraFields stores document fields that have no matching struct fields into a field tagged
+// with `orient:",extra"`, if the struct has one. Metadata fields (like @rid) are not stored.
+func setExtraFields(m map[string]interface{}, val interface{}, fm *FieldMapping) {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+	rv = rv.Elem()
+	ind := extraField(rv.Type())
+	if ind < 0 {
+		return
+	}
+	class, _ := m["@class"].(string)
+	known := make(map[string]struct{})
+	knownFieldNames(rv.Type(), class, fm, known)
+	var extra map[string]interface{}
+	for k, v := range m {
+		if strings.HasPrefix(k, "@") {
+			continue
+		} else if _, ok := known[strings.ToLower(k)]; ok {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]interface{})
+		}
+		extra[k] = v
+	}
+	rv.Field(ind).Set(reflect.ValueOf(extra))
+}
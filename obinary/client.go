@@ -2,9 +2,9 @@ package obinary
 
 import (
 	"bufio"
+	"crypto/tls"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"sync"
 	"time"
@@ -14,8 +14,8 @@ import (
 )
 
 func init() {
-	orient.RegisterProto(orient.ProtoBinary, func(addr string) (orient.DBConnection, error) {
-		return Dial(addr)
+	orient.RegisterProto(orient.ProtoBinary, func(addr string, opts orient.DialOptions) (orient.DBConnection, error) {
+		return dial(addr, opts)
 	})
 }
 
@@ -41,18 +41,40 @@ func validateAddr(addr string) (string, error) {
 // The Client returned is ready to make calls to the OrientDB but has not
 // yet established a database session or a session with the OrientDB server.
 // After this, the user needs to call either OpenDatabase or CreateServerSession.
-func Dial(addr string) (*Client, error) {
+func Dial(addr string, opts ...orient.DialOption) (*Client, error) {
+	var o orient.DialOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return dial(addr, o)
+}
+
+func dial(addr string, opts orient.DialOptions) (*Client, error) {
 	addr, err := validateAddr(addr)
 	if err != nil {
 		return nil, err
 	}
+	var ser orient.RecordSerializer
+	if opts.RecordFormat != "" {
+		if ser, err = orient.NewRecordSerializer(opts.RecordFormat); err != nil {
+			return nil, err
+		}
+	} else {
+		ser = orient.GetDefaultRecordSerializer()
+	}
 	conn, err := net.DialTimeout("tcp", addr, time.Minute)
 	if err != nil {
 		return nil, err
 	}
+	if opts.TLS != nil {
+		if conn, err = tlsHandshake(conn, addr, opts.TLS); err != nil {
+			return nil, err
+		}
+	}
 	c := &Client{
 		addr: addr, conn: conn, done: make(chan struct{}),
 		br: bufio.NewReader(conn), bw: bufio.NewWriter(conn),
+		recordFormat: ser, useToken: opts.TokenSession,
 	}
 	c.pr = rw.NewReader(c.br)
 	c.pw = rw.NewWriter(c.bw)
@@ -66,6 +88,22 @@ func Dial(addr string) (*Client, error) {
 	return c, nil
 }
 
+// tlsHandshake wraps connection with TLS. Handshake is done before OrientDB protocol handshake.
+func tlsHandshake(conn net.Conn, addr string, cfg *tls.Config) (net.Conn, error) {
+	if cfg.ServerName == "" {
+		cfg = cfg.Clone()
+		cfg.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tc := tls.Client(conn, cfg)
+	tc.SetDeadline(time.Now().Add(time.Minute))
+	if err := tc.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tc.SetDeadline(time.Time{})
+	return tc, nil
+}
+
 // Client encapsulates the active TCP connection to an OrientDB server
 // to be used with the Network Binary Protocol.
 // It also may be connected to up to one database at a time.
@@ -96,6 +134,13 @@ type Client struct {
 	curProtoVers int
 
 	recordFormat orient.RecordSerializer
+	useToken     bool // request token-based database sessions
+
+	livemu sync.Mutex
+	live   map[int32]*liveSub
+
+	errmu  sync.Mutex
+	runErr error // error that stopped reading loop
 }
 
 func (c *Client) handshakeVersion() error {
@@ -111,10 +156,9 @@ func (c *Client) handshakeVersion() error {
 	} else if c.srvProtoVers < minBinarySerializerVersion { // may switch to CSV serialization, but we don't care for now
 		return ErrUnsupportedVersion(c.srvProtoVers)
 	} else if c.srvProtoVers > MaxProtocolVersion {
-		log.Printf("OrientDB version is unsupported by driver: %d vs %d. Will fallback to protocol %d.",
+		orient.Logf("OrientDB version is unsupported by driver: %d vs %d. Will fallback to protocol %d.",
 			MaxProtocolVersion, c.srvProtoVers, CurrentProtoVersion)
 	}
-	c.recordFormat = orient.GetDefaultRecordSerializer()
 	c.curProtoVers = CurrentProtoVersion
 	if c.curProtoVers > c.srvProtoVers {
 		c.curProtoVers = c.srvProtoVers
@@ -122,11 +166,14 @@ func (c *Client) handshakeVersion() error {
 	return nil
 }
 
-func (c *Client) writeCmd(op byte, sid int32, wr func(*rw.Writer) error) error {
+func (c *Client) writeCmd(op byte, sid int32, token []byte, wr func(*rw.Writer) error) error {
 	c.cmuw.Lock()
 	defer c.cmuw.Unlock()
 	c.pw.WriteByte(op)
 	c.pw.WriteInt(sid)
+	if token != nil {
+		c.pw.WriteBytes(token)
+	}
 	if wr != nil {
 		if err := wr(c.pw); err != nil {
 			return err
@@ -234,23 +281,30 @@ func readErrorResponse(r *rw.Reader, protoVers int) (serverException error) {
 		exc = append(exc, orient.UnknownException{Class: exClass, Message: exMsg})
 	}
 
-	// Next there is a serialized exception of bytes, but it is only
-	// useful to Java clients, so read and ignore if present.
+	// Next there is a Java-serialized exception with a stack trace, which is kept as is.
+	var serialized []byte
 	if protoVers >= ProtoVersion19 {
-		_ = r.ReadBytes()
+		serialized = r.ReadBytes()
 	}
+	srvErr := orient.OServerException{Exceptions: exc, Serialized: serialized}
 
 	for _, e := range exc {
 		switch e.ExcClass() {
 		case "com.orientechnologies.orient.core.storage.ORecordDuplicatedException":
-			return ODuplicatedRecordException{OServerException: orient.OServerException{Exceptions: exc}}
+			return ODuplicatedRecordException{OServerException: srvErr}
 		}
 	}
-	return orient.OServerException{Exceptions: exc}
+	return srvErr
 }
 
-func (c *Client) run() error {
+func (c *Client) run() (err error) {
 	defer close(c.done)
+	defer c.closeLive()
+	defer func() {
+		c.errmu.Lock()
+		c.runErr = err
+		c.errmu.Unlock()
+	}()
 	var (
 		status byte
 		sessId int32
@@ -261,6 +315,9 @@ func (c *Client) run() error {
 		if err := c.pr.Err(); err != nil {
 			return err
 		}
+		if status != responseStatusPush {
+			c.readToken(sessId)
+		}
 		switch status {
 		case responseStatusOk:
 			c.pushResp(sessId, c.br, nil)
@@ -268,7 +325,9 @@ func (c *Client) run() error {
 			e := readErrorResponse(c.pr, c.curProtoVers)
 			c.pushResp(sessId, nil, e)
 		case responseStatusPush:
-			return ErrBrokenProtocol{fmt.Errorf("server push is not supported yet")}
+			if err := c.readPush(); err != nil {
+				return err
+			}
 		default:
 			return ErrBrokenProtocol{fmt.Errorf("unknown resp status: %d", status)}
 		}
@@ -280,11 +339,51 @@ type resp struct {
 	err error
 }
 
+// readToken reads a token from response header of token-based session, and renews session token, if needed.
+func (c *Client) readToken(id int32) {
+	c.sessmu.Lock()
+	s := c.sess[id]
+	c.sessmu.Unlock()
+	if s == nil || !s.useToken {
+		return
+	}
+	if tok := c.pr.ReadBytes(); len(tok) != 0 {
+		s.setToken(tok)
+	}
+}
+
 type session struct {
-	mu  sync.Mutex
-	id  int32
-	in  chan resp
-	cli *Client
+	mu       sync.Mutex
+	id       int32
+	in       chan resp
+	cli      *Client
+	timeout  time.Duration
+	useToken bool // set once, before any request is sent in this session
+
+	tokmu sync.Mutex
+	token []byte
+}
+
+func (s *session) getToken() []byte {
+	s.tokmu.Lock()
+	defer s.tokmu.Unlock()
+	return s.token
+}
+
+func (s *session) setToken(tok []byte) {
+	s.tokmu.Lock()
+	s.token = tok
+	s.tokmu.Unlock()
+}
+
+// checkTimeout converts network timeouts to orient.ErrCommandTimeout. Connection is closed after timeout,
+// since a late response would corrupt the stream.
+func (s *session) checkTimeout(err error) error {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		s.cli.conn.Close()
+		return orient.ErrCommandTimeout{Timeout: s.timeout}
+	}
+	return err
 }
 
 func (s *session) catch(err *error) {
@@ -302,15 +401,31 @@ func (s *session) catch(err *error) {
 func (s *session) sendCmd(op byte, wr func(*rw.Writer) error, rd func(*rw.Reader) error) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if err := s.cli.writeCmd(op, s.id, wr); err != nil {
-		return err
+	if s.timeout > 0 {
+		s.cli.conn.SetDeadline(time.Now().Add(s.timeout))
+		defer s.cli.conn.SetDeadline(time.Time{})
+	}
+	var token []byte
+	if s.useToken {
+		token = s.getToken()
+	}
+	if err := s.cli.writeCmd(op, s.id, token, wr); err != nil {
+		return s.checkTimeout(err)
 	}
 	if op == requestDbClose {
 		return nil
 	}
 	select {
 	case <-s.cli.done:
-		return fmt.Errorf("server gone")
+		s.cli.errmu.Lock()
+		err := s.cli.runErr
+		s.cli.errmu.Unlock()
+		if err = s.checkTimeout(err); err == nil {
+			err = fmt.Errorf("server gone")
+		} else if _, ok := err.(orient.ErrCommandTimeout); !ok {
+			err = fmt.Errorf("server gone: %v", err)
+		}
+		return err
 	case resp, ok := <-s.in:
 		if !ok {
 			return ErrClosedConnection
@@ -318,12 +433,15 @@ func (s *session) sendCmd(op byte, wr func(*rw.Writer) error, rd func(*rw.Reader
 			return resp.err
 		}
 		defer resp.Close()
+		if s.timeout > 0 { // must be reset before reading loop continues
+			defer s.cli.conn.SetDeadline(time.Time{})
+		}
 		if rd != nil {
 			br := rw.NewReader(resp.ReadCloser.(io.Reader))
 			if err := rd(br); err != nil {
-				return err
+				return s.checkTimeout(err)
 			} else if err = br.Err(); err != nil {
-				return err
+				return s.checkTimeout(err)
 			}
 		}
 		return nil
@@ -336,15 +454,37 @@ func (c *Client) getCurrDB() *Database {
 	return c.currdb
 }
 
+// SessionToken returns a token of token-based session, or nil if session is bound to the connection.
+func (db *Database) SessionToken() []byte {
+	if db == nil || db.sess == nil || !db.sess.useToken {
+		return nil
+	}
+	return append([]byte(nil), db.sess.getToken()...)
+}
+
+// ProtocolVersion returns a protocol version negotiated with the server.
+func (db *Database) ProtocolVersion() int16 {
+	if db == nil || db.sess == nil {
+		return 0
+	}
+	return int16(db.sess.cli.curProtoVers)
+}
+
 // GetCurDB returns database metadata
 func (db *Database) GetCurDB() *orient.ODatabase {
 	if db == nil || db.db == nil {
 		return nil
 	}
+	db.db.classesMu.RLock()
+	defer db.db.classesMu.RUnlock()
+	classes := make(map[string]*orient.OClass, len(db.db.Classes))
+	for name, c := range db.db.Classes {
+		classes[name] = c
+	}
 	return &orient.ODatabase{
 		Name:    db.db.Name,
 		Type:    db.db.Type,
-		Classes: db.db.Classes,
+		Classes: classes,
 	}
 }
 
@@ -14,10 +14,12 @@ func (db *Database) updateCachedRecord(rec orient.ORecord) {
 	// TODO: implement records cache
 }
 
-func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error) {
+// readSynchResult reads command result. Supplementary records sent by the server (fetched links and records
+// referenced by the result, like projections of subqueries) are returned separately as related.
+func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, related []orient.ORecord, err error) {
 	resType := rune(r.ReadByte())
 	if err = r.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	switch resType {
 	case 'n': // null result
@@ -25,7 +27,7 @@ func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error
 	case 'r': // single record
 		rec, err := db.readIdentifiable(r)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if rec, ok := rec.(orient.ORecord); ok {
 			db.updateCachedRecord(rec)
@@ -37,7 +39,7 @@ func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error
 		for i := range recs {
 			rec, err := db.readIdentifiable(r)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if rec, ok := rec.(orient.ORecord); ok {
 				db.updateCachedRecord(rec)
@@ -53,7 +55,7 @@ func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error
 				break
 			}
 			if rec, err := db.readIdentifiable(r); err != nil {
-				return nil, err
+				return nil, nil, err
 			} else if rec == nil {
 				continue
 			} else if status == 1 {
@@ -67,7 +69,7 @@ func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error
 	case 'a': // serialized type
 		s := r.ReadString()
 		if err = r.Err(); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		format := orient.StringRecordFormatAbs{}
 		result = format.FieldTypeFromStream(format.GetType(s), s)
@@ -82,26 +84,35 @@ func (db *Database) readSynchResult(r *rw.Reader) (result interface{}, err error
 			}
 			rec, err := db.readIdentifiable(r)
 			if err != nil {
-				return result, err
+				return result, related, err
 			}
 			if rec != nil && status == 2 {
 				if rec, ok := rec.(orient.ORecord); ok {
 					db.updateCachedRecord(rec)
+					related = append(related, rec)
 				}
 			}
 		}
 	}
-	return result, r.Err()
+	return result, related, r.Err()
 }
 
+// Command executes a command. If the server sent related records along with the result,
+// orient.FetchedResult is returned.
 func (db *Database) Command(cmd orient.CustomSerializable) (result interface{}, err error) {
+	return db.command(cmd, false, nil)
+}
+
+// command sends a command to the server. For live commands, onLive is called with a command result
+// before connection is released.
+func (db *Database) command(cmd orient.CustomSerializable, live bool, onLive func(result interface{}) error) (result interface{}, err error) {
 	var data []byte
 	data, err = orient.SerializeAnyStreamable(cmd)
 	if err != nil {
 		return
 	}
 
-	live, async := false, false // synchronous only supported for now
+	async := false // asynchronous commands are not supported for now
 
 	// for synchronous commands the remaining content is an array of form:
 	// [(synch-result-type:byte)[(synch-result-content:?)]]+
@@ -121,12 +132,18 @@ func (db *Database) Command(cmd orient.CustomSerializable) (result interface{},
 		if async {
 			// TODO: async
 		} else {
-			result, err = db.readSynchResult(r)
+			var related []orient.ORecord
+			result, related, err = db.readSynchResult(r)
 			if err != nil {
 				return err
 			}
+			if len(related) != 0 && !live {
+				result = orient.FetchedResult{Result: result, Related: related}
+			}
 			if live {
-				// TODO: live
+				if err = onLive(result); err != nil {
+					return err
+				}
 			}
 		}
 		return r.Err()
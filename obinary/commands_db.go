@@ -1,10 +1,13 @@
 package obinary
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
+	"time"
 
 	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/binserde"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
 )
 
@@ -20,14 +23,14 @@ func (c *Client) sendClientInfo(w *rw.Writer) {
 		panic("CSV serializer is not supported")
 	}
 	if c.curProtoVers > ProtoVersion26 {
-		w.WriteBool(false) // use token (true) or session (false)
+		w.WriteBool(c.useToken) // use token (true) or session (false)
 	}
 }
 
 func (c *Client) openDBSess(dbname string, dbtype orient.DatabaseType, user, pass string) (*session, *ODatabase, error) {
 	var (
-		sessId int32
-		//token []byte
+		sessId     int32
+		token      []byte
 		clusters   []OCluster
 		clusterCfg []byte
 		//serverVers string
@@ -43,8 +46,8 @@ func (c *Client) openDBSess(dbname string, dbtype orient.DatabaseType, user, pas
 		w.WriteString(pass)
 		return w.Err()
 	}, func(r *rw.Reader) error {
-		sessId = r.ReadInt() // new session id
-		_ = r.ReadBytes()    // token - may ignore this in session mode (is nil)
+		sessId = r.ReadInt()  // new session id
+		token = r.ReadBytes() // nil in session mode
 
 		n := int(r.ReadShort())
 		clusters = make([]OCluster, n)
@@ -63,6 +66,12 @@ func (c *Client) openDBSess(dbname string, dbtype orient.DatabaseType, user, pas
 		return nil, nil, fmt.Errorf("wrong session id returned: %d", sessId)
 	}
 	sess := c.newSess(sessId)
+	if c.useToken && c.curProtoVers > ProtoVersion26 {
+		if len(token) == 0 {
+			return nil, nil, fmt.Errorf("server returned no session token")
+		}
+		sess.useToken, sess.token = true, token
+	}
 	db := NewDatabase(dbname, dbtype)
 	db.Clusters = clusters
 	db.ClustCfg = clusterCfg
@@ -94,10 +103,19 @@ func (c *Client) OpenDatabase(dbname string, dbtype orient.DatabaseType, user, p
 	c.currmu.Unlock()
 	err = db.refreshGlobalProperties()
 	c.recordFormat.SetGlobalPropertyFunc(func(id int) (orient.OGlobalProperty, bool) {
-		// TODO: implement global property lookup
-		db.refreshGlobalPropertiesIfRequired(id)
+		if p, ok := db.db.GetGlobalProperty(id); ok {
+			return p, true
+		}
+		if err := db.refreshGlobalPropertiesIfRequired(id); err != nil {
+			orient.Logf("cannot reload global properties: %v", err)
+		}
 		return db.db.GetGlobalProperty(id)
 	})
+	if ser, ok := c.recordFormat.(orient.SchemaSerializer); ok {
+		ser.SetClassPropertyFunc(func(class, name string) (*orient.OProperty, bool) {
+			return db.db.ClassProperty(class, name)
+		})
+	}
 	return db, err
 }
 
@@ -112,11 +130,13 @@ func (c *Client) Open(dbname string, dbtype orient.DatabaseType, user, pass stri
 // were recently issued).
 //
 // If the GlobalProperties data is stale, then it must be refreshed, so
-// refreshGlobalProperties is called.
+// refreshGlobalProperties is called. Concurrent callers wait for a single refresh.
 func (db *Database) refreshGlobalPropertiesIfRequired(id int) error {
 	if db == nil || db.db == nil {
 		return nil
 	}
+	db.db.refreshMu.Lock()
+	defer db.db.refreshMu.Unlock()
 	if _, ok := db.db.GetGlobalProperty(id); !ok {
 		return db.refreshGlobalProperties()
 	}
@@ -201,7 +221,7 @@ func (db *Database) loadSchema(rid orient.RID) error {
 	for _, cfield := range classesFld.Value.([]interface{}) {
 		cdoc := cfield.(*orient.Document)
 		oclass = orient.NewOClassFromDocument(cdoc)
-		odb.Classes[oclass.Name] = oclass
+		odb.SetClass(oclass)
 	}
 	return nil
 }
@@ -221,6 +241,29 @@ func (db *Database) Close() error {
 	return err
 }
 
+// Alive reports if database session is opened and connection to the server is not closed.
+func (db *Database) Alive() bool {
+	if db == nil || db.sess == nil {
+		return false
+	}
+	select {
+	case <-db.sess.cli.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// SetTimeout sets a timeout for each subsequent request in this session. Zero means no timeout.
+func (db *Database) SetTimeout(d time.Duration) {
+	if db == nil || db.sess == nil {
+		return
+	}
+	db.sess.mu.Lock()
+	db.sess.timeout = d
+	db.sess.mu.Unlock()
+}
+
 // FetchDatabaseSize retrieves the size of the current database in bytes.
 // It is a database-level operation, so OpenDatabase must have already
 // been called first in order to start a session with the database.
@@ -239,7 +282,8 @@ func (db *Database) CountRecords() (int64, error) {
 //
 // If nil is returned, delete succeeded.
 // If error is returned, delete request was either never issued, or there was
-// a problem on the server end or the record did not exist in the database.
+// a problem on the server end. If the record did not exist in the database,
+// orient.ErrRecordNotFound is returned. Negative version disables version check.
 func (db *Database) DeleteRecordByRID(rid orient.RID, recVersion int) error {
 	var status byte
 	err := db.sess.sendCmd(requestRecordDELETE, func(w *rw.Writer) error {
@@ -259,7 +303,7 @@ func (db *Database) DeleteRecordByRID(rid orient.RID, recVersion int) error {
 	// status 1 means record was deleted;
 	// status 0 means record was not deleted (either failed or didn't exist)
 	if status == byte(0) {
-		return fmt.Errorf("Record %s was not deleted. Either failed or did not exist.", rid)
+		return orient.ErrRecordNotFound{RID: rid}
 	}
 	return nil
 }
@@ -268,6 +312,13 @@ func (db *Database) DeleteRecordByRID(rid orient.RID, recVersion int) error {
 //
 // ignoreCache = true
 func (db *Database) GetRecordByRID(rid orient.RID, fetchPlan orient.FetchPlan, ignoreCache bool) (rec orient.ORecord, err error) {
+	rec, _, err = db.LoadRecord(rid, fetchPlan, ignoreCache)
+	return
+}
+
+// LoadRecord reads a record from the database, same as GetRecordByRID, and also returns
+// related records that were pre-fetched by the server according to the fetch plan.
+func (db *Database) LoadRecord(rid orient.RID, fetchPlan orient.FetchPlan, ignoreCache bool) (rec orient.ORecord, related []orient.ORecord, err error) {
 	err = db.sess.sendCmd(requestRecordLOAD, func(w *rw.Writer) error {
 		if err := rid.ToStream(w); err != nil {
 			return err
@@ -320,11 +371,12 @@ func (db *Database) GetRecordByRID(rid orient.RID, fetchPlan orient.FetchPlan, i
 			}
 			if rec, ok := rec.(orient.ORecord); ok {
 				db.updateCachedRecord(rec)
+				related = append(related, rec)
 			}
 		}
 		return r.Err()
 	})
-	return rec, err
+	return rec, related, err
 }
 
 // ReloadSchema should be called after a schema is altered, such as properties
@@ -366,7 +418,7 @@ func (db *Database) AddClusterWithID(name string, id int16) (clusterID int16, er
 		return r.Err()
 	})
 	if err == nil {
-		db.db.Clusters = append(db.db.Clusters, OCluster{name, clusterID})
+		db.db.addCluster(OCluster{name, clusterID})
 	}
 	return clusterID, err
 }
@@ -393,96 +445,85 @@ func (db *Database) DropCluster(clusterName string) error {
 	return err
 }
 
-/*
-// FetchEntriesOfRemoteLinkBag fills in the links of an OLinkBag that is remote
-// (tree-based) rather than embedded.  This function will fill in the links
-// of the passed in OLinkBag, rather than returning the new links. The Links
-// will have RIDs only, not full Records (Documents).  If you then want the
-// Records filled in, call the ResolveLinks function.
-func (db *Database) GetEntriesOfRemoteLinkBag(linkBag *orient.OLinkBag, inclusive bool) (err error) {
-	defer catch(&err)
-	var (
-		firstLink orient.OIdentifiable
-		linkSerde = binserde.OLinkSerializer{}
-	)
-	firstLink, err = db.GetFirstKeyOfRemoteLinkBag(linkBag)
-	if err != nil {
-		return err
-	}
-	linkBytes, err := linkSerde.Serialize(firstLink)
-	if err != nil {
-		return err
-	}
+func writeRidBagPointer(w *rw.Writer, ptr orient.RidBagPointer) {
+	// collectionPtr = (fileId:long)(pageIndex:long)(pageOffset:int)
+	w.WriteLong(ptr.FileID)
+	w.WriteLong(ptr.PageIndex)
+	w.WriteInt(ptr.PageOffset)
+}
 
-	var linkEntryBytes []byte
-	err = db.sess.sendCmd(requestSBTREE_BONSAI_GET_ENTRIES_MAJOR, func(w io.Writer) {
-		writeLinkBagCollectionPointer(w, linkBag)
-		rw.WriteBytes(w, linkBytes)
-		rw.WriteBool(w, inclusive)
-		rw.WriteInt(w, 128) // if protoVers >= 21 from Java client OSBTreeBonsaiRemote#fetchEntriesMajor
-	}, func(r io.Reader) {
-		linkEntryBytes = rw.ReadBytes(r)
+// RidBagFirstKey returns the first link of a tree-based RidBag, or false if the tree is empty.
+func (db *Database) RidBagFirstKey(ptr orient.RidBagPointer) (rid orient.RID, ok bool, err error) {
+	var data []byte
+	err = db.sess.sendCmd(requestSBTREE_BONSAI_FIRST_KEY, func(w *rw.Writer) error {
+		writeRidBagPointer(w, ptr)
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		data = r.ReadBytes()
+		return r.Err()
 	})
-	if err != nil {
+	if err != nil || len(data) == 0 {
 		return
 	}
-	r := bytes.NewReader(linkEntryBytes)
-	n := int(rw.ReadInt(r))
-	var lnk orient.OIdentifiable
-	for i := 0; i < n; i++ { // loop over all the serialized links
-		lnk, err = linkSerde.DeserializeLink(r)
-		if err != nil {
-			return err
-		}
-		linkBag.AddLink(lnk)
-
-		// FIXME: for some reason the server returns a serialized link
-		//        followed by an integer (so far always a 1 in my expts).
-		//        Not sure what to do with this int, so ignore for now
-		intval := rw.ReadInt(r)
-
-		if intval != int32(1) {
-			glog.Warningf("Found a use case where the val pair of a link was not 1: %d", intval)
-		}
-	}
-
-	return nil
-}
-
-// FetchFirstKeyOfRemoteLinkBag is the entry point for retrieving links from
-// a remote server-side side LinkBag.  In general, this method should not be
-// called by end users. Instead, end users should call FetchEntriesOfRemoteLinkBag
-//
-// TODO: make this an unexported func?
-func (db *Database) GetFirstKeyOfRemoteLinkBag(linkBag *orient.OLinkBag) (lnk orient.OIdentifiable, err error) {
-	defer catch(&err)
-
-	var firstKeyBytes []byte
-	err = db.sess.sendCmd(requestSBTREE_BONSAI_FIRST_KEY, func(w io.Writer) {
-		writeLinkBagCollectionPointer(w, linkBag)
-	}, func(r io.Reader) {
-		firstKeyBytes = rw.ReadBytes(r)
-	})
-	if err != nil {
+	// (keySerializerId:byte)(key)
+	if data[0] != binserde.LinkSerializer {
+		err = fmt.Errorf("unexpected RidBag key serializer: %d", data[0])
 		return
 	}
-	r := bytes.NewReader(firstKeyBytes)
-	typeByte := rw.ReadByte(r)
-	if typeByte != binserde.LinkSerializer {
-		err = fmt.Errorf("GetFirstKeyOfRemoteLinkBag: unknown entry type: %d", typeByte)
+	if err = rid.FromStream(bytes.NewReader(data[1:])); err != nil {
 		return
 	}
-	return binserde.OLinkSerializer{}.DeserializeLink(r)
+	return rid, true, nil
 }
 
-func writeLinkBagCollectionPointer(w io.Writer, linkBag *orient.OLinkBag) {
-	// (treePointer:collectionPointer)(changes)
-	// where collectionPtr = (fileId:long)(pageIndex:long)(pageOffset:int)
-	rw.WriteLong(w, linkBag.GetFileID())
-	rw.WriteLong(w, linkBag.GetPageIndex())
-	rw.WriteInt(w, linkBag.GetPageOffset())
+// RidBagEntriesMajor returns up to pageSize entries of a tree-based RidBag with links greater than key
+// (or equal, if inclusive is set).
+func (db *Database) RidBagEntriesMajor(ptr orient.RidBagPointer, key orient.RID, inclusive bool, pageSize int) ([]orient.RidBagEntry, error) {
+	var data []byte
+	err := db.sess.sendCmd(requestSBTREE_BONSAI_GET_ENTRIES_MAJOR, func(w *rw.Writer) error {
+		writeRidBagPointer(w, ptr)
+		buf := bytes.NewBuffer(nil)
+		if err := key.ToStream(buf); err != nil {
+			return err
+		}
+		w.WriteBytes(buf.Bytes())
+		w.WriteBool(inclusive)
+		if db.sess.cli.curProtoVers >= ProtoVersion21 {
+			w.WriteInt(int32(pageSize))
+		}
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		data = r.ReadBytes()
+		return r.Err()
+	})
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	// (keySerializerId:byte)(valueSerializerId:byte)(size:int)[(key)(value:int)]*
+	r := rw.NewReader(bytes.NewReader(data))
+	kser, vser := r.ReadByte(), r.ReadByte()
+	if err = r.Err(); err != nil {
+		return nil, err
+	} else if kser != binserde.LinkSerializer || vser != binserde.IntegerSerializer {
+		return nil, fmt.Errorf("unexpected RidBag serializers: %d, %d", kser, vser)
+	}
+	n := int(r.ReadInt())
+	if err = r.Err(); err != nil {
+		return nil, err
+	} else if n < 0 || n > len(data)/(rw.SizeShort+rw.SizeLong+rw.SizeInt) {
+		return nil, fmt.Errorf("invalid number of RidBag entries: %d", n)
+	}
+	out := make([]orient.RidBagEntry, n)
+	for i := range out {
+		if err = out[i].RID.FromStream(r); err != nil {
+			return nil, err
+		}
+		out[i].Count = int(r.ReadInt())
+	}
+	return out, r.Err()
 }
 
+/*
 // Large LinkBags (aka RidBags) are stored on the server. To look up their
 // size requires a call to the database.  The size is returned.  Note that the
 // Size field of the linkBag is NOT updated.  That is left for the caller to
@@ -516,7 +557,7 @@ func (db *Database) ResolveLinks(links []orient.OIdentifiable) error {
 				if err != nil {
 					return err
 				} else if len(docs) != 1 {
-					glog.Warningf("More than one record returned from GetRecordByRID. Please report this use case!")
+					orient.Logf("More than one record returned from GetRecordByRID. Please report this use case!")
 				}
 				links[i].Record = docs[0]*/
 		}
@@ -534,6 +575,10 @@ func (db *Database) ClustersCount(withDeleted bool, clusterNames ...string) (val
 		}
 		clusterIDs[i] = clusterID
 	}
+	return db.countClusters(withDeleted, clusterIDs...)
+}
+
+func (db *Database) countClusters(withDeleted bool, clusterIDs ...int16) (val int64, err error) {
 	err = db.sess.sendCmd(requestDataClusterCOUNT, func(w *rw.Writer) error {
 		w.WriteShort(int16(len(clusterIDs)))
 		for _, id := range clusterIDs {
@@ -548,6 +593,34 @@ func (db *Database) ClustersCount(withDeleted bool, clusterNames ...string) (val
 	return
 }
 
+// Clusters reloads the list of database clusters and returns it with records count for each cluster.
+func (db *Database) Clusters() ([]orient.ClusterInfo, error) {
+	var clusters []OCluster
+	err := db.sess.sendCmd(requestDbRELOAD, nil, func(r *rw.Reader) error {
+		n := int(r.ReadShort())
+		clusters = make([]OCluster, n)
+		for i := range clusters {
+			name := r.ReadString()
+			id := r.ReadShort()
+			clusters[i] = OCluster{Name: name, Id: id}
+		}
+		return r.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	db.db.setClusters(clusters)
+	out := make([]orient.ClusterInfo, len(clusters))
+	for i, c := range clusters {
+		n, err := db.countClusters(false, c.Id)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = orient.ClusterInfo{Name: c.Name, ID: c.Id, Records: n}
+	}
+	return out, nil
+}
+
 func (db *Database) getLongFromDB(cmd byte) (val int64, err error) {
 	val = -1
 	err = db.sess.sendCmd(cmd, nil, func(r *rw.Reader) error {
@@ -560,13 +633,8 @@ func (db *Database) getLongFromDB(cmd byte) (val int64, err error) {
 // Returns negative number if no cluster with `name` is found in the clusters slice.
 func (db *Database) findClusterWithName(name string) (int16, error) {
 	name = strings.ToLower(name)
-	var id int16 = -1
-	for _, cluster := range db.db.Clusters {
-		if cluster.Name == name {
-			return cluster.Id, nil
-		}
-	}
-	if id < 0 {
+	id, ok := db.db.clusterID(name)
+	if !ok {
 		// TODO: This is problematic - someone else may add the cluster not through this
 		//       driver session and then this would fail - so options:
 		//       1) do a lookup of all clusters on the DB
@@ -586,10 +654,14 @@ func (db *Database) CreateRecord(rec orient.ORecord) error {
 		rid := rec.GetIdentity()
 		if rid.ClusterID > 0 {
 			clusterID = rid.ClusterID
-		} else if oclass, ok := db.db.Classes[r.ClassName()]; ok {
+		} else if oclass, ok := db.db.Class(r.ClassName()); ok {
 			clusterID = int16(oclass.DefaultClusterId) // TODO: need way to allow user to specify a non-default cluster
 		}
 		r.SetSerializer(db.serializer())
+	case *orient.BytesRecord:
+		if rid := r.GetIdentity(); rid.ClusterID > 0 {
+			clusterID = rid.ClusterID
+		}
 	}
 
 	content, err := rec.Content()
@@ -664,3 +736,114 @@ func (db *Database) UpdateRecord(rec orient.ORecord) error {
 	}
 	return rec.Fill(rec.GetIdentity(), vers, content)
 }
+
+// Commit sends all record operations of optimistic transaction to the server in one REQUEST_TX_COMMIT call.
+// Created records must have a temporary RIDs assigned. After the call, records will be filled with
+// persistent RIDs and new versions returned by the server.
+func (db *Database) Commit(txID int, entries []orient.TxEntry) error {
+	contents := make([][]byte, len(entries))
+	for i, e := range entries {
+		if e.Op == orient.TxDelete {
+			continue
+		}
+		if doc, ok := e.Record.(*orient.Document); ok {
+			doc.SetSerializer(db.serializer())
+		}
+		content, err := e.Record.Content()
+		if err != nil {
+			return err
+		}
+		contents[i] = content
+	}
+	// index changes are tracked on the server side, so empty document is sent
+	idxChanges := orient.NewEmptyDocument()
+	idxChanges.SetSerializer(db.serializer())
+	idxContent, err := idxChanges.Content()
+	if err != nil {
+		return err
+	}
+	var (
+		created  = make(map[orient.RID]orient.RID)
+		versions = make(map[orient.RID]int)
+	)
+	err = db.sess.sendCmd(requestTxCommit, func(w *rw.Writer) error {
+		w.WriteInt(int32(txID))
+		w.WriteBool(true) // using tx log
+		for i, e := range entries {
+			w.WriteByte(1) // record entry marker
+			w.WriteByte(byte(e.Op))
+			if err := e.Record.GetIdentity().ToStream(w); err != nil {
+				return err
+			}
+			w.WriteByte(byte(e.Record.RecordType()))
+			switch e.Op {
+			case orient.TxCreate:
+				w.WriteBytes(contents[i])
+			case orient.TxUpdate:
+				w.WriteInt(int32(e.Record.Version()))
+				w.WriteBytes(contents[i])
+				if db.sess.cli.curProtoVers >= ProtoVersion23 {
+					w.WriteBool(true) // update-content flag
+				}
+			case orient.TxDelete:
+				w.WriteInt(int32(e.Record.Version()))
+			default:
+				return fmt.Errorf("unknown tx operation: %d", e.Op)
+			}
+		}
+		w.WriteByte(0) // end of record entries
+		w.WriteBytes(idxContent)
+		return w.Err()
+	}, func(r *rw.Reader) error {
+		n := int(r.ReadInt())
+		for i := 0; i < n; i++ {
+			var from, to orient.RID
+			if err := from.FromStream(r); err != nil {
+				return err
+			}
+			if err := to.FromStream(r); err != nil {
+				return err
+			}
+			created[from] = to
+		}
+		n = int(r.ReadInt())
+		for i := 0; i < n; i++ {
+			var rid orient.RID
+			if err := rid.FromStream(r); err != nil {
+				return err
+			}
+			versions[rid] = int(r.ReadInt())
+		}
+		n = int(r.ReadInt())
+		for i := 0; i < n; i++ {
+			// collection changes are not supported for now:
+			// (uuid-most-sig-bits:long)(uuid-least-sig-bits:long)(updated-file-id:long)(updated-page-index:long)(updated-page-offset:int)
+			r.ReadLong()
+			r.ReadLong()
+			r.ReadLong()
+			r.ReadLong()
+			r.ReadInt()
+		}
+		return r.Err()
+	})
+	if err != nil {
+		return err
+	}
+	for i, e := range entries {
+		if e.Op == orient.TxDelete {
+			continue
+		}
+		rid := e.Record.GetIdentity()
+		if nrid, ok := created[rid]; ok {
+			rid = nrid
+		}
+		vers := e.Record.Version()
+		if v, ok := versions[rid]; ok {
+			vers = v
+		}
+		if err = e.Record.Fill(rid, vers, contents[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
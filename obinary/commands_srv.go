@@ -120,6 +120,11 @@ func (m *Manager) ListDatabases() (list map[string]string, err error) {
 	return
 }
 
+// ProtocolVersion returns a version of binary protocol used by the server.
+func (m *Manager) ProtocolVersion() (int, error) {
+	return m.sess.cli.srvProtoVers, nil
+}
+
 func (m *Manager) Close() error {
 	// TODO: what can we do?
 	return m.sess.cli.Close()
@@ -62,7 +62,9 @@ const (
 	requestConfigLIST                    = 72
 	requestDbRELOAD                      = 73 // SINCE 1.0rc4
 	requestDbLIST                        = 74 // SINCE 1.0rc6
+	requestPushRecord                    = 79
 	requestPushDistribConfig             = 80
+	requestPushLiveQuery                 = 81 // since 2.1
 	// DISTRIBUTED
 	requestDbCOPY      = 90 // SINCE 1.0rc8
 	requestREPLICATION = 91 // SINCE 1.0
@@ -11,13 +11,16 @@ type ODatabase struct {
 	Name             string
 	Type             orient.DatabaseType
 	Clusters         []OCluster
+	clustersMu       sync.RWMutex
 	ClustCfg         []byte // TODO: why is this a byte array? Just placeholder? What is it in the Java client?
 	SchemaVersion    int
 	Classes          map[string]*orient.OClass
+	classesMu        sync.RWMutex
 	storageMu        sync.RWMutex
 	StorageCfg       OStorageConfiguration // TODO: redundant to ClustCfg ??
 	globalPropMu     sync.RWMutex
 	globalProperties map[int]orient.OGlobalProperty
+	refreshMu        sync.Mutex // serializes schema reloads caused by unknown global properties
 }
 
 func (db *ODatabase) SetGlobalProperty(id int, p orient.OGlobalProperty) {
@@ -44,6 +47,71 @@ func (db *ODatabase) GetGlobalProperty(id int) (p orient.OGlobalProperty, ok boo
 	return
 }
 
+// setClusters replaces the list of known clusters.
+func (db *ODatabase) setClusters(clusters []OCluster) {
+	db.clustersMu.Lock()
+	db.Clusters = clusters
+	db.clustersMu.Unlock()
+}
+
+// addCluster adds a cluster to the list of known clusters.
+func (db *ODatabase) addCluster(c OCluster) {
+	db.clustersMu.Lock()
+	db.Clusters = append(db.Clusters, c)
+	db.clustersMu.Unlock()
+}
+
+// clusterID returns an ID of a known cluster with a given (lower-cased) name.
+func (db *ODatabase) clusterID(name string) (int16, bool) {
+	db.clustersMu.RLock()
+	defer db.clustersMu.RUnlock()
+	for _, c := range db.Clusters {
+		if c.Name == name {
+			return c.Id, true
+		}
+	}
+	return -1, false
+}
+
+// SetClass adds or replaces a class definition.
+func (db *ODatabase) SetClass(c *orient.OClass) {
+	db.classesMu.Lock()
+	db.Classes[c.Name] = c
+	db.classesMu.Unlock()
+}
+
+// Class returns a class definition by name.
+func (db *ODatabase) Class(name string) (*orient.OClass, bool) {
+	if db == nil {
+		return nil, false
+	}
+	db.classesMu.RLock()
+	c, ok := db.Classes[name]
+	db.classesMu.RUnlock()
+	return c, ok
+}
+
+// ClassProperty returns a property of a class, or of one of it's super classes.
+func (db *ODatabase) ClassProperty(class, name string) (*orient.OProperty, bool) {
+	if db == nil {
+		return nil, false
+	}
+	db.classesMu.RLock()
+	defer db.classesMu.RUnlock()
+	for i := 0; i <= len(db.Classes); i++ { // limit depth in case of broken hierarchy
+		c, ok := db.Classes[class]
+		if !ok {
+			return nil, false
+		} else if p, ok := c.Properties[name]; ok {
+			return p, true
+		} else if c.SuperClass == "" {
+			return nil, false
+		}
+		class = c.SuperClass
+	}
+	return nil, false
+}
+
 func NewDatabase(name string, dbtype orient.DatabaseType) *ODatabase {
 	return &ODatabase{
 		Name:          name,
@@ -1,9 +1,59 @@
 package obinary
 
 import (
+	"bytes"
+	"time"
+
+	"gopkg.in/istreamdata/orientgo.v2"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
 )
 
 func ReadErrorResponse(r *rw.Reader) (serverException error) {
 	return readErrorResponse(r, CurrentProtoVersion)
 }
+
+// ReadLivePushes reads server push messages from data and returns notifications for a given live query token.
+func ReadLivePushes(data []byte, token int32) (<-chan orient.LiveResult, func(), error) {
+	c := &Client{recordFormat: orient.GetDefaultRecordSerializer()}
+	c.pr = rw.NewReader(bytes.NewReader(data))
+	ch := c.subscribe(token)
+	for c.pr.Err() == nil {
+		if err := c.readPush(); err != nil {
+			if c.pr.Err() != nil { // end of data
+				break
+			}
+			return nil, nil, err
+		}
+	}
+	return ch, c.closeLive, nil
+}
+
+// SendWithTimeout sends an empty command in server session with a given timeout.
+func SendWithTimeout(c *Client, d time.Duration) error {
+	c.root.timeout = d
+	return c.root.sendCmd(requestDbLIST, nil, nil)
+}
+
+// IsClosed checks if client reading loop is stopped.
+func IsClosed(c *Client) bool {
+	select {
+	case <-c.done:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+// RecordFormat returns record serializer used by client.
+func RecordFormat(c *Client) orient.RecordSerializer {
+	return c.recordFormat
+}
+
+// OpenSession opens a database session without loading the schema.
+func OpenSession(c *Client, dbname, user, pass string) (*Database, error) {
+	sess, odb, err := c.openDBSess(dbname, orient.DocumentDB, user, pass)
+	if err != nil {
+		return nil, err
+	}
+	return &Database{sess: sess, db: odb}, nil
+}
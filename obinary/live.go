@@ -0,0 +1,196 @@
+package obinary
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+)
+
+// liveSub buffers live query notifications, so slow consumers will not block connection reader.
+type liveSub struct {
+	out  chan orient.LiveResult
+	wake chan struct{}
+	done chan struct{}
+
+	mu    sync.Mutex
+	queue []orient.LiveResult
+}
+
+func newLiveSub() *liveSub {
+	s := &liveSub{
+		out:  make(chan orient.LiveResult),
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *liveSub) push(r orient.LiveResult) {
+	s.mu.Lock()
+	s.queue = append(s.queue, r)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *liveSub) loop() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, r := range queue {
+			select {
+			case s.out <- r:
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *liveSub) close() { close(s.done) }
+
+func (c *Client) subscribe(token int32) <-chan orient.LiveResult {
+	s := newLiveSub()
+	c.livemu.Lock()
+	if c.live == nil {
+		c.live = make(map[int32]*liveSub)
+	}
+	if old := c.live[token]; old != nil {
+		old.close()
+	}
+	c.live[token] = s
+	c.livemu.Unlock()
+	return s.out
+}
+
+func (c *Client) unsubscribe(token int32) {
+	c.livemu.Lock()
+	s := c.live[token]
+	delete(c.live, token)
+	c.livemu.Unlock()
+	if s != nil {
+		s.close()
+	}
+}
+
+func (c *Client) closeLive() {
+	c.livemu.Lock()
+	live := c.live
+	c.live = nil
+	c.livemu.Unlock()
+	for _, s := range live {
+		s.close()
+	}
+}
+
+// readPush reads a message pushed by server. Only live query notifications are handled, others are ignored.
+func (c *Client) readPush() error {
+	tp := c.pr.ReadByte()
+	data := c.pr.ReadBytes()
+	if err := c.pr.Err(); err != nil {
+		return err
+	}
+	if tp != requestPushLiveQuery {
+		return nil
+	}
+	r := rw.NewReader(bytes.NewReader(data))
+	switch r.ReadByte() {
+	case 'r': // record change
+		op := orient.LiveOperation(r.ReadByte())
+		token := r.ReadInt()
+		tp := orient.RecordType(r.ReadByte())
+		version := int(r.ReadInt())
+		var rid orient.RID
+		if err := rid.FromStream(r); err != nil {
+			return ErrBrokenProtocol{err}
+		}
+		content := r.ReadBytes()
+		if err := r.Err(); err != nil {
+			return ErrBrokenProtocol{err}
+		}
+		doc, ok := orient.NewRecordOfType(tp).(*orient.Document)
+		if !ok { // only documents can be reported to subscribers
+			return nil
+		}
+		doc.SetSerializer(c.recordFormat)
+		res := orient.LiveResult{Op: op, Doc: doc}
+		if err := doc.Fill(rid, version, content); err != nil {
+			res = orient.LiveResult{Op: op, Err: fmt.Errorf("cannot read record %v: %v", rid, err)}
+		}
+		c.livemu.Lock()
+		s := c.live[token]
+		c.livemu.Unlock()
+		if s != nil {
+			s.push(res)
+		}
+	case 'u': // unsubscribed by server
+		token := r.ReadInt()
+		if r.Err() == nil {
+			c.unsubscribe(token)
+		}
+	}
+	return nil
+}
+
+// liveToken extracts subscription token from LIVE SELECT result.
+func liveToken(result interface{}) (int32, error) {
+	if recs, ok := result.([]orient.OIdentifiable); ok && len(recs) == 1 {
+		result = recs[0]
+	}
+	doc, ok := result.(*orient.Document)
+	if !ok {
+		return 0, fmt.Errorf("unexpected live query result: %T", result)
+	}
+	fld := doc.GetField("token")
+	if fld == nil {
+		return 0, fmt.Errorf("no token returned for live query")
+	}
+	switch v := fld.Value.(type) {
+	case int32:
+		return v, nil
+	case int64:
+		return int32(v), nil
+	case int:
+		return int32(v), nil
+	}
+	return 0, fmt.Errorf("unexpected live query token: %T", fld.Value)
+}
+
+// LiveQuery executes LIVE SELECT command and subscribes to record changes. Requires OrientDB 2.1+.
+func (db *Database) LiveQuery(cmd orient.CustomSerializable) (token int, ch <-chan orient.LiveResult, err error) {
+	if db.sess.cli.srvProtoVers < ProtoVersion32 {
+		return 0, nil, fmt.Errorf("live queries are not supported by server protocol %d", db.sess.cli.srvProtoVers)
+	}
+	_, err = db.command(cmd, true, func(result interface{}) error {
+		tok, err := liveToken(result)
+		if err != nil {
+			return err
+		}
+		// subscribe before response is released, so no notifications will be lost
+		token, ch = int(tok), db.sess.cli.subscribe(tok)
+		return nil
+	})
+	return token, ch, err
+}
+
+// Unsubscribe stops live query and closes it's results channel.
+func (db *Database) Unsubscribe(token int) error {
+	_, err := db.Command(orient.NewSQLCommand("LIVE UNSUBSCRIBE " + strconv.Itoa(token)))
+	db.sess.cli.unsubscribe(int32(token))
+	return err
+}
@@ -2,14 +2,25 @@ package obinary_test
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"gopkg.in/istreamdata/orientgo.v2"
 	"gopkg.in/istreamdata/orientgo.v2/obinary"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"testing"
+	"time"
 )
 
 // equals fails the test if exp is not equal to act.
@@ -67,3 +78,286 @@ func TestReadErrorResponseWithMultipleExceptions(t *testing.T) {
 	equals(t, "org.foo.WobbleException", e.Exceptions[2].ExcClass())
 	equals(t, "Orbital decay", e.Exceptions[2].ExcMessage())
 }
+
+func TestReadErrorResponseServerError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	bw := rw.NewWriter(buf)
+	bw.WriteByte(byte(1))
+	bw.WriteStrings("com.orientechnologies.orient.core.exception.OCommandExecutionException", "Error on execution")
+	bw.WriteByte(byte(1))
+	bw.WriteStrings("com.orientechnologies.orient.core.exception.OValidationException", "Field is mandatory")
+	bw.WriteByte(byte(0))
+	bw.WriteBytes([]byte("stack"))
+
+	e, ok := obinary.ReadErrorResponse(rw.NewReader(buf)).(orient.ServerError)
+	if !ok {
+		t.Fatal("wrong exception type")
+	}
+	equals(t, []string{
+		"com.orientechnologies.orient.core.exception.OCommandExecutionException",
+		"com.orientechnologies.orient.core.exception.OValidationException",
+	}, e.ExceptionClasses())
+	equals(t, []string{"Error on execution", "Field is mandatory"}, e.Messages())
+	equals(t, true, e.IsClass("OValidationException"))
+	equals(t, true, e.IsClass("com.orientechnologies.orient.core.exception.OCommandExecutionException"))
+	equals(t, false, e.IsClass("ValidationException"))
+	equals(t, []byte("stack"), e.(orient.OServerException).Serialized)
+}
+
+func writeLivePush(t *testing.T, bw *rw.Writer, op orient.LiveOperation, token int32, doc *orient.Document) {
+	content := new(bytes.Buffer)
+	if err := orient.GetDefaultRecordSerializer().ToStream(content, doc); err != nil {
+		t.Fatal(err)
+	}
+	msg := new(bytes.Buffer)
+	mw := rw.NewWriter(msg)
+	mw.WriteByte('r')
+	mw.WriteByte(byte(op))
+	mw.WriteInt(token)
+	mw.WriteByte(byte(orient.RecordTypeDocument))
+	mw.WriteInt(3) // version
+	doc.RID.ToStream(mw)
+	mw.WriteBytes(content.Bytes())
+
+	bw.WriteByte(81) // REQUEST_PUSH_LIVE_QUERY
+	bw.WriteBytes(msg.Bytes())
+}
+
+func TestReadLivePush(t *testing.T) {
+	buf := new(bytes.Buffer)
+	bw := rw.NewWriter(buf)
+	doc := orient.NewDocument("V")
+	doc.RID = orient.NewRID(9, 1)
+	doc.SetField("name", "one")
+	writeLivePush(t, bw, orient.LiveUpdate, 7, doc)
+	writeLivePush(t, bw, orient.LiveCreate, 8, doc) // other subscription
+
+	ch, closer, err := obinary.ReadLivePushes(buf.Bytes(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := <-ch
+	equals(t, orient.LiveUpdate, r.Op)
+	equals(t, doc.RID, r.Doc.RID)
+	equals(t, 3, r.Doc.Version())
+	equals(t, "one", r.Doc.GetField("name").Value)
+	closer()
+	if _, ok := <-ch; ok {
+		t.Fatal("unexpected notification")
+	}
+}
+
+func TestReadLivePushUnsubscribe(t *testing.T) {
+	buf := new(bytes.Buffer)
+	bw := rw.NewWriter(buf)
+	bw.WriteByte(81)
+	bw.WriteBytes([]byte{'u', 0, 0, 0, 7})
+
+	ch, closer, err := obinary.ReadLivePushes(buf.Bytes(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed after unsubscribe")
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.NewWriter(conn).WriteShort(obinary.CurrentProtoVersion)
+		io.Copy(ioutil.Discard, conn) // never respond
+	}()
+	cli, err := obinary.Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	err = obinary.SendWithTimeout(cli, 50*time.Millisecond)
+	if e, ok := err.(orient.ErrCommandTimeout); !ok {
+		t.Fatalf("expected timeout error, got: %T(%v)", err, err)
+	} else if e.Timeout != 50*time.Millisecond {
+		t.Fatalf("wrong timeout: %v", e.Timeout)
+	}
+	if !obinary.IsClosed(cli) {
+		t.Fatal("connection was not closed after timeout")
+	}
+}
+
+func selfSignedTLS(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestDialTLS(t *testing.T) {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{selfSignedTLS(t)}})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rw.NewWriter(conn).WriteShort(obinary.CurrentProtoVersion)
+				io.Copy(ioutil.Discard, conn)
+			}()
+		}
+	}()
+	if _, err = obinary.Dial(l.Addr().String(), orient.WithTLS(nil)); err == nil {
+		t.Fatal("self-signed certificate should not be accepted by default")
+	}
+	cli, err := obinary.Dial(l.Addr().String(), orient.WithTLS(&tls.Config{InsecureSkipVerify: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Close()
+}
+
+type testRecordFormat struct {
+	orient.BinaryRecordFormat
+}
+
+func (testRecordFormat) String() string { return "TestRecordFormat" }
+
+func TestDialRecordFormat(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rw.NewWriter(conn).WriteShort(obinary.CurrentProtoVersion)
+				io.Copy(ioutil.Discard, conn)
+			}()
+		}
+	}()
+	orient.RegisterRecordFormat("TestRecordFormat", func() orient.RecordSerializer { return &testRecordFormat{} })
+	if _, err = obinary.Dial(l.Addr().String(), orient.WithRecordFormat("Unknown")); err == nil {
+		t.Fatal("expected an error for unknown record format")
+	}
+	cli, err := obinary.Dial(l.Addr().String(), orient.WithRecordFormat("TestRecordFormat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	def, err := obinary.Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer def.Close()
+	if name := obinary.RecordFormat(cli).String(); name != "TestRecordFormat" {
+		t.Fatalf("wrong record format: %s", name)
+	} else if name = obinary.RecordFormat(def).String(); name == "TestRecordFormat" {
+		t.Fatal("record format of a connection changed the default")
+	}
+}
+
+func TestTokenSession(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	const sessID = 5
+	tokens := make(chan string, 2)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r, w := rw.NewReader(conn), rw.NewWriter(conn)
+		w.WriteShort(obinary.CurrentProtoVersion)
+		// REQUEST_DB_OPEN
+		r.ReadByte()
+		r.ReadInt()
+		r.ReadString() // driver name
+		r.ReadString() // driver version
+		r.ReadShort()
+		r.ReadBytes() // client id
+		r.ReadString()
+		if !r.ReadBool() {
+			tokens <- "token was not requested"
+			return
+		}
+		for i := 0; i < 4; i++ { // db name, type, user and password
+			r.ReadString()
+		}
+		w.WriteByte(0)
+		w.WriteInt(-1)
+		w.WriteInt(sessID)
+		w.WriteBytes([]byte("tok1"))
+		w.WriteShort(0)   // clusters
+		w.WriteNull()     // cluster config
+		w.WriteString("") // release
+		// REQUEST_DB_SIZE, the second response renews the token
+		for _, renew := range []string{"tok2", ""} {
+			r.ReadByte()
+			r.ReadInt()
+			tokens <- string(r.ReadBytes())
+			w.WriteByte(0)
+			w.WriteInt(sessID)
+			w.WriteBytes([]byte(renew))
+			w.WriteLong(42)
+		}
+	}()
+	cli, err := obinary.Dial(l.Addr().String(), orient.WithTokenSession())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+	db, err := obinary.OpenSession(cli, "db", "admin", "admin")
+	if err != nil {
+		t.Fatal(err)
+	} else if tok := string(db.SessionToken()); tok != "tok1" {
+		t.Fatalf("wrong session token: %q", tok)
+	}
+	for _, expect := range []string{"tok1", "tok2"} {
+		if n, err := db.Size(); err != nil {
+			t.Fatal(err)
+		} else if n != 42 {
+			t.Fatalf("wrong size: %d", n)
+		} else if tok := <-tokens; tok != expect {
+			t.Fatalf("wrong token sent: %q, expected %q", tok, expect)
+		}
+	}
+	if tok := string(db.SessionToken()); tok != "tok2" {
+		t.Fatalf("token was not renewed: %q", tok)
+	}
+}
@@ -11,10 +11,26 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"unsafe"
 )
 
 var Order = binary.BigEndian
 
+// MaxBytesLength is a default limit for lengths of byte arrays and strings read by Reader.
+// It matches the default of network.binary.maxLength setting of OrientDB server (16 MB).
+var MaxBytesLength = 16 << 20
+
+// ErrTooLarge is returned when a length prefix of byte array or string exceeds the limit of Reader.
+// It usually means that the stream is corrupted.
+type ErrTooLarge struct {
+	Size  int64
+	Limit int
+}
+
+func (e ErrTooLarge) Error() string {
+	return fmt.Sprintf("byte array is too large: %d bytes, limit is %d", e.Size, e.Limit)
+}
+
 func NewReader(r io.Reader) *Reader {
 	switch br := r.(type) {
 	case *Reader:
@@ -28,15 +44,74 @@ func NewReader(r io.Reader) *Reader {
 }
 
 type Reader struct {
-	err error
-	br  byteReader
-	R   io.Reader
+	err      error
+	br       byteReader
+	R        io.Reader
+	limit    int
+	zeroCopy bool
 }
 
 func (r *Reader) Err() error {
 	return r.err
 }
 
+// SetLimit sets a maximal length of byte arrays and strings. Zero means MaxBytesLength, negative value
+// disables the limit.
+func (r *Reader) SetLimit(n int) {
+	r.limit = n
+}
+
+// checkSize checks a length of byte array against the limit, and sets reader error if it's exceeded.
+func (r *Reader) checkSize(sz int64) bool {
+	limit := r.limit
+	if limit == 0 {
+		limit = MaxBytesLength
+	}
+	if limit > 0 && sz > int64(limit) {
+		if r.err == nil {
+			r.err = ErrTooLarge{Size: sz, Limit: limit}
+		}
+		return false
+	}
+	return true
+}
+
+// SetZeroCopy enables decoding of strings without copying. If the underlying reader is a BytesReader, strings
+// returned by ReadString and ReadStringVarint point directly into it's buffer, otherwise they share memory
+// with a temporary buffer allocated by Reader, which saves one copy.
+//
+// It is unsafe: the buffer of BytesReader must not be modified while any of returned strings is in use,
+// and it is not garbage-collected until all strings are released, even if they are short.
+func (r *Reader) SetZeroCopy(on bool) {
+	r.zeroCopy = on
+}
+
+// readN reads n bytes. If share is set and the underlying reader is a BytesReader, it returns a sub-slice
+// of it's buffer instead of a copy.
+func (r *Reader) readN(n int, share bool) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if br, ok := r.R.(*BytesReader); ok && share {
+		b, err := br.next(n)
+		if err != nil {
+			r.err = err
+		}
+		return b
+	}
+	b := make([]byte, n)
+	r.ReadRawBytes(b)
+	return b
+}
+
+// toString converts bytes returned by readN to a string, without copying if zero-copy mode is enabled.
+func (r *Reader) toString(b []byte) string {
+	if r.zeroCopy && len(b) != 0 {
+		return *(*string)(unsafe.Pointer(&b))
+	}
+	return string(b)
+}
+
 func (r *Reader) Read(p []byte) (int, error) {
 	if r.err != nil {
 		return 0, r.err
@@ -70,24 +145,25 @@ func (r *Reader) ReadRawBytes(buf []byte) error {
 // If the specified size of the byte array is 0 (empty) or negative (null)
 // nil is returned for the []byte.
 func (r *Reader) ReadBytes() []byte {
+	return r.readBytes(false)
+}
+
+func (r *Reader) readBytes(share bool) []byte {
 	// the first four bytes give the length of the remaining byte array
 	sz := r.ReadInt()
 	// sz of 0 indicates empty byte array
 	// sz of -1 indicates null value
 	// for now, I'm returning nil []byte for both
-	if sz <= 0 {
+	if sz <= 0 || !r.checkSize(int64(sz)) {
 		return nil
 	}
-
-	b := make([]byte, sz)
-	r.ReadRawBytes(b)
-	return b
+	return r.readN(int(sz), share)
 }
 
 // ReadString xxxx
 // If the string size is 0 an empty string and nil error are returned
 func (r *Reader) ReadString() string {
-	return string(r.ReadBytes())
+	return r.toString(r.readBytes(r.zeroCopy))
 }
 
 func (r *Reader) ReadByte() byte {
@@ -168,17 +244,20 @@ func (r *Reader) ReadUvarint() uint64 {
 // input buffer. The difference is that the integer indicating the length
 // of the byte array to follow is a zigzag encoded varint.
 func (r *Reader) ReadBytesVarint() []byte {
+	return r.readBytesVarint(false)
+}
+
+func (r *Reader) readBytesVarint(share bool) []byte {
 	// an encoded varint give the length of the remaining byte array
 	lenbytes := r.ReadVarint()
 	if lenbytes == 0 {
 		return nil
 	} else if lenbytes < 0 {
 		panic(fmt.Errorf("Error in varint.ReadBytes: size of bytes was less than zero: %v", lenbytes))
+	} else if !r.checkSize(lenbytes) {
+		return nil
 	}
-
-	data := make([]byte, int(lenbytes))
-	r.ReadRawBytes(data)
-	return data
+	return r.readN(int(lenbytes), share)
 }
 
 // varint.ReadString, like rw.ReadString, first reads a length from the
@@ -186,7 +265,58 @@ func (r *Reader) ReadBytesVarint() []byte {
 // from the input buffer. The difference is that the integer indicating the
 // length of the byte array to follow is a zigzag encoded varint.
 func (r *Reader) ReadStringVarint() string {
-	return string(r.ReadBytesVarint())
+	return r.toString(r.readBytesVarint(r.zeroCopy))
+}
+
+// BytesReader is an io.ReadSeeker over a byte slice, similar to bytes.Reader. Reader that reads from it
+// can decode strings without copying, see Reader.SetZeroCopy.
+type BytesReader struct {
+	buf []byte
+	off int
+}
+
+// NewBytesReader creates a new BytesReader reading from b.
+func NewBytesReader(b []byte) *BytesReader {
+	return &BytesReader{buf: b}
+}
+
+func (r *BytesReader) Read(p []byte) (int, error) {
+	if r.off >= len(r.buf) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.buf[r.off:])
+	r.off += n
+	return n, nil
+}
+
+func (r *BytesReader) Seek(off int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = off
+	case io.SeekCurrent:
+		abs = int64(r.off) + off
+	case io.SeekEnd:
+		abs = int64(len(r.buf)) + off
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if abs < 0 {
+		return 0, fmt.Errorf("negative position: %d", abs)
+	}
+	r.off = int(abs)
+	return abs, nil
+}
+
+// next returns the next n bytes of the buffer without copying them.
+func (r *BytesReader) next(n int) ([]byte, error) {
+	if r.off > len(r.buf) || len(r.buf)-r.off < n {
+		r.off = len(r.buf)
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.off : r.off+n : r.off+n]
+	r.off += n
+	return b, nil
 }
 
 func NewReadSeeker(r io.ReadSeeker) *ReadSeeker {
@@ -3,6 +3,7 @@ package rw
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
 	"testing"
 )
 
@@ -50,6 +51,28 @@ func TestReadBytes(t *testing.T) {
 	equals(t, byte(4), bs[3])
 }
 
+func TestReadBytesTooLarge(t *testing.T) {
+	data := []byte{0x7f, 0xff, 0xff, 0xff, 1, 2, 3, 4}
+	r := NewReader(bytes.NewReader(data))
+	if bs := r.ReadBytes(); bs != nil {
+		t.Fatalf("unexpected data: %v", bs)
+	} else if _, ok := r.Err().(ErrTooLarge); !ok {
+		t.Fatalf("expected ErrTooLarge, got: %v", r.Err())
+	}
+
+	r = NewReader(bytes.NewReader([]byte{0, 0, 0, 4, 1, 2, 3, 4}))
+	r.SetLimit(3)
+	if r.ReadBytes(); r.Err() != (ErrTooLarge{Size: 4, Limit: 3}) {
+		t.Fatalf("expected ErrTooLarge, got: %v", r.Err())
+	}
+
+	r = NewReader(bytes.NewReader([]byte{0x7e, 1, 2}))
+	r.SetLimit(10)
+	if r.ReadBytesVarint(); r.Err() != (ErrTooLarge{Size: 63, Limit: 10}) {
+		t.Fatalf("expected ErrTooLarge, got: %v", r.Err())
+	}
+}
+
 func TestReadBytesWithNullBytesArray(t *testing.T) {
 	var bs []byte
 
@@ -272,3 +295,25 @@ Let us now consider repetition clauses (like, while B repeat A or repeat A until
 The main point is that the values of these indices are outside programmer's control; they are generated (either by the write-up of his program or by the dynamic evolution of the process) whether he wishes or not. They provide independent coordinates in which to describe the progress of the process.
 
 Why do we need such independent coordinates? The reason is - and this seems to be inherent to sequential processes - that we can interpret the value of a variable only with respect to the progress of the process. If we wish to count the number, n say, of people in an initially empty room, we can achieve this by increasing n by one whenever we see someone entering the room. In the in-between moment that we have observed someone entering the room but have not yet performed the subsequent increase of n, its value equals the number of people in the room minus one!`
+
+func TestReadStringZeroCopy(t *testing.T) {
+	data := []byte{0, 0, 0, 3, 'a', 'b', 'c', 8, 'Z', 'A', 'X', 'X', 0, 0, 0, 1}
+	r := NewReader(NewBytesReader(data))
+	r.SetZeroCopy(true)
+	s1 := r.ReadString()
+	s2 := r.ReadStringVarint()
+	equals(t, "abc", s1)
+	equals(t, "ZAXX", s2)
+	equals(t, int32(1), r.ReadInt())
+	ok(t, r.Err())
+
+	// strings share memory with the buffer
+	data[4], data[8] = 'x', 'z'
+	equals(t, "xbc", s1)
+	equals(t, "zAXX", s2)
+
+	r = NewReader(NewBytesReader(data[:6]))
+	r.SetZeroCopy(true)
+	equals(t, "", r.ReadString())
+	equals(t, io.ErrUnexpectedEOF, r.Err())
+}
@@ -1,6 +1,10 @@
 package orient
 
-import "log"
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
 
 type OClass struct {
 	Name             string
@@ -9,6 +13,7 @@ type OClass struct {
 	DefaultClusterId int32
 	ClusterIds       []int32
 	SuperClass       string
+	Super            *OClass // resolved SuperClass, set only for classes loaded with Schema
 	OverSize         float32
 	StrictMode       bool
 	AbstractClass    bool
@@ -17,51 +22,40 @@ type OClass struct {
 }
 
 // Should be passed an Document that comes from a load schema
-// request to the database.
+// request to the database. Fields of unexpected types are logged and skipped.
 func NewOClassFromDocument(doc *Document) *OClass {
 	oclass := &OClass{Properties: make(map[string]*OProperty)}
 
-	if fld := doc.GetField("name"); fld != nil && fld.Value != nil {
-		oclass.Name = fld.Value.(string)
-	}
-	if fld := doc.GetField("shortName"); fld != nil && fld.Value != nil {
-		oclass.ShortName = fld.Value.(string)
-	}
+	schemaField(doc, "name", &oclass.Name)
+	schemaField(doc, "shortName", &oclass.ShortName)
 
-	// properties comes back as an Document
-	if fld := doc.GetField("properties"); fld != nil && fld.Value != nil {
-		propsDocs := convertToODocumentRefSlice(fld.Value.([]interface{}))
-		for _, propDoc := range propsDocs {
-			oprop := NewOPropertyFromDocument(propDoc)
-			oclass.Properties[oprop.Name] = oprop
+	// properties comes back as an Document; invalid items are skipped one by one
+	var props []interface{}
+	schemaField(doc, "properties", &props)
+	for i, p := range props {
+		if p == nil {
+			continue
 		}
+		var propDoc *Document
+		if err := convertTypes(reflect.ValueOf(&propDoc).Elem(), reflect.ValueOf(p)); err != nil || propDoc == nil {
+			Logf("unexpected type for property %d of class '%s': %T", i, oclass.Name, p)
+			continue
+		}
+		oprop := NewOPropertyFromDocument(propDoc)
+		oclass.Properties[oprop.Name] = oprop
 	}
-	if fld := doc.GetField("defaultClusterId"); fld != nil && fld.Value != nil {
-		oclass.DefaultClusterId = fld.Value.(int32)
-	}
-	if fld := doc.GetField("clusterIds"); fld != nil && fld.Value != nil {
-		oclass.ClusterIds = convertToInt32Slice(fld.Value.([]interface{}))
-	}
-	if fld := doc.GetField("superClass"); fld != nil && fld.Value != nil {
-		oclass.SuperClass = fld.Value.(string)
-	}
-	if fld := doc.GetField("overSize"); fld != nil && fld.Value != nil {
-		oclass.OverSize = fld.Value.(float32)
-	}
-	if fld := doc.GetField("strictMode"); fld != nil && fld.Value != nil {
-		oclass.StrictMode = fld.Value.(bool)
-	}
-	if fld := doc.GetField("abstract"); fld != nil && fld.Value != nil {
-		oclass.AbstractClass = fld.Value.(bool)
-	}
-	if fld := doc.GetField("clusterSelection"); fld != nil && fld.Value != nil {
-		oclass.ClusterSelection = fld.Value.(string)
-	}
+	schemaField(doc, "defaultClusterId", &oclass.DefaultClusterId)
+	schemaField(doc, "clusterIds", &oclass.ClusterIds)
+	schemaField(doc, "superClass", &oclass.SuperClass)
+	schemaField(doc, "overSize", &oclass.OverSize)
+	schemaField(doc, "strictMode", &oclass.StrictMode)
+	schemaField(doc, "abstract", &oclass.AbstractClass)
+	schemaField(doc, "clusterSelection", &oclass.ClusterSelection)
 	if fld := doc.GetField("customFields"); fld != nil && fld.Value != nil {
 		if m, ok := fld.Value.(map[string]string); ok {
 			oclass.CustomFields = m
 		} else {
-			log.Printf("unknown type for customFields: %T\n", fld.Value)
+			Logf("unknown type for customFields: %T", fld.Value)
 			oclass.CustomFields = make(map[string]string)
 		}
 	}
@@ -69,18 +63,73 @@ func NewOClassFromDocument(doc *Document) *OClass {
 	return oclass
 }
 
-func convertToODocumentRefSlice(x []interface{}) []*Document {
-	y := make([]*Document, len(x))
-	for i, v := range x {
-		y[i] = v.(*Document)
+// schemaField decodes a field of schema record into out. Server versions differ in types of some fields,
+// so values are converted if possible, and fields that can't be converted are logged and skipped.
+func schemaField(doc *Document, name string, out interface{}) {
+	fld := doc.GetField(name)
+	if fld == nil || fld.Value == nil {
+		return
+	}
+	targ := reflect.ValueOf(out).Elem()
+	if _, ok := fld.Value.(string); !ok && targ.Kind() == reflect.String {
+		// numbers are convertible to strings in Go, but as runes
+		Logf("unexpected type for schema field '%s': %T", name, fld.Value)
+		return
+	}
+	v := reflect.New(targ.Type()).Elem()
+	if err := convertTypes(v, reflect.ValueOf(fld.Value)); err != nil {
+		Logf("unexpected type for schema field '%s': %T: %v", name, fld.Value, err)
+		return
+	}
+	targ.Set(v)
+}
+
+// ToDocument converts class to a document in the format of schema record, as read by NewOClassFromDocument.
+// Properties are stored as embedded documents, sorted by name.
+func (c *OClass) ToDocument() *Document {
+	doc := NewEmptyDocument()
+	doc.SetField("name", c.Name)
+	if c.ShortName != "" {
+		doc.SetField("shortName", c.ShortName)
+	}
+	if c.SuperClass != "" {
+		doc.SetField("superClass", c.SuperClass)
+	}
+	doc.SetFieldWithType("defaultClusterId", c.DefaultClusterId, INTEGER)
+	ids := make([]interface{}, len(c.ClusterIds))
+	for i, id := range c.ClusterIds {
+		ids[i] = id
 	}
-	return y
+	doc.SetFieldWithType("clusterIds", ids, EMBEDDEDLIST)
+	if c.ClusterSelection != "" {
+		doc.SetField("clusterSelection", c.ClusterSelection)
+	}
+	doc.SetFieldWithType("overSize", c.OverSize, FLOAT)
+	doc.SetField("strictMode", c.StrictMode)
+	doc.SetField("abstract", c.AbstractClass)
+	names := make([]string, 0, len(c.Properties))
+	for name := range c.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	props := make([]interface{}, len(names))
+	for i, name := range names {
+		props[i] = c.Properties[name].ToDocument()
+	}
+	doc.SetFieldWithType("properties", props, EMBEDDEDSET)
+	if len(c.CustomFields) != 0 {
+		doc.SetFieldWithType("customFields", c.CustomFields, EMBEDDEDMAP)
+	}
+	return doc
 }
 
-func convertToInt32Slice(x []interface{}) []int32 {
-	y := make([]int32, len(x))
-	for i, v := range x {
-		y[i] = v.(int32)
+// IsSubClassOf checks if class is the same as a given class or inherits from it.
+// Requires resolved super classes (see Schema).
+func (c *OClass) IsSubClassOf(name string) bool {
+	for ; c != nil; c = c.Super {
+		if strings.EqualFold(c.Name, name) {
+			return true
+		}
 	}
-	return y
+	return false
 }
@@ -1,6 +1,7 @@
 package orient_test
 
 import (
+	"database/sql"
 	"fmt"
 	"math/rand"
 	"net"
@@ -12,7 +13,7 @@ import (
 
 	"github.com/fsouza/go-dockerclient"
 	"gopkg.in/istreamdata/orientgo.v2"
-	_ "gopkg.in/istreamdata/orientgo.v2/obinary"
+	"gopkg.in/istreamdata/orientgo.v2/obinary"
 	"reflect"
 )
 
@@ -429,6 +430,39 @@ func TestSQLCommandParams(t *testing.T) {
 	}
 }
 
+func TestPreparedStmt(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	stmt, err := db.Prepare(`SELECT FROM Cat WHERE name=?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	for _, name := range []string{"Linus", "Keiko"} {
+		var doc *orient.Document
+		if err = stmt.Query(name).All(&doc); err != nil {
+			t.Fatal(err)
+		} else if doc.GetField("name").Value.(string) != name {
+			t.Fatal("wrong field value")
+		}
+	}
+
+	upd, err := db.Prepare(`UPDATE Cat SET age = ? WHERE name = ?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err = upd.Exec(16, "Linus").All(&n); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("wrong affected count: %d", n)
+	}
+}
+
 func TestSQLCommandParamsCustomType(t *testing.T) {
 	notShort(t)
 	db, closer := SpinOrientAndOpenDB(t, false)
@@ -679,3 +713,673 @@ func TestConcurrentModification(t *testing.T) {
 		}
 	}
 }
+
+func TestTxCommit(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+	if err := db.ReloadSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := orient.NewDocument("Animal")
+	owner.SetField("name", "Anna")
+	if err = tx.CreateRecord(owner); err != nil {
+		t.Fatal(err)
+	} else if !owner.GetIdentity().IsTemporary() {
+		t.Fatalf("expected temporary RID, got %v", owner.GetIdentity())
+	}
+	cat := orient.NewDocument("Cat")
+	cat.SetField("name", "Tom")
+	cat.SetField("owner", owner.GetIdentity())
+	if err = tx.CreateRecord(cat); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if !owner.GetIdentity().IsPersistent() || !cat.GetIdentity().IsPersistent() {
+		t.Fatalf("expected persistent RIDs, got %v and %v", owner.GetIdentity(), cat.GetIdentity())
+	} else if link := cat.GetField("owner").Value.(orient.RID); link != owner.GetIdentity() {
+		t.Fatalf("link was not updated: %v vs %v", link, owner.GetIdentity())
+	}
+	var out struct {
+		Name string `mapstructure:"owner"`
+	}
+	err = db.Command(orient.NewSQLQuery(`SELECT owner.name AS owner FROM Cat WHERE name = ?`, "Tom")).All(&out)
+	if err != nil {
+		t.Fatal(err)
+	} else if out.Name != "Anna" {
+		t.Fatalf("wrong linked record: %q", out.Name)
+	}
+	if err = tx.Commit(); err != orient.ErrTxDone {
+		t.Fatalf("expected ErrTxDone, got %v", err)
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+	if err := db.ReloadSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := orient.NewDocument("Cat")
+	cat.SetField("name", "Tom")
+	if err = tx.CreateRecord(cat); err != nil {
+		t.Fatal(err)
+	} else if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	var docs []orient.OIdentifiable
+	if err = db.Command(orient.NewSQLQuery(`SELECT FROM Cat WHERE name = ?`, "Tom")).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 0 {
+		t.Fatal("record was saved after rollback")
+	}
+}
+
+func TestLiveQuery(t *testing.T) {
+	notShort(t)
+	if orientVersion < "2.1" {
+		t.Skip("live queries are supported since OrientDB 2.1")
+	}
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	token, ch, err := db.LiveQuery("SELECT FROM Cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Command(orient.NewSQLCommand(`INSERT INTO Cat SET name = ?, age = ?`, "Tom", 3)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-ch:
+		if r.Op != orient.LiveCreate {
+			t.Fatalf("unexpected operation: %v", r.Op)
+		} else if name := r.Doc.GetField("name"); name == nil || name.Value != "Tom" {
+			t.Fatalf("wrong record: %+v", r.Doc)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no live notification received")
+	}
+	if err = db.Unsubscribe(token); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("channel is not closed after unsubscribe")
+	}
+}
+
+func TestClusters(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	clusters, err := db.Clusters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cat *orient.ClusterInfo
+	for i, c := range clusters {
+		if c.Name == "cat" {
+			cat = &clusters[i]
+		}
+	}
+	if cat == nil {
+		t.Fatalf("no cluster for Cat class: %+v", clusters)
+	} else if cat.Records != 2 {
+		t.Fatalf("wrong records count: %d", cat.Records)
+	}
+	n, err := db.CountCluster("cat")
+	if err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatalf("wrong records count: %d", n)
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	s, err := db.LoadSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := s.Class("Cat")
+	if cat == nil {
+		t.Fatal("no Cat class in schema")
+	} else if !cat.IsSubClassOf("Animal") {
+		t.Fatalf("wrong super class: %+v", cat.Super)
+	} else if cat.Properties["caretaker"] == nil {
+		t.Fatalf("no property in class: %+v", cat.Properties)
+	}
+}
+
+func TestInsertAll(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	docs := make([]*orient.Document, 5)
+	for i := range docs {
+		docs[i] = orient.NewEmptyDocument()
+		docs[i].SetField("name", fmt.Sprintf("cat %d", i))
+		docs[i].SetField("age", int32(i))
+	}
+	rids, err := db.InsertAll("Cat", docs, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rids) != len(docs) {
+		t.Fatalf("wrong number of RIDs: %v", rids)
+	}
+	for i, rid := range rids {
+		var cat struct {
+			Name string
+			Age  int
+		}
+		if err = db.Command(orient.NewSQLQuery("SELECT FROM " + rid.String())).All(&cat); err != nil {
+			t.Fatal(err)
+		} else if cat.Name != fmt.Sprintf("cat %d", i) || cat.Age != i {
+			t.Fatalf("wrong record for %v: %+v", rid, cat)
+		} else if docs[i].RID != rid {
+			t.Fatalf("RID is not set for document %d", i)
+		}
+	}
+
+	_, err = db.InsertAll("NoSuchClass", docs[:1], 1)
+	if e, ok := err.(orient.ErrBatchInsert); !ok {
+		t.Fatalf("expected batch error, got: %v", err)
+	} else if e.Batch != 0 || e.Inserted != 0 {
+		t.Fatalf("wrong batch error: %v", e)
+	}
+}
+
+func TestUpdateRecordConcurrentModification(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	doc := orient.NewEmptyDocument()
+	doc.SetField("name", "first")
+	if err := db.CreateRecord(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Command(orient.NewSQLCommand(`UPDATE `+doc.RID.String()+` SET name = ?`, "second")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	doc.SetField("name", "third")
+	err := db.UpdateRecord(doc)
+	if !orient.IsConcurrentModification(err) {
+		t.Fatalf("expected concurrent modification, got: %v", err)
+	} else if e, ok := err.(orient.ErrConcurrentModification); ok && e.Actual >= 0 && e.Actual <= e.Expected {
+		t.Fatalf("wrong versions: %+v", e)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	key := map[string]interface{}{"name": "Tom"}
+	if _, err := db.Upsert("Cat", key, map[string]interface{}{"age": 3}); err == nil {
+		t.Fatal("expected an error for non-indexed key")
+	}
+	if err := db.Command(orient.NewSQLCommand(`CREATE INDEX Cat.name UNIQUE`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := db.Upsert("Cat", key, map[string]interface{}{"age": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc2, err := db.Upsert("Cat", key, map[string]interface{}{"age": 4})
+	if err != nil {
+		t.Fatal(err)
+	} else if doc2.RID != doc.RID {
+		t.Fatalf("upsert created a new record: %v != %v", doc2.RID, doc.RID)
+	}
+	var cat struct {
+		Name string
+		Age  int
+	}
+	if err = doc2.ToStruct(&cat); err != nil {
+		t.Fatal(err)
+	} else if cat.Name != "Tom" || cat.Age != 4 {
+		t.Fatalf("wrong record: %+v", cat)
+	}
+}
+
+func TestLoadFetchPlan(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	err := db.Command(orient.NewSQLCommand(`UPDATE Cat SET buddy = (SELECT FROM Cat WHERE name = 'Keiko') WHERE name = 'Linus'`)).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var linus *orient.Document
+	if err = db.Command(orient.NewSQLQuery(`SELECT FROM Cat WHERE name = 'Linus'`)).All(&linus); err != nil {
+		t.Fatal(err)
+	}
+	doc, related, err := db.Load(linus.RID, "*:1")
+	if err != nil {
+		t.Fatal(err)
+	} else if doc.RID != linus.RID {
+		t.Fatalf("wrong record loaded: %v", doc)
+	} else if len(related) != 1 {
+		t.Fatalf("expected one pre-fetched record, got: %v", related)
+	}
+	buddy, ok := doc.GetField("buddy").Value.(orient.OIdentifiable)
+	if !ok {
+		t.Fatalf("unexpected link: %v", doc.GetField("buddy"))
+	}
+	keiko, err := orient.PrefetchedLoader(related, nil).Load(buddy.GetIdentity())
+	if err != nil {
+		t.Fatal(err)
+	} else if keiko.GetField("name").Value != "Keiko" {
+		t.Fatalf("wrong buddy: %v", keiko)
+	}
+}
+
+func TestSave(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	doc := orient.NewDocument("Cat")
+	doc.SetField("name", "Tom")
+	if err := db.Save(doc); err != nil {
+		t.Fatal(err)
+	} else if !doc.RID.IsPersistent() {
+		t.Fatalf("record was not created: %v", doc.RID)
+	}
+	rid, vers := doc.RID, doc.Vers
+	doc.SetField("age", 3)
+	if err := db.Save(doc); err != nil {
+		t.Fatal(err)
+	} else if doc.RID != rid || doc.Vers <= vers {
+		t.Fatalf("record was not updated: %v (version %d)", doc.RID, doc.Vers)
+	}
+	doc.Vers = vers
+	if err := db.Save(doc); !orient.IsConcurrentModification(err) {
+		t.Fatalf("expected concurrent modification, got: %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	doc := orient.NewEmptyDocument()
+	doc.SetField("name", "first")
+	if err := db.CreateRecord(doc); err != nil {
+		t.Fatal(err)
+	}
+	stale := doc.Version()
+	doc.SetField("name", "second")
+	if err := db.UpdateRecord(doc); err != nil {
+		t.Fatal(err)
+	}
+	doc.Vers = stale
+	if err := db.DeleteDocument(doc); !orient.IsConcurrentModification(err) {
+		t.Fatalf("expected concurrent modification, got: %v", err)
+	}
+	if err := db.Delete(doc.RID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(doc.RID); err == nil {
+		t.Fatal("expected an error for deleted record")
+	} else if e, ok := err.(orient.ErrRecordNotFound); !ok || e.RID != doc.RID {
+		t.Fatalf("expected record not found error, got: %v", err)
+	}
+}
+
+func TestSQLDriverQuery(t *testing.T) {
+	notShort(t)
+	addr, rm := SpinOrientServer(t)
+	defer rm()
+	defer catch(t)
+
+	db, err := sql.Open(orient.DriverNameSQL, dbUser+"@"+dbPass+":"+addr+"/"+dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err = db.Exec("CREATE CLASS Cat"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := db.Exec("INSERT INTO Cat (name, age) VALUES (?, ?)", "Linus", 15)
+	if err != nil {
+		t.Fatal(err)
+	} else if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("wrong rows affected: %d", n)
+	}
+	rows, err := db.Query("SELECT name, age FROM Cat WHERE age > ?", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var (
+			name string
+			age  int
+		)
+		if err = rows.Scan(&name, &age); err != nil {
+			t.Fatal(err)
+		} else if age != 15 {
+			t.Fatalf("wrong age: %d", age)
+		}
+		names = append(names, name)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	} else if len(names) != 1 || names[0] != "Linus" {
+		t.Fatalf("wrong rows: %v", names)
+	}
+}
+
+func TestCommandAffectedCount(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	var n int
+	if err := db.Command(orient.NewSQLCommand(`UPDATE Cat SET age = age + 1`)).All(&n); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatalf("wrong affected count: %d", n)
+	}
+	err := db.Command(orient.NewSQLCommand(`INSERT INTO Cat (name) VALUES ('Tom')`)).All(&n)
+	if _, ok := err.(orient.ErrNoCount); !ok {
+		t.Fatalf("expected ErrNoCount, got: %v", err)
+	}
+	if err = db.Command(orient.NewSQLCommand(`DELETE FROM Cat WHERE name = 'Tom'`)).All(&n); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("wrong affected count: %d", n)
+	}
+}
+
+func TestSelectLetSubquery(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	type Friend struct {
+		Name string
+	}
+	type Row struct {
+		Name    string
+		Friends []Friend
+	}
+	var rows []Row
+	err := db.Command(orient.NewSQLQuery(
+		`SELECT name, $f AS friends FROM Cat LET $f = (SELECT name FROM Cat WHERE name <> $parent.$current.name) ORDER BY name`,
+	)).All(&rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Row{
+		{Name: "Keiko", Friends: []Friend{{Name: "Linus"}}},
+		{Name: "Linus", Friends: []Friend{{Name: "Keiko"}}},
+	}
+	if !reflect.DeepEqual(rows, expect) {
+		t.Fatalf("wrong data: %+v != %+v", rows, expect)
+	}
+
+	var maps []struct {
+		Friends []map[string]interface{}
+	}
+	err = db.Command(orient.NewSQLQuery(
+		`SELECT name, $f AS friends FROM Cat LET $f = (SELECT name FROM Cat WHERE name <> $parent.$current.name) ORDER BY name`,
+	)).All(&maps)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(maps) != 2 || len(maps[0].Friends) != 1 || maps[0].Friends[0]["name"] != "Linus" {
+		t.Fatalf("wrong data: %+v", maps)
+	}
+}
+
+func TestIndexGet(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+	SeedDB(t, db)
+
+	if err := db.CreateIndex("Cat.name", "Cat", orient.IndexUnique, "name"); err != nil {
+		t.Fatal(err)
+	} else if err = db.CreateIndex("CatNameAge", "Cat", orient.IndexNotUnique, "name", "age"); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := db.IndexGet("Cat.name", "Linus")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 1 || docs[0].GetField("name").Value != "Linus" {
+		t.Fatalf("wrong records: %v", docs)
+	}
+	if docs, err = db.IndexGet("CatNameAge", []interface{}{"Keiko", int32(10)}); err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 1 || docs[0].GetField("name").Value != "Keiko" {
+		t.Fatalf("wrong records: %v", docs)
+	}
+	if docs, err = db.IndexGet("Cat.name", "Tom"); err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 0 {
+		t.Fatalf("unexpected records: %v", docs)
+	}
+	if err = db.DropIndex("CatNameAge"); err != nil {
+		t.Fatal(err)
+	} else if _, err = db.IndexGet("CatNameAge", []interface{}{"Keiko", int32(10)}); err == nil {
+		t.Fatal("index was not dropped")
+	}
+}
+
+func TestIndexIntParams(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	for _, sql := range []string{
+		"CREATE CLASS Counter",
+		"CREATE PROPERTY Counter.small INTEGER",
+		"CREATE PROPERTY Counter.big LONG",
+		"CREATE INDEX Counter.small UNIQUE",
+		"CREATE INDEX Counter.big UNIQUE",
+		"INSERT INTO Counter SET small = 5, big = 5",
+	} {
+		if err := db.Command(orient.NewSQLCommand(sql)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tp := range []orient.OType{orient.INTEGER, orient.LONG} {
+		db.SetDefaultIntType(tp)
+		for _, field := range []string{"small", "big"} {
+			var docs []*orient.Document
+			if err := db.Command(orient.NewSQLQuery("SELECT FROM Counter WHERE "+field+" = ?", 5)).All(&docs); err != nil {
+				t.Fatal(err)
+			} else if len(docs) != 1 {
+				t.Fatalf("%v param: wrong records for %s: %v", tp, field, docs)
+			}
+		}
+	}
+	var docs []*orient.Document
+	if err := db.Command(orient.NewSQLQuery("SELECT FROM Counter WHERE big = ?", orient.TypedParam(5, orient.LONG))).All(&docs); err != nil {
+		t.Fatal(err)
+	} else if len(docs) != 1 {
+		t.Fatalf("wrong records: %v", docs)
+	}
+}
+
+func TestCreateClass(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	animal, err := db.CreateClass("Animal", orient.ClassOptions{Extends: "V", Abstract: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if !animal.AbstractClass || !animal.IsSubClassOf("V") {
+		t.Fatalf("wrong class: %+v", animal)
+	}
+	dog, err := db.CreateClass("Dog", orient.ClassOptions{Extends: "Animal", ClusterCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	} else if dog.AbstractClass || !dog.IsSubClassOf("Animal") || len(dog.ClusterIds) != 2 {
+		t.Fatalf("wrong class: %+v", dog)
+	}
+	if _, err = db.CreateClass("Dog", orient.ClassOptions{}); err == nil {
+		t.Fatal("expected an error for existing class")
+	} else if _, ok := err.(orient.ErrClassExists); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestConnectServer(t *testing.T) {
+	notShort(t)
+	addr, rm := SpinOrientServer(t)
+	defer rm()
+
+	admin, err := orient.ConnectServer(addr, srvUser, srvPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	if vers, err := admin.ProtocolVersion(); err != nil {
+		t.Fatal(err)
+	} else if vers < obinary.MinProtocolVersion {
+		t.Fatalf("wrong protocol version: %d", vers)
+	}
+	list, err := admin.DatabaseList()
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := list[dbName]; !ok {
+		t.Fatalf("database %q is not listed: %v", dbName, list)
+	}
+	if _, err = orient.ConnectServer(addr, srvUser, srvPass+"_wrong"); err == nil {
+		t.Fatal("expected an error for wrong password")
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	if vers := db.ProtocolVersion(); vers < obinary.MinProtocolVersion || vers > obinary.MaxProtocolVersion {
+		t.Fatalf("unexpected protocol version: %d", vers)
+	}
+}
+
+func TestAdminDatabaseErrors(t *testing.T) {
+	notShort(t)
+	addr, rm := SpinOrientServer(t)
+	defer rm()
+
+	admin, err := orient.ConnectServer(addr, srvUser, srvPass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	const name = "test_admin_errors"
+	if ok, err := admin.DatabaseExists(name, orient.Volatile); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("database should not exist")
+	}
+	if err = admin.CreateDatabase(name, orient.DocumentDB, orient.Volatile); err != nil {
+		t.Fatal(err)
+	}
+	if err = admin.CreateDatabase(name, orient.DocumentDB, orient.Volatile); err == nil {
+		t.Fatal("expected an error for existing database")
+	} else if _, ok := err.(orient.ErrDatabaseExists); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = admin.DropDatabase(name, orient.Volatile); err != nil {
+		t.Fatal(err)
+	}
+	if err = admin.DropDatabase(name, orient.Volatile); err == nil {
+		t.Fatal("expected an error for missing database")
+	} else if _, ok := err.(orient.ErrDatabaseNotFound); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLinkMapVertices(t *testing.T) {
+	notShort(t)
+	db, closer := SpinOrientAndOpenDB(t, false)
+	defer closer()
+	defer catch(t)
+
+	for _, sql := range []string{
+		"CREATE CLASS Member EXTENDS V",
+		"CREATE CLASS Team EXTENDS V",
+		"CREATE PROPERTY Team.members LINKMAP",
+	} {
+		if err := db.Command(orient.NewSQLCommand(sql)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var alice, bob orient.RID
+	Nil(t, db.Command(orient.NewSQLCommand("CREATE VERTEX Member SET name = 'alice'")).All(&alice))
+	Nil(t, db.Command(orient.NewSQLCommand("CREATE VERTEX Member SET name = 'bob'")).All(&bob))
+	Nil(t, db.Command(orient.NewSQLCommand(fmt.Sprintf(
+		"CREATE VERTEX Team SET name = 'core', members = {'lead': %v, 'dev': %v}", alice, bob))).Err())
+
+	var ids struct {
+		Members map[string]orient.RID
+	}
+	Nil(t, db.Command(orient.NewSQLQuery("SELECT members FROM Team")).All(&ids))
+	Equals(t, map[string]orient.RID{"lead": alice, "dev": bob}, ids.Members)
+
+	var team struct {
+		Members map[string]*orient.Document
+	}
+	Nil(t, db.Command(orient.NewSQLQuery("SELECT members FROM Team")).All(&team))
+	True(t, len(team.Members) == 2, "wrong number of members")
+	Equals(t, "alice", team.Members["lead"].GetField("name").Value)
+	Equals(t, "bob", team.Members["dev"].GetField("name").Value)
+}
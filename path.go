@@ -0,0 +1,168 @@
+package orient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Path is a path in a graph, that starts at a vertex and follows outgoing edges.
+// Edges[i] connects Vertices[i] to Vertices[i+1].
+type Path struct {
+	Vertices []*Document
+	Edges    []*Document
+}
+
+// Start returns the first vertex of a path.
+func (p Path) Start() *Document {
+	if len(p.Vertices) == 0 {
+		return nil
+	}
+	return p.Vertices[0]
+}
+
+// End returns the last vertex of a path.
+func (p Path) End() *Document {
+	if len(p.Vertices) == 0 {
+		return nil
+	}
+	return p.Vertices[len(p.Vertices)-1]
+}
+
+// Len returns the number of edges in a path.
+func (p Path) Len() int {
+	return len(p.Edges)
+}
+
+// edgeEnds returns source and destination vertexes of an edge document.
+func edgeEnds(e *Document) (from, to RID, err error) {
+	for _, f := range []struct {
+		name string
+		rid  *RID
+	}{{"out", &from}, {"in", &to}} {
+		fld := e.GetField(f.name)
+		if fld == nil {
+			return from, to, fmt.Errorf("edge %v has no '%s' field", e.RID, f.name)
+		}
+		id, ok := fld.Value.(OIdentifiable)
+		if !ok {
+			return from, to, fmt.Errorf("unexpected type for '%s' field of edge %v: %T", f.name, e.RID, fld.Value)
+		}
+		*f.rid = id.GetIdentity()
+	}
+	return from, to, nil
+}
+
+// traversePaths walks a graph in breadth-first order from the start vertex up to a given depth.
+// Edges returns outgoing edges for a set of vertexes, and vertexes loads vertex documents.
+// Each reachable vertex is visited once, so a single shortest path is returned for it. Cycles are not followed.
+func traversePaths(start *Document, depth int,
+	edges func(from []RID) ([]*Document, error),
+	vertexes func(rids []RID) (map[RID]*Document, error),
+) ([]Path, error) {
+	paths := map[RID]Path{start.RID: {Vertices: []*Document{start}}}
+	frontier := []RID{start.RID}
+	var out []Path
+	for level := 0; level < depth && len(frontier) != 0; level++ {
+		list, err := edges(frontier)
+		if err != nil {
+			return nil, err
+		}
+		type step struct {
+			from RID
+			edge *Document
+		}
+		var (
+			next  []RID
+			steps = make(map[RID]step)
+		)
+		for _, e := range list {
+			from, to, err := edgeEnds(e)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := paths[from]; !ok {
+				continue // not an edge of the current frontier
+			} else if _, visited := paths[to]; visited {
+				continue
+			} else if _, ok := steps[to]; ok {
+				continue // already reached by another edge on this level
+			}
+			steps[to] = step{from: from, edge: e}
+			next = append(next, to)
+		}
+		if len(next) == 0 {
+			break
+		}
+		docs, err := vertexes(next)
+		if err != nil {
+			return nil, err
+		}
+		for _, rid := range next {
+			v, ok := docs[rid]
+			if !ok {
+				return nil, fmt.Errorf("vertex %v not found", rid)
+			}
+			s := steps[rid]
+			prev := paths[s.from]
+			p := Path{
+				Vertices: append(append(make([]*Document, 0, len(prev.Vertices)+1), prev.Vertices...), v),
+				Edges:    append(append(make([]*Document, 0, len(prev.Edges)+1), prev.Edges...), s.edge),
+			}
+			paths[rid] = p
+			out = append(out, p)
+		}
+		frontier = next
+	}
+	return out, nil
+}
+
+func ridList(rids []RID) string {
+	s := make([]string, len(rids))
+	for i, rid := range rids {
+		s[i] = rid.String()
+	}
+	return "[" + strings.Join(s, ", ") + "]"
+}
+
+// Traverse walks outgoing edges of given classes (all edges, if none are set) from the start vertex, up to
+// a given depth, and returns a path to each reachable vertex. Vertexes are visited in breadth-first order,
+// so the path to each vertex is one of the shortest ones, and each vertex is returned only once, even
+// if graph has cycles. Lightweight edges are not followed, since they have no edge records.
+func (db *Database) Traverse(start RID, edgeClasses []string, depth int) ([]Path, error) {
+	if !start.IsPersistent() {
+		return nil, fmt.Errorf("invalid start vertex: %v", start)
+	} else if depth < 0 {
+		return nil, fmt.Errorf("invalid traverse depth: %d", depth)
+	}
+	classes := make([]string, len(edgeClasses))
+	for i, class := range edgeClasses {
+		if !isSQLName(class) {
+			return nil, fmt.Errorf("invalid edge class name: %q", class)
+		}
+		classes[i] = "'" + class + "'"
+	}
+	var first *Document
+	if err := db.Command(NewSQLQuery(`SELECT FROM ` + start.String())).All(&first); err != nil {
+		return nil, err
+	}
+	edges := func(from []RID) ([]*Document, error) {
+		var docs []*Document
+		sql := `SELECT expand(outE(` + strings.Join(classes, ", ") + `)) FROM ` + ridList(from)
+		if err := db.Command(NewSQLQuery(sql)).All(&docs); err != nil && err != ErrNoRecord {
+			return nil, err
+		}
+		return docs, nil
+	}
+	vertexes := func(rids []RID) (map[RID]*Document, error) {
+		var docs []*Document
+		if err := db.Command(NewSQLQuery(`SELECT FROM ` + ridList(rids))).All(&docs); err != nil && err != ErrNoRecord {
+			return nil, err
+		}
+		out := make(map[RID]*Document, len(docs))
+		for _, doc := range docs {
+			out[doc.RID] = doc
+		}
+		return out, nil
+	}
+	return traversePaths(first, depth, edges, vertexes)
+}
@@ -0,0 +1,72 @@
+package orient
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTraversePaths(t *testing.T) {
+	vertexes := make(map[RID]*Document)
+	for i, name := range []string{"A", "B", "C", "D"} {
+		v := NewDocument("V")
+		v.RID = NewRID(9, int64(i))
+		v.SetField("name", name)
+		vertexes[v.RID] = v
+	}
+	var edges []*Document
+	for i, e := range [][2]int64{{0, 1}, {1, 2}, {2, 0}, {0, 2}, {2, 3}} {
+		doc := NewDocument("E")
+		doc.RID = NewRID(10, int64(i))
+		doc.SetField("out", NewRID(9, e[0]))
+		doc.SetField("in", NewRID(9, e[1]))
+		edges = append(edges, doc)
+	}
+	outE := func(from []RID) ([]*Document, error) {
+		var out []*Document
+		for _, e := range edges {
+			for _, rid := range from {
+				if e.GetField("out").Value == rid {
+					out = append(out, e)
+				}
+			}
+		}
+		return out, nil
+	}
+	load := func(rids []RID) (map[RID]*Document, error) {
+		out := make(map[RID]*Document)
+		for _, rid := range rids {
+			out[rid] = vertexes[rid]
+		}
+		return out, nil
+	}
+	str := func(paths []Path) string {
+		s := ""
+		for _, p := range paths {
+			for i, v := range p.Vertices {
+				if i > 0 {
+					s += fmt.Sprintf("-%d-", p.Edges[i-1].RID.ClusterPos)
+				}
+				s += v.GetField("name").Value.(string)
+			}
+			s += " "
+		}
+		return s
+	}
+	for depth, expect := range []string{
+		"",
+		"A-0-B A-3-C ",
+		"A-0-B A-3-C A-3-C-4-D ",
+		"A-0-B A-3-C A-3-C-4-D ",
+	} {
+		paths, err := traversePaths(vertexes[NewRID(9, 0)], depth, outE, load)
+		if err != nil {
+			t.Fatal(err)
+		} else if s := str(paths); s != expect {
+			t.Fatalf("wrong paths for depth %d: %q, expected: %q", depth, s, expect)
+		}
+	}
+	paths, _ := traversePaths(vertexes[NewRID(9, 0)], 2, outE, load)
+	if p := paths[2]; p.Len() != 2 || p.Start() != vertexes[NewRID(9, 0)] || p.End() != vertexes[NewRID(9, 3)] {
+		t.Fatalf("wrong path: %+v", p)
+	}
+}
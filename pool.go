@@ -0,0 +1,291 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultMaxIdle = 2
+
+// ErrPoolClosed is returned by Pool.Acquire after the pool was closed.
+var ErrPoolClosed = fmt.Errorf("pool is closed")
+
+// PoolOptions is a set of settings for database sessions pool.
+type PoolOptions struct {
+	// Type is a database type used to open sessions. Default is DocumentDB.
+	Type DatabaseType
+	// MaxOpen limits the number of opened sessions, including idle ones. Zero means no limit.
+	MaxOpen int
+	// MaxIdle limits the number of idle sessions kept in the pool. Zero means default (2), negative value disables reuse.
+	MaxIdle int
+	// IdleTimeout is the time after which idle sessions are closed. Zero means no timeout.
+	IdleTimeout time.Duration
+	// HealthCheck enables a server round-trip to check each idle session before handing it out.
+	HealthCheck bool
+	// Dial is a list of connection options, such as WithTLS.
+	Dial []DialOption
+}
+
+// PoolConn is a database session acquired from the Pool. It must be returned with Pool.Release after use.
+type PoolConn struct {
+	DBSession
+	lastUsed time.Time
+}
+
+// Command executes command using pooled session.
+func (c *PoolConn) Command(cmd OCommandRequestText) Results {
+	result, err := c.DBSession.Command(cmd)
+	if err != nil {
+		return &errorResult{err: convertError(err)}
+	}
+	return newResults(result)
+}
+
+// Pool keeps a set of opened database sessions for reuse. It is safe for concurrent use.
+//
+// Unlike Database, which manages connections internally, Pool gives a caller an exclusive session
+// for a series of requests. Sessions with broken connections are discarded on Release.
+type Pool struct {
+	cli  *Client
+	open func() (DBSession, error)
+	opts PoolOptions
+
+	mu      sync.Mutex
+	idle    []*PoolConn
+	numOpen int
+	closed  bool
+	freed   chan struct{} // closed and replaced each time a session is returned or closed
+	done    chan struct{}
+}
+
+// NewPool connects to OrientDB server and creates a pool of sessions to a given database.
+func NewPool(addr, db, user, pass string, opts PoolOptions) (*Pool, error) {
+	if opts.Type == "" {
+		opts.Type = DocumentDB
+	}
+	if opts.MaxIdle == 0 {
+		opts.MaxIdle = defaultMaxIdle
+	}
+	cli, err := Dial(addr, opts.Dial...)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pool{
+		cli: cli, opts: opts,
+		freed: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	p.open = func() (DBSession, error) {
+		return cli.openSess(db, opts.Type, user, pass)
+	}
+	// check credentials and keep the session for later use
+	conn, err := p.Acquire(context.Background())
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+	p.Release(conn)
+	if opts.IdleTimeout > 0 {
+		go p.reaper()
+	}
+	return p, nil
+}
+
+// signal wakes up all callers waiting for a session. Must be called with mu held.
+func (p *Pool) signal() {
+	close(p.freed)
+	p.freed = make(chan struct{})
+}
+
+// Acquire returns an idle session or opens a new one. If MaxOpen sessions are already in use,
+// it waits for one of them to be released or for ctx to be done.
+func (p *Pool) Acquire(ctx context.Context) (*PoolConn, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if n := len(p.idle); n > 0 {
+			c := p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			p.mu.Unlock()
+			if p.check(c) {
+				return c, nil
+			}
+			p.discard(c)
+			continue
+		}
+		if p.opts.MaxOpen <= 0 || p.numOpen < p.opts.MaxOpen {
+			p.numOpen++
+			p.mu.Unlock()
+			conn, err := p.open()
+			if err != nil {
+				p.mu.Lock()
+				p.numOpen--
+				p.signal()
+				p.mu.Unlock()
+				return nil, err
+			}
+			return &PoolConn{DBSession: conn}, nil
+		}
+		freed := p.freed
+		p.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// check tests if idle session can be handed out.
+func (p *Pool) check(c *PoolConn) bool {
+	if !c.Alive() {
+		return false
+	} else if p.opts.IdleTimeout > 0 && time.Since(c.lastUsed) > p.opts.IdleTimeout {
+		return false
+	} else if p.opts.HealthCheck {
+		if _, err := c.Size(); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// discard closes a session that was removed from the pool.
+func (p *Pool) discard(c *PoolConn) {
+	c.DBSession.Close()
+	p.mu.Lock()
+	p.numOpen--
+	p.signal()
+	p.mu.Unlock()
+}
+
+// Release returns session to the pool. Sessions with broken connections are closed.
+func (p *Pool) Release(c *PoolConn) {
+	if c == nil {
+		return
+	}
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.opts.MaxIdle || !c.Alive() {
+		p.mu.Unlock()
+		p.discard(c)
+		return
+	}
+	c.lastUsed = time.Now()
+	p.idle = append(p.idle, c)
+	p.signal()
+	p.mu.Unlock()
+}
+
+// reaper periodically closes sessions that were idle for longer than IdleTimeout.
+func (p *Pool) reaper() {
+	t := time.NewTicker(p.opts.IdleTimeout / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-p.done:
+			return
+		}
+		var expired []*PoolConn
+		p.mu.Lock()
+		idle := p.idle[:0]
+		for _, c := range p.idle {
+			if time.Since(c.lastUsed) > p.opts.IdleTimeout {
+				expired = append(expired, c)
+			} else {
+				idle = append(idle, c)
+			}
+		}
+		p.idle = idle
+		p.mu.Unlock()
+		for _, c := range expired {
+			p.discard(c)
+		}
+	}
+}
+
+// Stats returns the number of opened and idle sessions.
+func (p *Pool) Stats() (open, idle int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.numOpen, len(p.idle)
+}
+
+// Close closes all idle sessions and server connection. Sessions in use are closed when released.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	close(p.done)
+	p.signal()
+	p.mu.Unlock()
+	for _, c := range idle {
+		p.discard(c)
+	}
+	if p.cli == nil {
+		return nil
+	}
+	return p.cli.Close()
+}
+
+// QueryAll executes SQL queries in parallel using pooled sessions, running at most concurrency queries
+// at once. Zero or negative concurrency means no limit. Results are returned in the order of queries;
+// a failed query does not stop others, and it's error is returned by Err method of it's results.
+//
+// If ctx is done before all queries were started, remaining queries are not executed, their results
+// hold ctx.Err(), and the same error is returned.
+func (p *Pool) QueryAll(ctx context.Context, queries []string, concurrency int) ([]Results, error) {
+	if concurrency <= 0 || concurrency > len(queries) {
+		concurrency = len(queries)
+	}
+	out := make([]Results, len(queries))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				out[i] = p.query(ctx, queries[i])
+			}
+		}()
+	}
+	var err error
+	for i := range queries {
+		if err = ctx.Err(); err == nil {
+			select {
+			case next <- i:
+				continue
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+		for ; i < len(queries); i++ {
+			out[i] = &errorResult{err: err}
+		}
+		break
+	}
+	close(next)
+	wg.Wait()
+	return out, err
+}
+
+// query executes a single query using a session from the pool.
+func (p *Pool) query(ctx context.Context, sql string) Results {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return &errorResult{err: err}
+	}
+	defer p.Release(c)
+	return c.Command(NewSQLQuery(sql))
+}
@@ -0,0 +1,195 @@
+package orient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSession struct {
+	DBSession
+	broken bool
+	closed bool
+}
+
+func (s *fakeSession) SetTimeout(d time.Duration) {}
+func (s *fakeSession) Alive() bool                { return !s.broken && !s.closed }
+func (s *fakeSession) Close() error               { s.closed = true; return nil }
+func (s *fakeSession) Size() (int64, error) {
+	if s.broken {
+		return 0, fmt.Errorf("broken")
+	}
+	return 0, nil
+}
+
+func newTestPool(opts PoolOptions) (*Pool, *[]*fakeSession) {
+	var opened []*fakeSession
+	if opts.MaxIdle == 0 {
+		opts.MaxIdle = defaultMaxIdle
+	}
+	p := &Pool{
+		opts:  opts,
+		freed: make(chan struct{}),
+		done:  make(chan struct{}),
+		open: func() (DBSession, error) {
+			s := &fakeSession{}
+			opened = append(opened, s)
+			return s, nil
+		},
+	}
+	return p, &opened
+}
+
+func TestPoolReuse(t *testing.T) {
+	p, opened := newTestPool(PoolOptions{MaxOpen: 2})
+	defer p.Close()
+	ctx := context.Background()
+	a, err := p.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Release(a)
+	b, err := p.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if b != a {
+		t.Fatal("idle session was not reused")
+	} else if len(*opened) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(*opened))
+	}
+	p.Release(b)
+}
+
+func TestPoolMaxOpen(t *testing.T) {
+	p, _ := newTestPool(PoolOptions{MaxOpen: 1})
+	defer p.Close()
+	a, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = p.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected timeout, got: %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.Release(a)
+	}()
+	b, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if b != a {
+		t.Fatal("released session was not reused")
+	}
+	p.Release(b)
+}
+
+func TestPoolDiscardBroken(t *testing.T) {
+	p, opened := newTestPool(PoolOptions{HealthCheck: true})
+	defer p.Close()
+	ctx := context.Background()
+	a, _ := p.Acquire(ctx)
+	a.DBSession.(*fakeSession).broken = true
+	p.Release(a)
+	if open, idle := p.Stats(); open != 0 || idle != 0 {
+		t.Fatalf("broken session was kept: open=%d, idle=%d", open, idle)
+	} else if !(*opened)[0].closed {
+		t.Fatal("broken session was not closed")
+	}
+
+	b, _ := p.Acquire(ctx)
+	p.Release(b)
+	// break the connection while session is idle; health check should detect it
+	b.DBSession.(*fakeSession).broken = true
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if c == b {
+		t.Fatal("broken idle session was handed out")
+	}
+	p.Release(c)
+}
+
+func TestPoolIdleReaping(t *testing.T) {
+	p, opened := newTestPool(PoolOptions{IdleTimeout: 20 * time.Millisecond})
+	go p.reaper()
+	defer p.Close()
+	a, _ := p.Acquire(context.Background())
+	p.Release(a)
+	time.Sleep(100 * time.Millisecond)
+	if open, idle := p.Stats(); open != 0 || idle != 0 {
+		t.Fatalf("idle session was not reaped: open=%d, idle=%d", open, idle)
+	} else if !(*opened)[0].closed {
+		t.Fatal("reaped session was not closed")
+	}
+}
+
+// queryCounter tracks the number of queries running at once in all sessions.
+type queryCounter struct {
+	mu           sync.Mutex
+	running, max int
+}
+
+type querySession struct {
+	fakeSession
+	cnt *queryCounter
+}
+
+func (s *querySession) Command(cmd CustomSerializable) (interface{}, error) {
+	c := s.cnt
+	c.mu.Lock()
+	c.running++
+	if c.running > c.max {
+		c.max = c.running
+	}
+	c.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	text := cmd.(OCommandRequestText).GetText()
+	if text == "bad" {
+		return nil, fmt.Errorf("bad query")
+	}
+	return []OIdentifiable{NewEmptyDocument().SetField("q", text)}, nil
+}
+
+func TestPoolQueryAll(t *testing.T) {
+	cnt := &queryCounter{}
+	p, _ := newTestPool(PoolOptions{MaxIdle: 10})
+	p.open = func() (DBSession, error) {
+		return &querySession{cnt: cnt}, nil
+	}
+	defer p.Close()
+	queries := []string{"a", "b", "bad", "c", "d", "e"}
+	res, err := p.QueryAll(context.Background(), queries, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res) != len(queries) {
+		t.Fatalf("expected %d results, got %d", len(queries), len(res))
+	} else if cnt.max > 2 {
+		t.Fatalf("concurrency limit exceeded: %d", cnt.max)
+	}
+	for i, r := range res {
+		if queries[i] == "bad" {
+			if r.Err() == nil {
+				t.Fatal("expected an error")
+			}
+			continue
+		}
+		var doc *Document
+		if err := r.All(&doc); err != nil {
+			t.Fatal(err)
+		} else if v := doc.GetField("q").Value; v != queries[i] {
+			t.Fatalf("wrong order: %v vs %v", v, queries[i])
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = p.QueryAll(ctx, queries, 1); err != context.Canceled {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+}
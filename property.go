@@ -17,46 +17,64 @@ type OProperty struct {
 	Regexp       string
 	CustomFields map[string]string
 	Readonly     bool
+	LinkedClass  string // class of linked records for LINK* properties
+	LinkedType   OType  // type of items for EMBEDDED* properties, UNKNOWN if not set
 }
 
 // NewOPropertyFromDocument creates a new OProperty from an Document
 // that was created after a load schema call to the OrientDB server.
+// Fields of unexpected types are logged and skipped.
 func NewOPropertyFromDocument(doc *Document) *OProperty {
-	oprop := &OProperty{}
-	if fld := doc.GetField("globalId"); fld != nil && fld.Value != nil {
-		oprop.Id = fld.Value.(int32)
-	}
-	if fld := doc.GetField("name"); fld != nil && fld.Value != nil {
-		oprop.Name = fld.Value.(string)
-	}
-	if fld := doc.GetField("type"); fld != nil && fld.Value != nil {
-		oprop.Type = byte(fld.Value.(int32))
-	}
-	if fld := doc.GetField("notNull"); fld != nil && fld.Value != nil {
-		oprop.NotNull = fld.Value.(bool)
-	}
-	if fld := doc.GetField("collate"); fld != nil && fld.Value != nil {
-		oprop.Collate = fld.Value.(string)
-	}
-	if fld := doc.GetField("mandatory"); fld != nil && fld.Value != nil {
-		oprop.Mandatory = fld.Value.(bool)
-	}
-	if fld := doc.GetField("min"); fld != nil && fld.Value != nil {
-		oprop.Min = fld.Value.(string)
-	}
-	if fld := doc.GetField("max"); fld != nil && fld.Value != nil {
-		oprop.Max = fld.Value.(string)
-	}
-	if fld := doc.GetField("regexp"); fld != nil && fld.Value != nil {
-		oprop.Regexp = fld.Value.(string)
-	}
+	oprop := &OProperty{LinkedType: UNKNOWN}
+	schemaField(doc, "globalId", &oprop.Id)
+	schemaField(doc, "name", &oprop.Name)
+	var typ int32
+	schemaField(doc, "type", &typ)
+	oprop.Type = byte(typ)
+	schemaField(doc, "notNull", &oprop.NotNull)
+	schemaField(doc, "collate", &oprop.Collate)
+	schemaField(doc, "mandatory", &oprop.Mandatory)
+	schemaField(doc, "min", &oprop.Min)
+	schemaField(doc, "max", &oprop.Max)
+	schemaField(doc, "regexp", &oprop.Regexp)
 	if fld := doc.GetField("customFields"); fld != nil && fld.Value != nil {
-		oprop.CustomFields = make(map[string]string)
-		panic("customFields handling NOT IMPLEMENTED: Don't know what data structure is coming back from the server (need example)")
-	}
-	if fld := doc.GetField("readonly"); fld != nil && fld.Value != nil {
-		oprop.Readonly = fld.Value.(bool)
+		if m, ok := fld.Value.(map[string]string); ok {
+			oprop.CustomFields = m
+		} else {
+			Logf("unknown type for customFields: %T", fld.Value)
+			oprop.CustomFields = make(map[string]string)
+		}
 	}
+	schemaField(doc, "readonly", &oprop.Readonly)
+	schemaField(doc, "linkedClass", &oprop.LinkedClass)
+	linkedType := int32(UNKNOWN)
+	schemaField(doc, "linkedType", &linkedType)
+	oprop.LinkedType = OType(linkedType)
 
 	return oprop
 }
+
+// ToDocument converts property to a document in the format of schema record, as read by NewOPropertyFromDocument.
+func (p *OProperty) ToDocument() *Document {
+	doc := NewEmptyDocument()
+	doc.SetField("name", p.Name)
+	doc.SetFieldWithType("type", int32(p.Type), INTEGER)
+	doc.SetFieldWithType("globalId", p.Id, INTEGER)
+	doc.SetField("mandatory", p.Mandatory)
+	doc.SetField("readonly", p.Readonly)
+	doc.SetField("notNull", p.NotNull)
+	for _, f := range []struct{ name, val string }{
+		{"min", p.Min}, {"max", p.Max}, {"regexp", p.Regexp}, {"collate", p.Collate}, {"linkedClass", p.LinkedClass},
+	} {
+		if f.val != "" {
+			doc.SetField(f.name, f.val)
+		}
+	}
+	if p.LinkedType != UNKNOWN {
+		doc.SetFieldWithType("linkedType", int32(p.LinkedType), INTEGER)
+	}
+	if len(p.CustomFields) != 0 {
+		doc.SetFieldWithType("customFields", p.CustomFields, EMBEDDEDMAP)
+	}
+	return doc
+}
@@ -1,5 +1,7 @@
 package orient
 
+import "time"
+
 // Default protocols
 const (
 	ProtoBinary = "binary"
@@ -25,11 +27,11 @@ const (
 )
 
 var (
-	protos = make(map[string]func(addr string) (DBConnection, error))
+	protos = make(map[string]func(addr string, opts DialOptions) (DBConnection, error))
 )
 
 // RegisterProto registers a new protocol for Dial command
-func RegisterProto(name string, dial func(addr string) (DBConnection, error)) {
+func RegisterProto(name string, dial func(addr string, opts DialOptions) (DBConnection, error)) {
 	protos[name] = dial
 }
 
@@ -46,28 +48,53 @@ type DBAdmin interface {
 	CreateDatabase(name string, dbType DatabaseType, storageType StorageType) error
 	DropDatabase(name string, storageType StorageType) error
 	ListDatabases() (map[string]string, error)
+	// ProtocolVersion returns a version of binary protocol used by the server.
+	ProtocolVersion() (int, error)
 	Close() error
 }
 
 // DBSession is a minimal interface for database API implementation
 type DBSession interface {
 	Close() error
+	// Alive reports if underlying connection is still usable. It must not make any requests to the server.
+	Alive() bool
+	// SetTimeout sets a timeout for each subsequent request in this session. Zero means no timeout.
+	SetTimeout(d time.Duration)
 	Size() (int64, error)
 	ReloadSchema() error
 	GetCurDB() *ODatabase
+	// ProtocolVersion returns a protocol version negotiated with the server: the highest version
+	// supported both by the server and the driver.
+	ProtocolVersion() int16
+	// SessionToken returns a token of token-based session, or nil if session is bound to the connection.
+	SessionToken() []byte
 
 	AddClusterWithID(clusterName string, id int16) (clusterID int16, err error)
 	DropCluster(clusterName string) (err error)
 	GetClusterDataRange(clusterName string) (begin, end int64, err error)
 	ClustersCount(withDeleted bool, clusterNames ...string) (int64, error)
+	Clusters() ([]ClusterInfo, error)
 
 	CreateRecord(rec ORecord) (err error)
 	DeleteRecordByRID(rid RID, recVersion int) error
 	GetRecordByRID(rid RID, fetchPlan FetchPlan, ignoreCache bool) (rec ORecord, err error)
+	// LoadRecord is the same as GetRecordByRID, but also returns related records pre-fetched according to the fetch plan.
+	LoadRecord(rid RID, fetchPlan FetchPlan, ignoreCache bool) (rec ORecord, related []ORecord, err error)
 	UpdateRecord(rec ORecord) error
 	CountRecords() (int64, error)
+	Commit(txID int, entries []TxEntry) error
 
+	// Command executes a command. Implementation may return FetchedResult if related records were sent with the result.
 	Command(cmd CustomSerializable) (result interface{}, err error)
+	LiveQuery(cmd CustomSerializable) (token int, ch <-chan LiveResult, err error)
+	Unsubscribe(token int) error
+}
+
+// FetchedResult is a command result with related records sent by the server, like records fetched
+// according to the fetch plan or projections of subqueries (LET variables) that are linked by temporary RIDs.
+type FetchedResult struct {
+	Result  interface{}
+	Related []ORecord
 }
 
 // DBConnection is a minimal interface for OrientDB server API implementation
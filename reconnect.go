@@ -0,0 +1,158 @@
+package orient
+
+import (
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ReconnectPolicy controls how Database handles broken connections.
+//
+// Broken connections are always discarded and replaced with new ones on the next call. Additionally,
+// idempotent requests (SELECT queries, record reads and commands marked with Idempotent(true)) that failed
+// due to a broken connection are retried up to MaxRetries times, waiting Backoff before the first retry
+// and doubling the delay each time, up to MaxBackoff. Non-idempotent requests are never retried,
+// since they may have been applied by the server.
+type ReconnectPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultReconnectPolicy is used by databases opened with Client.Open.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxRetries: 3,
+	Backoff:    100 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+}
+
+// delay returns a time to wait before retry number n (starting from 0).
+func (p ReconnectPolicy) delay(n int) time.Duration {
+	d := p.Backoff
+	for i := 0; i < n && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// SetReconnectPolicy sets a policy for retrying requests on broken connections. Zero policy disables retries.
+func (db *Database) SetReconnectPolicy(p ReconnectPolicy) {
+	db.mu.Lock()
+	db.reconnect = p
+	db.mu.Unlock()
+}
+
+func (db *Database) reconnectPolicy() ReconnectPolicy {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.reconnect
+}
+
+// isBrokenConn checks if request error means that connection can no longer be used.
+func isBrokenConn(conn DBSession, err error) bool {
+	if err == nil {
+		return false
+	} else if !conn.Alive() {
+		return true
+	}
+	switch err.(type) {
+	case net.Error, *net.OpError:
+		return true
+	}
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// withConn runs a request on a pooled connection with configured timeout. Broken connections are discarded,
+// and idempotent requests are retried on a new connection according to reconnect policy.
+func (db *Database) withConn(idempotent bool, fnc func(conn DBSession) error) error {
+	policy, timeout := db.reconnectPolicy(), db.commandTimeout()
+	for i := 0; ; i++ {
+		conn, err := db.pool.getConn()
+		if err != nil {
+			return err
+		}
+		conn.SetTimeout(timeout)
+		if err = db.syncSchema(conn); err == nil {
+			err = fnc(conn)
+		}
+		if !isBrokenConn(conn, err) {
+			db.pool.putConn(conn)
+			return err
+		}
+		db.pool.discard(conn)
+		if _, ok := err.(ErrCommandTimeout); ok { // slow requests are likely to time out again
+			return err
+		} else if !idempotent || i >= policy.MaxRetries {
+			return err
+		}
+		time.Sleep(policy.delay(i))
+	}
+}
+
+// idempotency is an explicit mark of a command, set with Idempotent method of the command.
+type idempotency byte
+
+const (
+	idempotencyUnset idempotency = iota
+	idempotencyYes
+	idempotencyNo
+)
+
+func markIdempotent(v bool) idempotency {
+	if v {
+		return idempotencyYes
+	}
+	return idempotencyNo
+}
+
+// idempotencyMarker is implemented by commands that can be marked as idempotent or not.
+type idempotencyMarker interface {
+	idempotency() idempotency
+}
+
+// isIdempotent checks if command can be safely sent to the server more than once. Commands marked explicitly
+// with their Idempotent method are classified according to the mark. Otherwise, SQL queries are idempotent,
+// SQL commands are classified with IsIdempotentSQL, and other commands are not idempotent.
+func isIdempotent(cmd OCommandRequestText) bool {
+	if m, ok := cmd.(idempotencyMarker); ok {
+		switch m.idempotency() {
+		case idempotencyYes:
+			return true
+		case idempotencyNo:
+			return false
+		}
+	}
+	switch c := cmd.(type) {
+	case SQLQuery:
+		return true
+	case SQLCommand:
+		return IsIdempotentSQL(c.GetText())
+	}
+	return false
+}
+
+var idempotentSQLPrefixes = []string{"SELECT", "TRAVERSE", "MATCH"}
+
+// IsIdempotentSQL heuristically checks if SQL statement only reads data, so it can be retried safely.
+// Statements that start with SELECT, TRAVERSE or MATCH are considered idempotent. Note that SELECT
+// statements that call server-side functions may still modify data; mark such commands explicitly
+// with Idempotent(false).
+func IsIdempotentSQL(sql string) bool {
+	sql = strings.TrimSpace(sql)
+	for _, p := range idempotentSQLPrefixes {
+		if len(sql) < len(p) || !strings.EqualFold(sql[:len(p)], p) {
+			continue
+		} else if len(sql) == len(p) {
+			return true
+		}
+		switch sql[len(p)] {
+		case ' ', '\t', '\r', '\n', '(', '{':
+			return true
+		}
+	}
+	return false
+}
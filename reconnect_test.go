@@ -0,0 +1,121 @@
+package orient
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// flakySession fails the first command with EOF, as if server has closed the connection.
+type flakySession struct {
+	fakeSession
+	calls *int
+}
+
+func (s *flakySession) Command(cmd CustomSerializable) (interface{}, error) {
+	*s.calls++
+	if *s.calls == 1 {
+		s.broken = true
+		return nil, io.EOF
+	}
+	return int32(1), nil
+}
+
+func newFlakyDB() (*Database, *int, *int) {
+	var dials, calls int
+	db := &Database{
+		pool: newConnPool(2, func() (DBSession, error) {
+			dials++
+			return &flakySession{calls: &calls}, nil
+		}),
+		reconnect: ReconnectPolicy{MaxRetries: 2, Backoff: time.Millisecond},
+	}
+	return db, &dials, &calls
+}
+
+func TestReconnectRetryQuery(t *testing.T) {
+	db, dials, calls := newFlakyDB()
+	var n int
+	if err := db.Command(NewSQLQuery("SELECT 1")).All(&n); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("wrong result: %v", n)
+	} else if *dials != 2 || *calls != 2 {
+		t.Fatalf("expected reconnect: dials=%d, calls=%d", *dials, *calls)
+	}
+}
+
+func TestReconnectNoRetryCommand(t *testing.T) {
+	db, dials, calls := newFlakyDB()
+	if err := db.Command(NewSQLCommand("INSERT INTO V SET a = 1")).Err(); err != io.EOF {
+		t.Fatalf("expected EOF, got: %v", err)
+	} else if *calls != 1 {
+		t.Fatalf("non-idempotent command was retried: calls=%d", *calls)
+	}
+	// broken connection must not be reused
+	if err := db.Command(NewSQLCommand("INSERT INTO V SET a = 1")).Err(); err != nil {
+		t.Fatal(err)
+	} else if *dials != 2 {
+		t.Fatalf("expected new connection: dials=%d", *dials)
+	}
+}
+
+func TestReconnectIdempotentMark(t *testing.T) {
+	db, _, calls := newFlakyDB()
+	if err := db.Command(NewSQLCommand("INSERT INTO Log SET a = 1 RETURN BEFORE").Idempotent(true)).Err(); err != nil {
+		t.Fatal(err)
+	} else if *calls != 2 {
+		t.Fatalf("marked command was not retried: calls=%d", *calls)
+	}
+	db, _, calls = newFlakyDB()
+	if err := db.Command(NewSQLQuery("SELECT logEvent()").Idempotent(false)).Err(); err != io.EOF {
+		t.Fatalf("expected EOF, got: %v", err)
+	} else if *calls != 1 {
+		t.Fatalf("marked query was retried: calls=%d", *calls)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	cases := []struct {
+		cmd OCommandRequestText
+		exp bool
+	}{
+		{NewSQLQuery("INSERT INTO V SET a = 1"), true},
+		{NewSQLCommand("SELECT FROM V"), true},
+		{NewSQLCommand("  select\nFROM V"), true},
+		{NewSQLCommand("TRAVERSE out() FROM #9:1"), true},
+		{NewSQLCommand("MATCH {class: V, as: v} RETURN v"), true},
+		{NewSQLCommand("SELECTED"), false},
+		{NewSQLCommand("UPDATE V SET a = 1"), false},
+		{NewSQLCommand("SELECT FROM V").Idempotent(false), false},
+		{NewSQLCommand("DELETE VERTEX V").Idempotent(true), true},
+		{NewScriptCommand(LangJS, "db.query('SELECT FROM V')"), false},
+		{NewScriptCommand(LangJS, "db.query('SELECT FROM V')").Idempotent(true), true},
+		{NewFunctionCommand("f"), false},
+		{NewGremlinCommand("g.V").Idempotent(true), true},
+	}
+	for _, c := range cases {
+		if v := isIdempotent(c.cmd); v != c.exp {
+			t.Errorf("%T(%q): expected %v, got %v", c.cmd, c.cmd.GetText(), c.exp, v)
+		}
+	}
+}
+
+func TestReconnectDisabled(t *testing.T) {
+	db, _, calls := newFlakyDB()
+	db.SetReconnectPolicy(ReconnectPolicy{})
+	if err := db.Command(NewSQLQuery("SELECT 1")).Err(); err != io.EOF {
+		t.Fatalf("expected EOF, got: %v", err)
+	} else if *calls != 1 {
+		t.Fatalf("query was retried: calls=%d", *calls)
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	p := ReconnectPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for i, exp := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if d := p.delay(i); d != exp*time.Millisecond {
+			t.Errorf("wrong delay for retry %d: %v", i, d)
+		}
+	}
+}
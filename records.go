@@ -2,6 +2,7 @@ package orient
 
 import (
 	"fmt"
+	"strings"
 )
 
 type ORecord interface {
@@ -16,6 +17,7 @@ type ORecord interface {
 
 var (
 	_ ORecord = (*BytesRecord)(nil)
+	_ ORecord = (*FlatRecord)(nil)
 	_ ORecord = (*Document)(nil)
 )
 
@@ -28,14 +30,24 @@ const (
 
 func init() {
 	declareRecordType(RecordTypeDocument, "document", func() ORecord { return NewEmptyDocument() })
-	//declareRecordType(RecordTypeFlat,"flat",func() orient.ORecord { panic("flat record type is not implemented") }) // TODO: implement
+	declareRecordType(RecordTypeFlat, "flat", func() ORecord { return NewFlatRecord() })
 	declareRecordType(RecordTypeBytes, "bytes", func() ORecord { return NewBytesRecord() })
 }
 
 // RecordType defines a registered record type
 type RecordType byte
 
-var recordFactories = make(map[RecordType]RecordFactory)
+func (tp RecordType) String() string {
+	if name, ok := recordTypeNames[tp]; ok {
+		return name
+	}
+	return fmt.Sprintf("RecordType(%q)", byte(tp))
+}
+
+var (
+	recordFactories = make(map[RecordType]RecordFactory)
+	recordTypeNames = make(map[RecordType]string)
+)
 
 // RecordFactory is a function to create records of certain type
 type RecordFactory func() ORecord
@@ -45,6 +57,7 @@ func declareRecordType(tp RecordType, name string, fnc RecordFactory) {
 		panic(fmt.Errorf("record type byte '%v' already in use", tp))
 	}
 	recordFactories[tp] = fnc
+	recordTypeNames[tp] = name
 }
 
 // GetRecordFactory returns RecordFactory for a given type
@@ -114,3 +127,78 @@ func (r *BytesRecord) Fill(rid RID, version int, content []byte) error {
 func (r BytesRecord) String() string {
 	return fmt.Sprintf("Bytes{RID: %s, Vers: %d, Data: [%d]}", r.RID, r.Vers, len(r.Data))
 }
+
+func NewFlatRecord() *FlatRecord { return &FlatRecord{} }
+
+// FlatRecord is a schema less record that holds a single string value.
+type FlatRecord struct {
+	RID  RID
+	Vers int
+	Data string
+}
+
+func (r FlatRecord) Content() ([]byte, error) {
+	return []byte(r.Data), nil
+}
+
+func (r FlatRecord) Version() int {
+	return r.Vers
+}
+func (r *FlatRecord) SetVersion(v int) {
+	r.Vers = v
+}
+func (r *FlatRecord) SetRID(rid RID) {
+	r.RID = rid
+}
+
+func (r FlatRecord) RecordType() RecordType {
+	return RecordTypeFlat
+}
+
+// GetIdentity returns a record RID
+func (r FlatRecord) GetIdentity() RID {
+	return r.RID
+}
+
+// GetRecord returns a record data
+func (r FlatRecord) GetRecord() interface{} {
+	return r.Data
+}
+
+// Fill sets identity, version and string data of the record
+func (r *FlatRecord) Fill(rid RID, version int, content []byte) error {
+	r.RID = rid
+	r.Vers = version
+	r.Data = string(content)
+	return nil
+}
+
+// Parse decodes content of the record as a list of fields in "name:value,name:value" form, optionally prefixed
+// with "Class@", which is used by legacy records. Strings, numbers, booleans and links are parsed,
+// other values (like embedded documents and collections) are stored as strings with their raw text.
+func (r FlatRecord) Parse() (*Document, error) {
+	var f StringRecordFormatAbs
+	class, fields, err := splitFlatFields(r.Data)
+	if err != nil {
+		return nil, err
+	}
+	doc := NewDocument(class)
+	doc.RID, doc.Vers = r.RID, r.Vers
+	for _, fld := range fields {
+		i := strings.IndexRune(fld, string_ENTRY_SEPARATOR)
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid field in flat record: %q", fld)
+		}
+		name := strings.TrimSpace(fld[:i])
+		val, tp, err := f.flatValue(fld[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse field %s of flat record: %v", name, err)
+		}
+		doc.SetFieldWithType(name, val, tp)
+	}
+	return doc, nil
+}
+
+func (r FlatRecord) String() string {
+	return fmt.Sprintf("Flat{RID: %s, Vers: %d, Data: %q}", r.RID, r.Vers, r.Data)
+}
@@ -0,0 +1,62 @@
+package orient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatRecordParse(t *testing.T) {
+	rec := NewRecordOfType(RecordTypeFlat).(*FlatRecord)
+	if err := rec.Fill(NewRID(0, 1), 3, []byte(`Config@name:"a, \"b\"",size:12,big:3000000000,ratio:1.5,on:true,next:#5:2,tags:[1,2],none:`)); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := rec.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ClassName() != "Config" || doc.RID != NewRID(0, 1) || doc.Vers != 3 {
+		t.Fatalf("wrong document metadata: %v", doc)
+	}
+	expect := map[string]interface{}{
+		"name": `a, "b"`, "size": int32(12), "big": int64(3000000000), "ratio": float32(1.5),
+		"on": true, "next": NewRID(5, 2), "tags": "[1,2]", "none": nil,
+	}
+	got := make(map[string]interface{})
+	for name, fld := range doc.Fields() {
+		got[name] = fld.Value
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("wrong fields:\n%#v\nvs\n%#v", got, expect)
+	}
+
+	if doc, err = (FlatRecord{Data: "plain text"}).Parse(); err == nil {
+		t.Fatalf("expected an error, got: %v", doc)
+	} else if _, err = (FlatRecord{Data: `name:"open`}).Parse(); err == nil {
+		t.Fatal("expected an error for unterminated string")
+	}
+}
+
+func TestFlatRecordParseNumbers(t *testing.T) {
+	for data, exp := range map[string]interface{}{
+		"n:-5":          int32(-5),
+		"n:-3000000000": int64(-3000000000),
+		"n:12l":         int64(12),
+		"n:-7s":         int16(-7),
+		"n:100b":        byte(100),
+		"n:-1b":         byte(255),
+		"n:-1.5":        float32(-1.5),
+		"n:2.5d":        2.5,
+	} {
+		doc, err := (FlatRecord{Data: data}).Parse()
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		} else if v := doc.GetField("n").Value; v != exp {
+			t.Fatalf("%s: expected %v (%T), got %v (%T)", data, exp, exp, v, v)
+		}
+	}
+	for _, data := range []string{"n:99999999999999999999", "n:300b", "n:40000s", "n:1.5e50f"} {
+		if doc, err := (FlatRecord{Data: data}).Parse(); err == nil {
+			t.Fatalf("%s: expected an error, got: %v", data, doc.GetField("n"))
+		}
+	}
+}
@@ -10,13 +10,30 @@ import (
 	"strings"
 )
 
+// OIdentifiable is implemented by values that identify a record: links and the records themselves.
+// Result consumers can use it to get an identity of a result element, regardless of whether it is a link
+// or a full document.
+//
+// It is implemented by:
+//
+//		RID          - GetRecord returns nil
+//		*Document    - GetRecord returns the document itself
+//		BytesRecord  - GetRecord returns raw record content
+//		*LazyLink    - GetRecord returns linked document if it was loaded, or nil otherwise
+//
+// Results decoding uses GetIdentity to convert any of these types into RID or string destinations.
 type OIdentifiable interface {
+	// GetIdentity returns a RID of the record.
 	GetIdentity() RID
+	// GetRecord returns record content, if it is available.
 	GetRecord() interface{}
 }
 
 var (
 	_ OIdentifiable = RID{}
+	_ OIdentifiable = (*Document)(nil)
+	_ OIdentifiable = BytesRecord{}
+	_ OIdentifiable = (*LazyLink)(nil)
 )
 
 const (
@@ -0,0 +1,238 @@
+package orient
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// schemaRID is a default RID of schema record.
+var schemaRID = RID{ClusterID: 0, ClusterPos: 1}
+
+// Schema is a registry of database classes.
+type Schema struct {
+	Version int
+	Classes map[string]*OClass // by class name
+}
+
+// Class returns a class with a given name, or nil if it is not defined. Class names are case-insensitive.
+func (s *Schema) Class(name string) *OClass {
+	if s == nil {
+		return nil
+	}
+	if c, ok := s.Classes[name]; ok {
+		return c
+	}
+	for cname, c := range s.Classes {
+		if strings.EqualFold(cname, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// NewSchemaFromDocument creates a schema from the schema record (#0:1). Super classes are resolved,
+// so OClass.Super can be used to navigate classes hierarchy.
+func NewSchemaFromDocument(doc *Document) (*Schema, error) {
+	s := &Schema{Classes: make(map[string]*OClass)}
+	if fld := doc.GetField("schemaVersion"); fld != nil && fld.Value != nil {
+		if v, ok := fld.Value.(int32); ok {
+			s.Version = int(v)
+		}
+	}
+	fld := doc.GetField("classes")
+	if fld == nil || fld.Value == nil {
+		return s, nil
+	}
+	classes, ok := fld.Value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for schema classes: %T", fld.Value)
+	}
+	for _, c := range classes {
+		cdoc, ok := c.(*Document)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type for schema class: %T", c)
+		}
+		oclass := NewOClassFromDocument(cdoc)
+		s.Classes[oclass.Name] = oclass
+	}
+	for _, c := range s.Classes {
+		if c.SuperClass != "" {
+			c.Super = s.Class(c.SuperClass)
+		}
+	}
+	return s, nil
+}
+
+// LoadSchema reads the schema record and returns all database classes.
+func (db *Database) LoadSchema() (*Schema, error) {
+	rec, err := db.GetRecordByRID(schemaRID, "", true)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := rec.(*Document)
+	if !ok {
+		return nil, fmt.Errorf("expected document record for schema, got %T", rec)
+	}
+	return NewSchemaFromDocument(doc)
+}
+
+// ClassOptions are optional settings for CreateClass.
+type ClassOptions struct {
+	Extends      string // super class name
+	Abstract     bool   // abstract classes have no clusters and cannot have records
+	ClusterCount int    // number of clusters to create for the class, server default is used if zero
+}
+
+// reSQLName matches names of classes, sequences and functions that are safe to put into SQL text as is.
+var reSQLName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isSQLName checks a name of class, property, sequence or function before it's used in SQL text.
+func isSQLName(name string) bool {
+	return reSQLName.MatchString(name)
+}
+
+// createClassSQL builds CREATE CLASS command.
+func createClassSQL(name string, opts ClassOptions) (string, error) {
+	if !isSQLName(name) {
+		return "", fmt.Errorf("invalid class name: %q", name)
+	} else if opts.Extends != "" && !isSQLName(opts.Extends) {
+		return "", fmt.Errorf("invalid super class name: %q", opts.Extends)
+	} else if opts.ClusterCount < 0 {
+		return "", fmt.Errorf("invalid clusters count: %d", opts.ClusterCount)
+	}
+	sql := `CREATE CLASS ` + name
+	if opts.Extends != "" {
+		sql += ` EXTENDS ` + opts.Extends
+	}
+	if opts.ClusterCount > 0 {
+		sql += ` CLUSTERS ` + strconv.Itoa(opts.ClusterCount)
+	}
+	if opts.Abstract {
+		sql += ` ABSTRACT`
+	}
+	return sql, nil
+}
+
+// isClassExists checks if server error was caused by an attempt to create a class that already exists.
+func isClassExists(err error) bool {
+	e, ok := err.(ServerError)
+	return ok && e.IsClass("OSchemaException") && serverErrorContains(err, "already exists")
+}
+
+// CreateClass creates a new class and returns it's definition loaded from the schema. Super classes of
+// returned class are resolved. If class with the same name already exists, ErrClassExists is returned.
+func (db *Database) CreateClass(name string, opts ClassOptions) (*OClass, error) {
+	sql, err := createClassSQL(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Command(NewSQLCommand(sql)).Err(); isClassExists(err) {
+		return nil, ErrClassExists{Name: name}
+	} else if err != nil {
+		return nil, err
+	}
+	db.InvalidateSchema() // in case DDL detector was changed
+	s, err := db.LoadSchema()
+	if err != nil {
+		return nil, err
+	}
+	class := s.Class(name)
+	if class == nil {
+		return nil, fmt.Errorf("class %s was created, but not found in schema", name)
+	}
+	return class, nil
+}
+
+// DDLDetector checks if a command changes database schema. See SetDDLDetector.
+type DDLDetector func(cmd OCommandRequestText) bool
+
+// SetDDLDetector sets a function that is used by Command to detect schema changes. Schema metadata cached
+// by database sessions is invalidated after each successful command it reports (see InvalidateSchema).
+// Nil restores the default detector, which checks SQL commands with IsDDLSQL.
+func (db *Database) SetDDLDetector(fnc DDLDetector) {
+	db.mu.Lock()
+	db.ddl = fnc
+	db.mu.Unlock()
+}
+
+func (db *Database) ddlDetector() DDLDetector {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.ddl == nil {
+		return isDDL
+	}
+	return db.ddl
+}
+
+func isDDL(cmd OCommandRequestText) bool {
+	switch cmd.(type) {
+	case SQLCommand:
+		return IsDDLSQL(cmd.GetText())
+	case ScriptCommand:
+		// batch scripts may contain any statements
+		for _, st := range strings.FieldsFunc(cmd.GetText(), func(r rune) bool { return r == ';' || r == '\n' }) {
+			if IsDDLSQL(st) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsDDLSQL heuristically checks if SQL statement changes database schema. Statements that create, alter
+// or drop classes or properties are considered DDL.
+func IsDDLSQL(sql string) bool {
+	f := strings.Fields(sql)
+	if len(f) < 2 {
+		return false
+	}
+	switch strings.ToUpper(f[0]) {
+	case "CREATE", "ALTER", "DROP":
+	default:
+		return false
+	}
+	switch strings.ToUpper(f[1]) {
+	case "CLASS", "PROPERTY":
+		return true
+	}
+	return false
+}
+
+// InvalidateSchema marks schema metadata cached by database sessions as stale, so each session reloads it
+// before the next request. It's called by Command after DDL statements, and should be called manually
+// if schema is changed by other means, for example by another client.
+func (db *Database) InvalidateSchema() {
+	db.schemamu.Lock()
+	db.schemaGen++
+	db.schemamu.Unlock()
+}
+
+// syncSchema reloads schema of a session if it was invalidated after the last reload.
+func (db *Database) syncSchema(conn DBSession) error {
+	db.schemamu.Lock()
+	gen := db.schemaGen
+	seen := db.schemaSeen[conn]
+	db.schemamu.Unlock()
+	if gen == seen {
+		return nil
+	}
+	if err := conn.ReloadSchema(); err != nil {
+		return err
+	}
+	db.schemamu.Lock()
+	if db.schemaSeen == nil {
+		db.schemaSeen = make(map[DBSession]uint64)
+	}
+	db.schemaSeen[conn] = gen
+	db.schemamu.Unlock()
+	return nil
+}
+
+// forgetSchema removes a closed session from schema tracking.
+func (db *Database) forgetSchema(conn DBSession) {
+	db.schemamu.Lock()
+	delete(db.schemaSeen, conn)
+	db.schemamu.Unlock()
+}
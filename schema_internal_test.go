@@ -0,0 +1,87 @@
+package orient
+
+import "testing"
+
+type schemaSession struct {
+	cmdSession
+	reloads int
+}
+
+func (s *schemaSession) ReloadSchema() error {
+	s.reloads++
+	return nil
+}
+
+func TestInvalidateSchemaOnDDL(t *testing.T) {
+	sess := &schemaSession{cmdSession: cmdSession{result: []OIdentifiable{}}}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	Nil := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	Nil(db.Command(NewSQLCommand("CREATE VERTEX V SET name = 'a'")).Err())
+	Nil(db.Command(NewSQLCommand("CREATE PROPERTY V.name STRING")).Err())
+	if sess.reloads != 0 {
+		t.Fatalf("schema reloaded too early: %d", sess.reloads)
+	}
+	Nil(db.Command(NewSQLQuery("SELECT FROM V")).Err())
+	Nil(db.Command(NewSQLQuery("SELECT FROM V")).Err())
+	if sess.reloads != 1 {
+		t.Fatalf("expected a single schema reload, got: %d", sess.reloads)
+	}
+	db.SetDDLDetector(func(OCommandRequestText) bool { return false })
+	Nil(db.Command(NewSQLCommand("ALTER CLASS V STRICTMODE true")).Err())
+	Nil(db.Command(NewSQLQuery("SELECT FROM V")).Err())
+	if sess.reloads != 1 {
+		t.Fatalf("unexpected schema reload: %d", sess.reloads)
+	}
+	db.InvalidateSchema()
+	Nil(db.Command(NewSQLQuery("SELECT FROM V")).Err())
+	if sess.reloads != 2 {
+		t.Fatalf("expected schema reload after invalidation, got: %d", sess.reloads)
+	}
+}
+
+func TestIsDDLSQL(t *testing.T) {
+	for sql, exp := range map[string]bool{
+		"CREATE CLASS Cat EXTENDS V":     true,
+		"  alter property Cat.age LONG":  true,
+		"DROP CLASS Cat":                 true,
+		"CREATE VERTEX Cat SET name='a'": false,
+		"DROP INDEX Cat.name":            false,
+		"SELECT FROM Cat":                false,
+		"CREATE":                         false,
+	} {
+		if IsDDLSQL(sql) != exp {
+			t.Errorf("unexpected result for %q", sql)
+		}
+	}
+	if !isDDL(NewScriptCommand(LangSQL, "begin;\nCREATE PROPERTY Cat.age LONG;\ncommit")) {
+		t.Error("DDL in script was not detected")
+	}
+}
+
+func TestForgetClosedSessions(t *testing.T) {
+	sess := &schemaSession{cmdSession: cmdSession{result: []OIdentifiable{}}}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	db.pool.onClose = db.forgetSchema
+	db.InvalidateSchema()
+	if err := db.Command(NewSQLQuery("SELECT FROM V")).Err(); err != nil {
+		t.Fatal(err)
+	} else if len(db.schemaSeen) != 1 {
+		t.Fatalf("session is not tracked: %v", db.schemaSeen)
+	}
+	// session returned to a full pool is closed
+	conn, err := db.pool.getConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.pool.ch <- &fakeSession{}
+	db.pool.putConn(conn)
+	if !sess.closed {
+		t.Fatal("session was not closed")
+	} else if len(db.schemaSeen) != 0 {
+		t.Fatalf("closed session is still tracked: %v", db.schemaSeen)
+	}
+}
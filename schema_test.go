@@ -0,0 +1,140 @@
+package orient_test
+
+import (
+	"gopkg.in/istreamdata/orientgo.v2"
+	"reflect"
+	"testing"
+)
+
+func schemaClass(name, super string) *orient.Document {
+	doc := orient.NewEmptyDocument()
+	doc.SetField("name", name)
+	if super != "" {
+		doc.SetField("superClass", super)
+	}
+	return doc
+}
+
+func TestSchemaFromDocument(t *testing.T) {
+	doc := orient.NewEmptyDocument()
+	doc.SetField("schemaVersion", int32(4))
+	doc.SetFieldWithType("classes", []interface{}{
+		schemaClass("V", ""),
+		schemaClass("Animal", "V"),
+		schemaClass("Cat", "Animal"),
+	}, orient.EMBEDDEDSET)
+
+	s, err := orient.NewSchemaFromDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	} else if s.Version != 4 || len(s.Classes) != 3 {
+		t.Fatalf("wrong schema: %+v", s)
+	}
+	cat := s.Class("cat")
+	if cat == nil || cat.Name != "Cat" {
+		t.Fatalf("class not found: %+v", cat)
+	} else if cat.Super != s.Class("Animal") || cat.Super.Super != s.Class("V") {
+		t.Fatal("super classes are not resolved")
+	} else if !cat.IsSubClassOf("V") || cat.IsSubClassOf("E") {
+		t.Fatal("wrong class hierarchy")
+	}
+	if s.Class("E") != nil {
+		t.Fatal("unexpected class")
+	}
+}
+
+func TestClassFromDocumentUnexpectedTypes(t *testing.T) {
+	prop := orient.NewEmptyDocument()
+	prop.SetField("name", "age")
+	prop.SetField("type", int16(orient.INTEGER))
+	prop.SetField("mandatory", "yes")
+	doc := schemaClass("Cat", "")
+	doc.SetField("shortName", int32(1))
+	doc.SetField("defaultClusterId", int64(9))
+	doc.SetField("clusterIds", []interface{}{int64(9), int16(10)})
+	doc.SetField("abstract", "false")
+	doc.SetFieldWithType("properties", []interface{}{prop, "broken"}, orient.EMBEDDEDLIST)
+
+	c := orient.NewOClassFromDocument(doc)
+	if c.Name != "Cat" || c.ShortName != "" || c.AbstractClass {
+		t.Fatalf("wrong class: %+v", c)
+	} else if c.DefaultClusterId != 9 || !reflect.DeepEqual(c.ClusterIds, []int32{9, 10}) {
+		t.Fatalf("wrong clusters: %+v", c)
+	} else if len(c.Properties) != 1 || c.Properties["age"] == nil {
+		t.Fatalf("only invalid property should be skipped: %+v", c.Properties)
+	}
+
+	p := orient.NewOPropertyFromDocument(prop)
+	if p.Name != "age" || p.Type != byte(orient.INTEGER) || p.Mandatory {
+		t.Fatalf("wrong property: %+v", p)
+	}
+}
+
+func TestClassValidate(t *testing.T) {
+	animal := &orient.OClass{Name: "Animal", Properties: map[string]*orient.OProperty{
+		"name": {Name: "name", Mandatory: true, NotNull: true, Min: "2", Regexp: "[A-Z][a-z]+"},
+	}}
+	cat := &orient.OClass{Name: "Cat", Super: animal, StrictMode: true, Properties: map[string]*orient.OProperty{
+		"age": {Name: "age", Min: "0", Max: "30"},
+	}}
+	for i, c := range []struct {
+		fields map[string]interface{}
+		field  string
+	}{
+		{map[string]interface{}{"name": "Linus", "age": int32(15)}, ""},
+		{map[string]interface{}{"age": int32(15)}, "Cat.name"},
+		{map[string]interface{}{"name": nil}, "Cat.name"},
+		{map[string]interface{}{"name": "L"}, "Cat.name"},
+		{map[string]interface{}{"name": "linus"}, "Cat.name"},
+		{map[string]interface{}{"name": "Linus", "age": int32(31)}, "Cat.age"},
+		{map[string]interface{}{"name": "Linus", "color": "red"}, "Cat.color"},
+	} {
+		doc := orient.NewDocument("Cat")
+		for k, v := range c.fields {
+			if v == nil {
+				doc.SetFieldWithType(k, v, orient.STRING)
+			} else {
+				doc.SetField(k, v)
+			}
+		}
+		err := cat.Validate(doc)
+		if c.field == "" {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if e, ok := err.(orient.ErrValidation); !ok {
+			t.Fatalf("case %d: expected validation error, got: %v", i, err)
+		} else if e.Field != c.field {
+			t.Fatalf("case %d: wrong field: %v", i, e)
+		}
+	}
+}
+
+func TestClassToDocument(t *testing.T) {
+	class := &orient.OClass{
+		Name:             "Cat",
+		ShortName:        "C",
+		SuperClass:       "Animal",
+		DefaultClusterId: 9,
+		ClusterIds:       []int32{9, 10},
+		ClusterSelection: "round-robin",
+		OverSize:         1.5,
+		StrictMode:       true,
+		CustomFields:     map[string]string{"owner": "me"},
+		Properties: map[string]*orient.OProperty{
+			"name": {Id: 3, Name: "name", Type: byte(orient.STRING), Mandatory: true, NotNull: true, Min: "2", Regexp: "[A-Z].*"},
+			"age":  {Id: 4, Name: "age", Type: byte(orient.INTEGER), Max: "30", CustomFields: map[string]string{"unit": "year"}},
+		},
+	}
+	out := orient.NewOClassFromDocument(class.ToDocument())
+	if !reflect.DeepEqual(out, class) {
+		t.Fatalf("class changed after round-trip:\n%+v\n%+v", out, class)
+	}
+	for name, prop := range class.Properties {
+		if p := out.Properties[name]; !reflect.DeepEqual(p, prop) {
+			t.Fatalf("property changed after round-trip:\n%+v\n%+v", p, prop)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package orient
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder builds parameterized SELECT queries. It only builds the query text and collects
+// parameters, use Query to get a command for Database.Command. Example:
+//
+//		q, err := orient.Select("name", "age").From("Person").Where("age > ?", 18).OrderBy("name").Limit(50).Query()
+//		if err != nil {
+//			return err
+//		}
+//		err = db.Command(q).All(&people)
+//
+// Field and class names are escaped, while conditions are inserted as is and should use
+// parameter placeholders for values. Names with backticks or backslashes cannot be escaped safely;
+// they are left out of the query and Query returns an error for them.
+type SelectBuilder struct {
+	fields  []string
+	from    string
+	where   []string
+	params  []interface{}
+	groupBy []string
+	orderBy []string
+	skip    int
+	limit   int
+	plan    FetchPlan
+	err     error // first invalid name
+}
+
+// Select starts a new SELECT query with given projections. If no fields are set, whole records are returned.
+func Select(fields ...string) *SelectBuilder {
+	b := &SelectBuilder{limit: -1}
+	for _, f := range fields {
+		if f = b.quote(f); f != "" {
+			b.fields = append(b.fields, f)
+		}
+	}
+	return b
+}
+
+// Expr adds a projection expression, like "count(*)" or "out('Friend').name AS friends". It is not escaped.
+func (b *SelectBuilder) Expr(expr string) *SelectBuilder {
+	b.fields = append(b.fields, expr)
+	return b
+}
+
+// From sets a class to select records from.
+func (b *SelectBuilder) From(class string) *SelectBuilder {
+	b.from = b.quote(class)
+	return b
+}
+
+// Where adds a condition with parameters bound to '?' placeholders. Multiple conditions are joined with AND.
+func (b *SelectBuilder) Where(cond string, params ...interface{}) *SelectBuilder {
+	b.where = append(b.where, cond)
+	b.params = append(b.params, params...)
+	return b
+}
+
+// GroupBy sets fields to group records by.
+func (b *SelectBuilder) GroupBy(fields ...string) *SelectBuilder {
+	for _, f := range fields {
+		if f = b.quote(f); f != "" {
+			b.groupBy = append(b.groupBy, f)
+		}
+	}
+	return b
+}
+
+// OrderBy adds fields to sort records by. Each field can have an " ASC" or " DESC" suffix.
+func (b *SelectBuilder) OrderBy(fields ...string) *SelectBuilder {
+	for _, f := range fields {
+		dir := ""
+		if i := strings.LastIndex(f, " "); i > 0 {
+			if d := strings.ToUpper(strings.TrimSpace(f[i+1:])); d == "ASC" || d == "DESC" {
+				f, dir = strings.TrimSpace(f[:i]), " "+d
+			}
+		}
+		if f = b.quote(f); f != "" {
+			b.orderBy = append(b.orderBy, f+dir)
+		}
+	}
+	return b
+}
+
+// Skip sets a number of records to skip.
+func (b *SelectBuilder) Skip(n int) *SelectBuilder {
+	b.skip = n
+	return b
+}
+
+// Limit sets a maximal number of records to return. Negative value means no limit.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// FetchPlan sets a query fetch plan.
+func (b *SelectBuilder) FetchPlan(plan FetchPlan) *SelectBuilder {
+	b.plan = plan
+	return b
+}
+
+// String returns query text.
+func (b *SelectBuilder) String() string {
+	sql := "SELECT"
+	if len(b.fields) != 0 {
+		sql += " " + strings.Join(b.fields, ", ")
+	}
+	if b.from != "" {
+		sql += " FROM " + b.from
+	}
+	if len(b.where) == 1 {
+		sql += " WHERE " + b.where[0]
+	} else if len(b.where) > 1 {
+		sql += " WHERE (" + strings.Join(b.where, ") AND (") + ")"
+	}
+	if len(b.groupBy) != 0 {
+		sql += " GROUP BY " + strings.Join(b.groupBy, ", ")
+	}
+	if len(b.orderBy) != 0 {
+		sql += " ORDER BY " + strings.Join(b.orderBy, ", ")
+	}
+	if b.skip > 0 {
+		sql += " SKIP " + strconv.Itoa(b.skip)
+	}
+	if b.limit >= 0 {
+		sql += " LIMIT " + strconv.Itoa(b.limit)
+	}
+	return sql
+}
+
+// Params returns parameters collected from conditions.
+func (b *SelectBuilder) Params() []interface{} {
+	return append([]interface{}(nil), b.params...)
+}
+
+// Err returns an error for the first field or class name that cannot be escaped.
+func (b *SelectBuilder) Err() error {
+	return b.err
+}
+
+// Query returns a query command that can be executed with Database.Command.
+func (b *SelectBuilder) Query() (SQLQuery, error) {
+	if b.err != nil {
+		return SQLQuery{}, b.err
+	}
+	return NewSQLQuery(b.String(), b.Params()...).FetchPlan(b.plan), nil
+}
+
+// quote escapes a name, or remembers an error and returns an empty string if the name is invalid.
+func (b *SelectBuilder) quote(name string) string {
+	q, err := quoteIdent(name)
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	return q
+}
+
+var reIdent = regexp.MustCompile(`^@?[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// quoteIdent escapes field or class name with backticks, unless it's a plain identifier or a field path.
+// Names with backticks or backslashes are rejected, since they can end the quoted name.
+func quoteIdent(name string) (string, error) {
+	if name == "*" || reIdent.MatchString(name) {
+		return name, nil
+	} else if name == "" || strings.ContainsAny(name, "`\\") {
+		return "", fmt.Errorf("invalid identifier: %q", name)
+	}
+	return "`" + name + "`", nil
+}
@@ -0,0 +1,74 @@
+package orient_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/istreamdata/orientgo.v2"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	cases := []struct {
+		b      *orient.SelectBuilder
+		sql    string
+		params string
+	}{
+		{
+			b:   orient.Select().From("V"),
+			sql: "SELECT FROM V",
+		},
+		{
+			b:      orient.Select("name", "age").From("Person").Where("age > ?", 18).OrderBy("name").Limit(50),
+			sql:    "SELECT name, age FROM Person WHERE age > ? ORDER BY name LIMIT 50",
+			params: "[18]",
+		},
+		{
+			b: orient.Select("city").Expr("count(*) AS n").From("Person").
+				Where("age > ?", 18).Where("name <> ?", "bob").
+				GroupBy("city").OrderBy("n desc").Skip(10).Limit(5),
+			sql:    "SELECT city, count(*) AS n FROM Person WHERE (age > ?) AND (name <> ?) GROUP BY city ORDER BY n DESC SKIP 10 LIMIT 5",
+			params: "[18 bob]",
+		},
+		{
+			b:   orient.Select("@rid", "address.city", "first name").From("My Class"),
+			sql: "SELECT @rid, address.city, `first name` FROM `My Class`",
+		},
+	}
+	for _, c := range cases {
+		if sql := c.b.String(); sql != c.sql {
+			t.Errorf("wrong sql:\n%s\nexpected:\n%s", sql, c.sql)
+		}
+		params := ""
+		if p := c.b.Params(); len(p) != 0 {
+			params = fmt.Sprint(p)
+		}
+		if params != c.params {
+			t.Errorf("wrong params for %q: %s", c.sql, params)
+		}
+		if q, err := c.b.Query(); err != nil {
+			t.Error(err)
+		} else if q.GetText() != c.sql {
+			t.Errorf("wrong query text: %s", q.GetText())
+		}
+	}
+}
+
+func TestSelectBuilderInvalidNames(t *testing.T) {
+	for _, b := range []*orient.SelectBuilder{
+		orient.Select("a`b").From("V"),
+		orient.Select("name").From("V` WHERE 1=1 --"),
+		orient.Select("name").From("V").GroupBy("a\\"),
+		orient.Select("name").From("V").OrderBy("x\\` DESC"),
+		orient.Select().From(""),
+	} {
+		if _, err := b.Query(); err == nil {
+			t.Errorf("expected error for %q", b.String())
+		} else if b.Err() != err {
+			t.Errorf("expected Err to return %v, got %v", err, b.Err())
+		}
+		if sql := b.String(); strings.ContainsAny(sql, "`\\") {
+			t.Errorf("invalid name is not removed from query: %s", sql)
+		}
+	}
+}
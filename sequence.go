@@ -0,0 +1,92 @@
+package orient
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Sequence types for CreateSequence
+const (
+	SequenceOrdered = "ORDERED"
+	SequenceCached  = "CACHED"
+)
+
+// SequenceOptions are optional settings for CreateSequence.
+type SequenceOptions struct {
+	Type      string // SequenceOrdered or SequenceCached, server default is used if empty
+	Start     int64  // initial value
+	Increment int64  // server default is used if zero
+	Cache     int    // number of values to cache, only valid for cached sequences
+}
+
+func checkSequenceName(name string) error {
+	if !isSQLName(name) {
+		return fmt.Errorf("invalid sequence name: %q", name)
+	}
+	return nil
+}
+
+// createSequenceSQL builds CREATE SEQUENCE command.
+func createSequenceSQL(name string, opts SequenceOptions) (string, error) {
+	if err := checkSequenceName(name); err != nil {
+		return "", err
+	}
+	switch opts.Type {
+	case "", SequenceOrdered, SequenceCached:
+	default:
+		return "", fmt.Errorf("invalid sequence type: %q", opts.Type)
+	}
+	if opts.Cache < 0 {
+		return "", fmt.Errorf("invalid sequence cache size: %d", opts.Cache)
+	} else if opts.Cache > 0 && opts.Type != SequenceCached {
+		return "", fmt.Errorf("cache size is only valid for cached sequences")
+	}
+	sql := `CREATE SEQUENCE ` + name
+	if opts.Type != "" {
+		sql += ` TYPE ` + opts.Type
+	}
+	if opts.Start != 0 {
+		sql += ` START ` + strconv.FormatInt(opts.Start, 10)
+	}
+	if opts.Increment != 0 {
+		sql += ` INCREMENT ` + strconv.FormatInt(opts.Increment, 10)
+	}
+	if opts.Cache > 0 {
+		sql += ` CACHE ` + strconv.Itoa(opts.Cache)
+	}
+	return sql, nil
+}
+
+// CreateSequence creates a server-side sequence with a given name.
+func (db *Database) CreateSequence(name string, opts SequenceOptions) error {
+	sql, err := createSequenceSQL(name, opts)
+	if err != nil {
+		return err
+	}
+	return db.Command(NewSQLCommand(sql)).Err()
+}
+
+// DropSequence removes a sequence with a given name.
+func (db *Database) DropSequence(name string) error {
+	if err := checkSequenceName(name); err != nil {
+		return err
+	}
+	return db.Command(NewSQLCommand(`DROP SEQUENCE ` + name)).Err()
+}
+
+// SequenceNext increments a sequence and returns it's new value.
+func (db *Database) SequenceNext(name string) (int64, error) {
+	if err := checkSequenceName(name); err != nil {
+		return 0, err
+	}
+	// not safe to retry - sequence may be incremented twice
+	return db.Command(NewSQLCommand(`SELECT sequence('` + name + `').next()`).Idempotent(false)).Count()
+}
+
+// SequenceCurrent returns current value of a sequence without changing it.
+func (db *Database) SequenceCurrent(name string) (int64, error) {
+	if err := checkSequenceName(name); err != nil {
+		return 0, err
+	}
+	return db.Command(NewSQLQuery(`SELECT sequence('` + name + `').current()`)).Count()
+}
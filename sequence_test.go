@@ -0,0 +1,47 @@
+package orient
+
+import "testing"
+
+func TestCreateSequenceSQL(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts SequenceOptions
+		sql  string
+	}{
+		{"ids", SequenceOptions{}, `CREATE SEQUENCE ids`},
+		{"ids", SequenceOptions{Type: SequenceOrdered, Start: 100, Increment: 5}, `CREATE SEQUENCE ids TYPE ORDERED START 100 INCREMENT 5`},
+		{"ids", SequenceOptions{Type: SequenceCached, Cache: 20}, `CREATE SEQUENCE ids TYPE CACHED CACHE 20`},
+		{"ids", SequenceOptions{Type: "RANDOM"}, ""},
+		{"ids", SequenceOptions{Type: SequenceOrdered, Cache: 20}, ""},
+		{"ids'); DROP CLASS V", SequenceOptions{}, ""},
+		{"", SequenceOptions{}, ""},
+		{"ids#1", SequenceOptions{}, ""},
+		{"ids{1}", SequenceOptions{}, ""},
+		{"$ids", SequenceOptions{}, ""},
+		{"my-ids", SequenceOptions{}, ""},
+	} {
+		sql, err := createSequenceSQL(c.name, c.opts)
+		if c.sql == "" {
+			if err == nil {
+				t.Fatalf("expected an error for %+v, got: %s", c, sql)
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		} else if sql != c.sql {
+			t.Fatalf("wrong sql:\n%s\nexpected:\n%s", sql, c.sql)
+		}
+	}
+}
+
+func TestSequenceNext(t *testing.T) {
+	sess := &cmdSession{result: []OIdentifiable{NewEmptyDocument().SetField("sequence", int64(42))}}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	if v, err := db.SequenceNext("ids"); err != nil {
+		t.Fatal(err)
+	} else if v != 42 {
+		t.Fatalf("unexpected value: %d", v)
+	}
+	if _, err := db.SequenceCurrent("bad name"); err == nil {
+		t.Fatal("expected an error for invalid name")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
 	"io"
+	"sync"
 )
 
 // ErrTypeSerialization represent serialization/deserialization error
@@ -30,7 +31,8 @@ type Classer interface {
 }
 
 var (
-	recordFormats = map[string]func() RecordSerializer{
+	recordFormatsMu sync.RWMutex
+	recordFormats   = map[string]func() RecordSerializer{
 		binaryFormatName: func() RecordSerializer { return &BinaryRecordFormat{} },
 	}
 	recordFormatDefault = binaryFormatName
@@ -46,9 +48,20 @@ type Deserializable interface {
 	FromStream(r io.Reader) error
 }
 
-// GlobalPropertyFunc is a function for getting global properties by id
+// GlobalPropertyFunc is a function for getting global properties by id. Implementations are expected
+// to reload schema when an unknown id is requested, since properties may be added mid-session.
 type GlobalPropertyFunc func(id int) (OGlobalProperty, bool)
 
+// ClassPropertyFunc is a function for getting a property of a class from the database schema, including
+// properties inherited from super classes.
+type ClassPropertyFunc func(class, name string) (*OProperty, bool)
+
+// SchemaSerializer is implemented by record serializers that use class properties from the schema,
+// for example to get types of collection items.
+type SchemaSerializer interface {
+	SetClassPropertyFunc(fnc ClassPropertyFunc)
+}
+
 // RecordSerializer is an interface for serializing records to byte streams
 type RecordSerializer interface {
 	// String, in case of RecordSerializer must return it's class name, as it will be sent to server
@@ -62,26 +75,45 @@ type RecordSerializer interface {
 
 // RegisterRecordFormat registers RecordSerializer with a given class name
 func RegisterRecordFormat(name string, fnc func() RecordSerializer) {
+	recordFormatsMu.Lock()
 	recordFormats[name] = fnc
+	recordFormatsMu.Unlock()
 }
 
-// SetDefaultRecordFormat sets default record serializer
+// SetDefaultRecordFormat sets default record serializer for new connections. It does not affect
+// connections that are already open. Use WithRecordFormat to set record format for a single connection.
 func SetDefaultRecordFormat(name string) {
+	recordFormatsMu.Lock()
 	recordFormatDefault = name
+	recordFormatsMu.Unlock()
 }
 
-// GetRecordFormat returns record serializer by class name
-func GetRecordFormat(name string) RecordSerializer {
+// NewRecordSerializer returns a new record serializer by class name, or an error, if format is not registered.
+func NewRecordSerializer(name string) (RecordSerializer, error) {
+	recordFormatsMu.RLock()
 	f := recordFormats[name]
+	recordFormatsMu.RUnlock()
 	if f == nil {
-		panic(fmt.Errorf("unknown record format: %s", name))
+		return nil, fmt.Errorf("unknown record format: %s", name)
+	}
+	return f(), nil
+}
+
+// GetRecordFormat returns record serializer by class name. It panics if format is not registered.
+func GetRecordFormat(name string) RecordSerializer {
+	ser, err := NewRecordSerializer(name)
+	if err != nil {
+		panic(err)
 	}
-	return f()
+	return ser
 }
 
 // GetDefaultRecordSerializer returns default record serializer
 func GetDefaultRecordSerializer() RecordSerializer {
-	return GetRecordFormat(recordFormatDefault)
+	recordFormatsMu.RLock()
+	name := recordFormatDefault
+	recordFormatsMu.RUnlock()
+	return GetRecordFormat(name)
 }
 
 // DocumentSerializable is an interface for objects that can be converted to Document
@@ -89,7 +121,8 @@ type DocumentSerializable interface {
 	ToDocument() (*Document, error)
 }
 
-// DocumentDeserializable is an interface for objects that can be filled from Document
+// DocumentDeserializable is an interface for objects that can be filled from Document.
+// Results decoding calls FromDocument instead of reflection-based decoding for structs that implement it.
 type DocumentDeserializable interface {
 	FromDocument(*Document) error
 }
@@ -2,10 +2,12 @@ package orient
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"reflect"
 	"runtime"
+	"strconv"
 	"time"
 
 	"gopkg.in/istreamdata/orientgo.v2/obinary/rw"
@@ -39,17 +41,55 @@ type binaryRecordFormat interface {
 	Deserialize(doc *Document, r *rw.ReadSeeker) error
 
 	SetGlobalPropertyFunc(fnc GlobalPropertyFunc)
+	SetClassPropertyFunc(fnc ClassPropertyFunc)
+	SetSkipUnresolvedProperties(skip bool)
+	SetContext(ctx context.Context)
 }
 
+var _ SchemaSerializer = (*BinaryRecordFormat)(nil)
+
 type BinaryRecordFormat struct {
-	fnc GlobalPropertyFunc
+	fnc  GlobalPropertyFunc
+	cfnc ClassPropertyFunc
+
+	// SkipUnresolvedProperties allows to decode records that reference global properties unknown to the client
+	// (for example, during schema upgrades). Such fields are stored under "_prop_<id>" name as BINARY values
+	// with raw field data, instead of failing with ErrUnknownGlobalProperty. Raw data of a field spans up to
+	// the next field value, or up to the end of the record for the last field.
+	//
+	// It can be enabled for all connections by registering a custom format:
+	//
+	//		orient.RegisterRecordFormat("ORecordSerializerBinary", func() orient.RecordSerializer {
+	//			return &orient.BinaryRecordFormat{SkipUnresolvedProperties: true}
+	//		})
+	SkipUnresolvedProperties bool
+
+	// ZeroCopyStrings enables decoding of string values, field and class names without copying them from the
+	// record buffer, which reduces allocations for records with many string fields. Decoded strings keep
+	// the whole record buffer in memory, and the buffer passed to FromStream must not be modified after the call.
+	// The driver allocates a new buffer for each record it reads, so it's safe to enable for records loaded
+	// from the server. See rw.Reader.SetZeroCopy.
+	ZeroCopyStrings bool
 }
 
 func (BinaryRecordFormat) String() string { return binaryFormatName }
 func (f *BinaryRecordFormat) SetGlobalPropertyFunc(fnc GlobalPropertyFunc) {
 	f.fnc = fnc
 }
+func (f *BinaryRecordFormat) SetClassPropertyFunc(fnc ClassPropertyFunc) {
+	f.cfnc = fnc
+}
 func (f BinaryRecordFormat) ToStream(w io.Writer, rec ORecord) error {
+	return f.ToStreamContext(context.Background(), w, rec)
+}
+
+// ToStreamContext serializes a record the same way as ToStream, but stops with ctx.Err() if the context
+// is cancelled. Context is checked periodically while writing fields and items of collections, so
+// serialization of large records can be interrupted. Part of the record may be already written to w.
+func (f BinaryRecordFormat) ToStreamContext(ctx context.Context, w io.Writer, rec ORecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	doc, ok := rec.(*Document)
 	if !ok {
 		return ErrTypeSerialization{Val: rec, Serializer: f}
@@ -61,6 +101,8 @@ func (f BinaryRecordFormat) ToStream(w io.Writer, rec ORecord) error {
 	// TODO: apply partial serialization to prevent infinite recursion of records
 	ser := binaryFormatVerions[binaryFormatCurrentVersion]()
 	ser.SetGlobalPropertyFunc(f.fnc)
+	ser.SetClassPropertyFunc(f.cfnc)
+	ser.SetContext(ctx)
 	if err := bw.Err(); err != nil {
 		return err
 	}
@@ -75,8 +117,14 @@ func (f BinaryRecordFormat) FromStream(data []byte) (out ORecord, err error) {
 		return
 	}
 
-	r := bytes.NewReader(data)
-	br := rw.NewReadSeeker(r)
+	var br *rw.ReadSeeker
+	if f.ZeroCopyStrings {
+		br = rw.NewReadSeeker(rw.NewBytesReader(data))
+		br.SetZeroCopy(true)
+	} else {
+		br = rw.NewReadSeeker(bytes.NewReader(data))
+	}
+	br.SetLimit(len(data)) // no value can be larger than the record itself
 	vers := br.ReadByte()
 	if err = br.Err(); err != nil {
 		return
@@ -86,10 +134,13 @@ func (f BinaryRecordFormat) FromStream(data []byte) (out ORecord, err error) {
 
 	ser := binaryFormatVerions[vers]()
 	ser.SetGlobalPropertyFunc(f.fnc)
+	ser.SetClassPropertyFunc(f.cfnc)
+	ser.SetSkipUnresolvedProperties(f.SkipUnresolvedProperties)
 	doc := NewEmptyDocument()
 	if err = ser.Deserialize(doc, br); err != nil {
 		return
 	}
+	doc.raw = data
 	return doc, nil
 }
 
@@ -100,22 +151,57 @@ type globalProperty struct {
 
 type binaryRecordFormatV0 struct {
 	getGlobalPropertyFunc GlobalPropertyFunc
+	getClassPropertyFunc  ClassPropertyFunc
+	skipUnresolved        bool
+	ctx                   context.Context // checked during serialization, if set
 }
 
 func (f *binaryRecordFormatV0) SetGlobalPropertyFunc(fnc GlobalPropertyFunc) {
 	f.getGlobalPropertyFunc = fnc
 }
-func (f binaryRecordFormatV0) getGlobalProperty(doc *Document, leng int) OGlobalProperty {
+func (f *binaryRecordFormatV0) SetClassPropertyFunc(fnc ClassPropertyFunc) {
+	f.getClassPropertyFunc = fnc
+}
+func (f *binaryRecordFormatV0) SetSkipUnresolvedProperties(skip bool) {
+	f.skipUnresolved = skip
+}
+func (f *binaryRecordFormatV0) SetContext(ctx context.Context) {
+	f.ctx = ctx
+}
+
+// ctxCheckInterval is a number of fields or collection items written between context checks.
+const ctxCheckInterval = 64
+
+// checkContext returns context error for every ctxCheckInterval-th item written.
+func (f binaryRecordFormatV0) checkContext(i int) error {
+	if f.ctx == nil || i%ctxCheckInterval != 0 {
+		return nil
+	}
+	return f.ctx.Err()
+}
+
+// linkedType returns a type of collection items of a field from the schema, or UNKNOWN if it's not set.
+func (f binaryRecordFormatV0) linkedType(doc *Document, name string) OType {
+	if f.getClassPropertyFunc == nil || doc.ClassName() == "" {
+		return UNKNOWN
+	}
+	prop, ok := f.getClassPropertyFunc(doc.ClassName(), name)
+	if !ok || prop == nil || prop.LinkedType == ANY {
+		return UNKNOWN
+	}
+	return prop.LinkedType
+}
+func (f binaryRecordFormatV0) getGlobalProperty(doc *Document, leng int) (OGlobalProperty, error) {
 	id := (leng * -1) - 1
 
 	if f.getGlobalPropertyFunc == nil {
-		panic("can't read global properties")
+		return OGlobalProperty{}, fmt.Errorf("can't read global property %d: no global properties source", id)
 	}
 	prop, ok := f.getGlobalPropertyFunc(id)
 	if !ok {
-		panic("no global properties")
+		return OGlobalProperty{}, ErrUnknownGlobalProperty{ID: id}
 	}
-	return prop
+	return prop, nil
 }
 func (f binaryRecordFormatV0) Deserialize(doc *Document, r *rw.ReadSeeker) error {
 
@@ -128,10 +214,12 @@ func (f binaryRecordFormatV0) Deserialize(doc *Document, r *rw.ReadSeeker) error
 	}
 
 	var (
-		fieldName string
-		valuePos  int
-		valueType OType
-		last      int64
+		fieldName  string
+		valuePos   int
+		valueType  OType
+		last       int64
+		positions  []int // value positions of all fields, to determine bounds of unresolved fields
+		unresolved []unresolvedField
 	)
 	for {
 		//var prop core.OGlobalProperty
@@ -151,7 +239,20 @@ func (f binaryRecordFormatV0) Deserialize(doc *Document, r *rw.ReadSeeker) error
 			valueType = f.readOType(r)
 		} else {
 			// LOAD GLOBAL PROPERTY BY ID
-			prop := f.getGlobalProperty(doc, leng)
+			prop, err := f.getGlobalProperty(doc, leng)
+			if err != nil && f.skipUnresolved {
+				// properties from the schema always have a type, so it's not stored in the header
+				fieldName = unresolvedPropertyName(-leng - 1)
+				valuePos = int(f.readInteger(r))
+				positions = append(positions, valuePos)
+				if valuePos != 0 && !doc.RawContainsField(fieldName) {
+					unresolved = append(unresolved, unresolvedField{Name: fieldName, Pos: valuePos})
+					doc.RawSetField(fieldName, nil, BINARY) // raw data is read after the header
+				}
+				continue
+			} else if err != nil {
+				return err
+			}
 			fieldName = prop.Name
 			valuePos = int(f.readInteger(r))
 			if prop.Type != ANY {
@@ -161,6 +262,7 @@ func (f binaryRecordFormatV0) Deserialize(doc *Document, r *rw.ReadSeeker) error
 			}
 		}
 
+		positions = append(positions, valuePos)
 		if doc.RawContainsField(fieldName) {
 			continue
 		}
@@ -175,12 +277,21 @@ func (f binaryRecordFormatV0) Deserialize(doc *Document, r *rw.ReadSeeker) error
 				last = cur
 			}
 			r.Seek(headerCursor, 0)
+			if valueType == EMBEDDEDLIST || valueType == EMBEDDEDSET {
+				value = convertLinkedItems(value, f.linkedType(doc, fieldName))
+			}
 			doc.RawSetField(fieldName, value, valueType)
 		} else {
 			doc.RawSetField(fieldName, nil, UNKNOWN)
 		}
 	}
 
+	if len(unresolved) != 0 {
+		if err := f.readUnresolved(r, doc, unresolved, positions); err != nil {
+			return err
+		}
+	}
+
 	//doc.ClearSource()
 
 	if cur, _ := r.Seek(0, 1); last > cur {
@@ -188,6 +299,40 @@ func (f binaryRecordFormatV0) Deserialize(doc *Document, r *rw.ReadSeeker) error
 	}
 	return r.Err()
 }
+
+// unresolvedPropertyName returns a field name for values of a global property that cannot be resolved.
+func unresolvedPropertyName(id int) string {
+	return "_prop_" + strconv.Itoa(id)
+}
+
+type unresolvedField struct {
+	Name string
+	Pos  int
+}
+
+// readUnresolved sets raw data of unresolved fields. Data spans up to the next field value, or up to the end of the stream.
+func (f binaryRecordFormatV0) readUnresolved(r *rw.ReadSeeker, doc *Document, fields []unresolvedField, positions []int) error {
+	cur, _ := r.Seek(0, 1)
+	size, _ := r.Seek(0, 2)
+	for _, fld := range fields {
+		start := fld.Pos
+		end := int(size)
+		for _, p := range positions {
+			if p > start && p < end {
+				end = p
+			}
+		}
+		if start > end {
+			return io.ErrUnexpectedEOF
+		}
+		data := make([]byte, end-start)
+		r.Seek(int64(start), 0)
+		r.ReadRawBytes(data)
+		doc.RawSetField(fld.Name, data, BINARY)
+	}
+	r.Seek(cur, 0)
+	return r.Err()
+}
 func (f binaryRecordFormatV0) readByte(r *rw.ReadSeeker) byte {
 	return r.ReadByte()
 }
@@ -487,6 +632,7 @@ func (f binaryRecordFormatV0) Serialize(doc *Document, w io.Writer, off int, cla
 		Pos   int
 		Ptr   int
 		Field *DocEntry
+		Value interface{}
 		Type  OType
 	}
 
@@ -494,12 +640,17 @@ func (f binaryRecordFormatV0) Serialize(doc *Document, w io.Writer, off int, cla
 		items = make([]item, 0, len(fields))
 	)
 	for _, entry := range fields {
-		it := item{Field: entry}
+		it := item{Field: entry, Value: entry.Value}
 		// TODO: use global properties for serialization, if class is known
 		f.writeString(bw, entry.Name)
 		it.Pos = buf.Len() // save buffer offset of pointer
 		bw.WriteInt(0)     // placeholder for data pointer
 		tp := f.getFieldType(entry)
+		if v, vt, ok, err := toStoredValue(entry.Value); err != nil {
+			return fmt.Errorf("field '%s': %v", entry.Name, err)
+		} else if ok {
+			it.Value, tp = v, vt
+		}
 		if tp == UNKNOWN {
 			return fmt.Errorf("Can't serialize type %T with Document binary serializer", entry.Type)
 		}
@@ -509,11 +660,13 @@ func (f binaryRecordFormatV0) Serialize(doc *Document, w io.Writer, off int, cla
 	}
 	f.writeEmptyString(bw)
 	for i, it := range items {
-		if it.Field.Value == nil {
+		if err := f.checkContext(i); err != nil {
+			return err
+		} else if it.Value == nil {
 			continue
 		}
 		ptr := buf.Len()
-		if err := f.writeSingleValue(bw, off+ptr, it.Field.Value, it.Type, f.getLinkedType(doc, it.Type, it.Field.Name)); err != nil {
+		if err := f.writeSingleValue(bw, off+ptr, it.Value, it.Type, f.getLinkedType(doc, it.Type, it.Field.Name)); err != nil {
 			return err
 		}
 		if buf.Len() != ptr {
@@ -573,8 +726,10 @@ func (f binaryRecordFormatV0) writeLinkCollection(w *rw.Writer, o interface{}) e
 	switch col := o.(type) {
 	case []RID:
 		w.WriteVarint(int64(len(col)))
-		for _, rid := range col {
-			if rid == nilRID {
+		for i, rid := range col {
+			if err := f.checkContext(i); err != nil {
+				return err
+			} else if rid == nilRID {
 				f.writeNullLink(w)
 			} else {
 				if _, err := f.writeOptimizedLink(w, rid); err != nil {
@@ -584,8 +739,10 @@ func (f binaryRecordFormatV0) writeLinkCollection(w *rw.Writer, o interface{}) e
 		}
 	case []OIdentifiable:
 		w.WriteVarint(int64(len(col)))
-		for _, item := range col {
-			if item.GetIdentity() == nilRID {
+		for i, item := range col {
+			if err := f.checkContext(i); err != nil {
+				return err
+			} else if item == nil || item.GetIdentity() == nilRID {
 				f.writeNullLink(w)
 			} else {
 				if _, err := f.writeOptimizedLink(w, item); err != nil {
@@ -596,7 +753,12 @@ func (f binaryRecordFormatV0) writeLinkCollection(w *rw.Writer, o interface{}) e
 	case OIdentifiableCollection:
 		// TODO: assert (!(value instanceof OMVRBTreeRIDSet))
 		w.WriteVarint(int64(col.Len()))
+		i := 0
 		for item := range col.OIdentifiableIterator() {
+			if err := f.checkContext(i); err != nil {
+				return err
+			}
+			i++
 			if item == nil {
 				f.writeNullLink(w)
 			} else {
@@ -611,13 +773,32 @@ func (f binaryRecordFormatV0) writeLinkCollection(w *rw.Writer, o interface{}) e
 	return w.Err()
 }
 func (f binaryRecordFormatV0) writeLinkMap(w *rw.Writer, o interface{}) error {
-	m := o.(map[string]OIdentifiable) // TODO: can use reflect to support map[Stringer]OIdentifiable
-	w.WriteVarint(int64(len(m)))
-	for k, v := range m {
+	// any map with string keys and link values is accepted, like map[string]RID or map[string]*Document
+	mv := reflect.ValueOf(o)
+	if mv.Kind() != reflect.Map || mv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("only maps with string keys are supported as %v, got %T", LINKMAP, o)
+	}
+	w.WriteVarint(int64(mv.Len()))
+	for i, key := range mv.MapKeys() {
+		if err := f.checkContext(i); err != nil {
+			return err
+		}
+		val := mv.MapIndex(key)
+		if val.Kind() == reflect.Interface {
+			val = val.Elem()
+		}
+		var v OIdentifiable
+		if val.IsValid() && (val.Kind() != reflect.Ptr || !val.IsNil()) {
+			id, ok := val.Interface().(OIdentifiable)
+			if !ok {
+				return fmt.Errorf("expected a link in %v, got %T", LINKMAP, val.Interface())
+			}
+			v = id
+		}
 		// TODO @orient: check skip of complex types
 		// FIXME @orient: changed to support only string key on map
 		f.writeOType(w, STRING)
-		f.writeString(w, k)
+		f.writeString(w, key.String())
 		if v == nil {
 			f.writeNullLink(w)
 		} else {
@@ -657,9 +838,17 @@ func (f binaryRecordFormatV0) writeEmbeddedMap(w *rw.Writer, off int, o interfac
 		// FIXME @orient: changed to support only string key on map
 		f.writeOType(bw, STRING)
 		f.writeString(bw, fmt.Sprint(k)) // convert key to string
+		tp := ANY
+		sv, vt, custom, err := toStoredValue(v)
+		if err != nil {
+			return err
+		} else if custom {
+			v, tp = sv, vt
+		} else if v != nil { // ANY signals a null value
+			tp = f.getTypeFromValueEmbedded(v)
+		}
 		it := item{Pos: buf.Len(), Val: v}
 		bw.WriteInt(0) // ptr placeholder
-		tp := f.getTypeFromValueEmbedded(v)
 		if tp == UNKNOWN {
 			panic(ErrTypeSerialization{Val: v, Serializer: f})
 		}
@@ -669,6 +858,9 @@ func (f binaryRecordFormatV0) writeEmbeddedMap(w *rw.Writer, off int, o interfac
 	}
 
 	for i := range items {
+		if err := f.checkContext(i); err != nil {
+			return err
+		}
 		ptr := buf.Len()
 		if err := f.writeSingleValue(bw, off+ptr, items[i].Val, items[i].Type, UNKNOWN); err != nil {
 			return err
@@ -757,12 +949,18 @@ func (f binaryRecordFormatV0) writeSingleValue(w *rw.Writer, off int, o interfac
 		}
 		err = f.Serialize(edoc, w, off, false)
 	case EMBEDDEDSET, EMBEDDEDLIST:
+		if set, ok := o.(OrientSet); ok {
+			o = set.unique()
+		}
 		err = f.writeEmbeddedCollection(w, off, o, linkedType)
 	case DECIMAL:
 		f.writeDecimal(w, o)
 	case BINARY:
 		_, err = f.writeBinary(w, o.([]byte))
 	case LINKSET, LINKLIST:
+		if set, ok := o.(OrientSet); ok {
+			o = set.unique().oidentifiables()
+		}
 		err = f.writeLinkCollection(w, o)
 	case LINK:
 		_, err = f.writeOptimizedLink(w, o.(OIdentifiable))
@@ -798,15 +996,22 @@ func (f binaryRecordFormatV0) writeEmbeddedCollection(w *rw.Writer, off int, o i
 	// TODO @orient: manage embedded type from schema and auto-determined.
 	f.writeOType(bw, ANY)
 	for i := 0; i < mv.Len(); i++ {
+		if err := f.checkContext(i); err != nil {
+			return err
+		}
 		item := mv.Index(i).Interface()
 		// TODO @orient: manage in a better way null entry
 		if item == nil {
 			f.writeOType(bw, ANY)
 			continue
 		}
-		var tp OType = linkedType
-		if tp == UNKNOWN {
-			tp = f.getTypeFromValueEmbedded(item)
+		var tp OType
+		if v, vt, ok, err := toStoredValue(item); err != nil {
+			return err
+		} else if ok {
+			item, tp = v, vt
+		} else {
+			tp = linkedItemType(f.getTypeFromValueEmbedded(item), linkedType)
 		}
 		if tp != UNKNOWN {
 			f.writeOType(bw, tp)
@@ -823,12 +1028,45 @@ func (f binaryRecordFormatV0) writeEmbeddedCollection(w *rw.Writer, off int, o i
 	}
 	return w.WriteRawBytes(buf.Bytes())
 }
-func (binaryRecordFormatV0) getLinkedType(doc *Document, tp OType, key string) OType {
+func (f binaryRecordFormatV0) getLinkedType(doc *Document, tp OType, key string) OType {
 	if tp != EMBEDDEDLIST && tp != EMBEDDEDSET && tp != EMBEDDEDMAP {
 		return UNKNOWN
 	}
-	// TODO: OClass clazz = ODocumentInternal.getImmutableSchemaClass(document); if (clazz != null) ...
-	return UNKNOWN
+	return f.linkedType(doc, key)
+}
+
+// isNumberType checks if values of a type are integers or floats.
+func isNumberType(tp OType) bool {
+	return isNumberKind(tp.ReflectKind())
+}
+
+// linkedItemType returns a type to store a collection item with. Linked type of collection is used for numbers,
+// items of other types are stored with their own type, the same way as in schemaless mode.
+func linkedItemType(tp, linkedType OType) OType {
+	if linkedType != UNKNOWN && isNumberType(tp) && isNumberType(linkedType) {
+		return linkedType
+	}
+	return tp
+}
+
+// convertLinkedItems converts numeric items of a collection that was read from a record to a linked type
+// of collection, so items have the same type regardless of how they were stored.
+func convertLinkedItems(value interface{}, linkedType OType) interface{} {
+	items, ok := value.([]interface{})
+	if !ok || !isNumberType(linkedType) {
+		return value
+	}
+	typ := linkedType.GoType()
+	for i, item := range items {
+		v := reflect.ValueOf(item)
+		if !v.IsValid() || v.Type() == typ || !isNumberKind(v.Kind()) {
+			continue
+		}
+		if nv, err := convertNumber(v, typ); err == nil {
+			items[i] = nv.Interface()
+		}
+	}
+	return items
 }
 func (f binaryRecordFormatV0) getFieldType(fld *DocEntry) OType {
 	tp := fld.Type
@@ -910,6 +1148,8 @@ func toString(o interface{}) string {
 		return v
 	case []byte:
 		return string(v)
+	case Geometry:
+		return v.WKT()
 	default: // TODO: use Stringer interface in case of failure?
 		return reflect.ValueOf(o).Convert(reflect.TypeOf(string(""))).Interface().(string)
 	}
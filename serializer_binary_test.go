@@ -2,9 +2,12 @@ package orient
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"io/ioutil"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -32,6 +35,57 @@ func TestDeserializeRecordData(t *testing.T) {
 	}
 }
 
+func TestDeserializeRecordRawBytes(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(`AAASY2FyZXRha2VyAAAAJQcIbmFtZQAAAC0HBmFnZQAAADMBAA5NaWNoYWVsCkxpbnVzHg==`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := NewEmptyDocument()
+	rec.SetSerializer(&BinaryRecordFormat{})
+	rec.Fill(NewRID(9, 1), 1, data)
+	rec.SetField("name", "Keiko") // decodes the record
+	if _, err = rec.Content(); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(rec.RawBytes(), data) {
+		t.Fatalf("raw bytes changed: %v", rec.RawBytes())
+	}
+	out, err := BinaryRecordFormat{}.FromStream(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(out.(*Document).RawBytes(), data) {
+		t.Fatal("raw bytes are not set by FromStream")
+	}
+	if NewEmptyDocument().RawBytes() != nil {
+		t.Fatal("new document has raw bytes")
+	}
+}
+
+func TestDeserializeGlobalProperty(t *testing.T) {
+	// version 0, no class, field with global property id 0 at position 8, end of header, "bob"
+	data := []byte{0, 0, 1, 0, 0, 0, 8, 0, 6, 'b', 'o', 'b'}
+	f := &BinaryRecordFormat{}
+	f.SetGlobalPropertyFunc(func(id int) (OGlobalProperty, bool) {
+		if id != 0 {
+			return OGlobalProperty{}, false
+		}
+		return OGlobalProperty{Id: 0, Name: "name", Type: STRING}, true
+	})
+	out, err := f.FromStream(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if fld := out.(*Document).GetField("name"); fld == nil || fld.Value != "bob" {
+		t.Fatalf("wrong field: %v", fld)
+	}
+	f.SetGlobalPropertyFunc(func(id int) (OGlobalProperty, bool) {
+		return OGlobalProperty{}, false
+	})
+	if _, err = f.FromStream(data); err == nil {
+		t.Fatal("expected an error for unknown property")
+	} else if e, ok := err.(ErrUnknownGlobalProperty); !ok || e.ID != 0 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func testBase64Compare(t *testing.T, out []byte, origBase64 string) {
 	orig, _ := base64.StdEncoding.DecodeString(origBase64)
 	if bytes.Compare(out, orig) != 0 {
@@ -57,6 +111,112 @@ func TestSerializeCommandIntParam(t *testing.T) {
 	testBase64Compare(t, buf.Bytes(), "AAAAGlNFTEVDVCBGUk9NIFYgV0hFUkUgSWQgPSA/AQAAAB0AABRwYXJhbWV0ZXJzAAAAEwwAAgcCMAAAABwBMgA=")
 }
 
+func readCommandParams(t *testing.T, data []byte) map[string]interface{} {
+	br := rw.NewReader(bytes.NewReader(data))
+	br.ReadString() // command text
+	if !br.ReadBool() {
+		t.Fatal("simple params are absent")
+	}
+	sparams := br.ReadBytes()
+	if err := br.Err(); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := GetDefaultRecordSerializer().FromStream(sparams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var params map[string]interface{}
+	if err = convertTypes(reflect.ValueOf(&params).Elem(), reflect.ValueOf(rec.(*Document).GetField("parameters").Value)); err != nil {
+		t.Fatal(err)
+	}
+	return params
+}
+
+func TestSerializeCommandNamedParams(t *testing.T) {
+	rid := NewRID(9, 1)
+	doc := NewDocumentFromRID(rid)
+	buf := bytes.NewBuffer(nil)
+	err := NewSQLCommand("SELECT FROM V WHERE out = :out AND name = :name", map[string]interface{}{
+		"out": doc, "name": "one",
+	}).ToStream(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := readCommandParams(t, buf.Bytes())
+	if params["out"] != rid {
+		t.Fatalf("expected record to be bound as link: %T(%v)", params["out"], params["out"])
+	} else if params["name"] != "one" {
+		t.Fatalf("wrong named param: %v", params["name"])
+	}
+}
+
+func TestSerializeCommandPositionalParams(t *testing.T) {
+	tm := time.Unix(1450000000, 0)
+	rids := []RID{NewRID(9, 1), NewRID(9, 2)}
+	buf := bytes.NewBuffer(nil)
+	err := NewSQLCommand("SELECT FROM V WHERE @rid IN ? AND created > ? AND tags CONTAINSANY ?",
+		[]*Document{NewDocumentFromRID(rids[0]), NewDocumentFromRID(rids[1])}, &tm, []string{"a", "b"},
+	).ToStream(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := readCommandParams(t, buf.Bytes())
+	if len(params) != 3 {
+		t.Fatalf("wrong params count: %d", len(params))
+	}
+	var links []RID
+	if err = convertTypes(reflect.ValueOf(&links).Elem(), reflect.ValueOf(params["0"])); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(links, rids) {
+		t.Fatalf("wrong links: %v", links)
+	}
+	if v, ok := params["1"].(time.Time); !ok || !v.Equal(tm) {
+		t.Fatalf("wrong time param: %T(%v)", params["1"], params["1"])
+	}
+	if v, ok := params["2"].([]interface{}); !ok || len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Fatalf("wrong slice param: %T(%v)", params["2"], params["2"])
+	}
+}
+
+func TestSerializeCommandIntTypes(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	cmd := NewSQLCommand("SELECT FROM V WHERE a = ? AND b = ? AND c = ? AND d IN ?",
+		5, TypedParam(7, INTEGER), TypedParam(int32(3), LONG), []interface{}{uint(1)},
+	).withIntType(INTEGER)
+	if err := cmd.ToStream(buf); err != nil {
+		t.Fatal(err)
+	}
+	params := readCommandParams(t, buf.Bytes())
+	if params["0"] != int32(5) || params["1"] != int32(7) || params["2"] != int64(3) {
+		t.Fatalf("wrong param types: %T %T %T", params["0"], params["1"], params["2"])
+	} else if v, ok := params["3"].([]interface{}); !ok || len(v) != 1 || v[0] != int32(1) {
+		t.Fatalf("wrong slice param: %T(%v)", params["3"], params["3"])
+	}
+
+	buf.Reset()
+	if err := NewSQLCommand("SELECT FROM V WHERE a = ?", 5).withIntType(LONG).ToStream(buf); err != nil {
+		t.Fatal(err)
+	} else if params = readCommandParams(t, buf.Bytes()); params["0"] != int64(5) {
+		t.Fatalf("wrong param type: %T", params["0"])
+	}
+	if err := NewSQLCommand("SELECT FROM V WHERE a = ?", TypedParam(int64(1)<<40, INTEGER)).ToStream(buf); err == nil {
+		t.Fatal("expected an overflow error")
+	} else if err = NewSQLCommand("SELECT FROM V WHERE a = ?", TypedParam("x", LINK)).ToStream(buf); err == nil {
+		t.Fatal("expected an error for unsupported type")
+	}
+}
+
+func TestSerializeCommandNilParam(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	if err := NewSQLCommand("SELECT FROM V WHERE name = ?", nil).ToStream(buf); err != nil {
+		t.Fatal(err)
+	}
+	params := readCommandParams(t, buf.Bytes())
+	if v, ok := params["0"]; !ok || v != nil {
+		t.Fatalf("expected nil param, got: %T(%v), %v", v, v, ok)
+	}
+}
+
 func testSerializeEmbMap(t *testing.T, off int, mp interface{}, origBase64 string) {
 	buf := bytes.NewBuffer(nil)
 	for i := 0; i < off; i++ {
@@ -248,3 +408,163 @@ func TestDocumentInnerStruct(t *testing.T) {
 func TestDocumentInnerMapToStruct(t *testing.T) {
 	testDocumentToStruct(t, "AAJWBk9uZQAAABgMCklubmVyAAAAKAoAAgcITmFtZQAAACQHBm9uZQQXDAIHCE5hbWUAAAA3BwZvbmUMAgcITmFtZQAAAEgHBnR3bw==")
 }
+
+func TestSerializeDocumentFieldOrder(t *testing.T) {
+	names := []string{"zeta", "alpha", "mid", "beta"}
+	doc := NewDocument("V")
+	for i, name := range names {
+		doc.SetField(name, int32(i))
+	}
+	doc.SetField("alpha", "replaced")
+	if got := doc.FieldNames(); !reflect.DeepEqual(got, names) {
+		t.Fatalf("wrong field order: %v", got)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := GetDefaultRecordSerializer().ToStream(buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	out, err := GetDefaultRecordSerializer().FromStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.(*Document).FieldNames(); !reflect.DeepEqual(got, names) {
+		t.Fatalf("wrong field order after decoding: %v", got)
+	}
+	data, err := out.(*Document).ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expect = `"zeta":0,"alpha":"replaced","mid":2,"beta":3`
+	if !strings.Contains(string(data), expect) {
+		t.Fatalf("wrong field order in JSON: %s", data)
+	}
+}
+
+func TestSerializeLinkedType(t *testing.T) {
+	pdoc := NewEmptyDocument()
+	pdoc.SetField("name", "nums")
+	pdoc.SetField("type", int32(EMBEDDEDLIST))
+	pdoc.SetField("linkedType", int32(INTEGER))
+	prop := NewOPropertyFromDocument(pdoc)
+	if prop.LinkedType != INTEGER {
+		t.Fatalf("wrong linked type: %v", prop.LinkedType)
+	}
+	classProp := func(class, name string) (*OProperty, bool) {
+		if class == "Item" && name == "nums" {
+			return prop, true
+		}
+		return nil, false
+	}
+	roundTrip := func(encode, decode ClassPropertyFunc) []interface{} {
+		doc := NewDocument("Item")
+		doc.SetField("nums", []interface{}{int64(1), 2, "three"})
+		buf := bytes.NewBuffer(nil)
+		ser := &BinaryRecordFormat{}
+		ser.SetClassPropertyFunc(encode)
+		if err := ser.ToStream(buf, doc); err != nil {
+			t.Fatal(err)
+		}
+		ser.SetClassPropertyFunc(decode)
+		out, err := ser.FromStream(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out.(*Document).GetField("nums").Value.([]interface{})
+	}
+	expect := []interface{}{int32(1), int32(2), "three"}
+	if got := roundTrip(classProp, nil); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("items were not stored with linked type: %#v", got)
+	}
+	if got := roundTrip(nil, classProp); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("items were not converted to linked type: %#v", got)
+	}
+	if got := roundTrip(nil, nil); !reflect.DeepEqual(got, []interface{}{int64(1), int64(2), "three"}) {
+		t.Fatalf("unexpected items without schema: %#v", got)
+	}
+}
+
+func TestDeserializeUnresolvedProperty(t *testing.T) {
+	// version 0, no class, unknown global property 1 at position 15, field "n" (STRING) at position 19,
+	// end of header, raw value of property 1, "bob"
+	data := []byte{0, 0, 3, 0, 0, 0, 15, 2, 'n', 0, 0, 0, 19, 7, 0, 42, 1, 2, 3, 6, 'b', 'o', 'b'}
+	f := &BinaryRecordFormat{}
+	f.SetGlobalPropertyFunc(func(id int) (OGlobalProperty, bool) {
+		return OGlobalProperty{}, false
+	})
+	if _, err := f.FromStream(data); err == nil {
+		t.Fatal("expected an error for unknown property")
+	}
+	f.SkipUnresolvedProperties = true
+	out, err := f.FromStream(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := out.(*Document)
+	if fld := doc.GetField("n"); fld == nil || fld.Value != "bob" {
+		t.Fatalf("wrong field: %v", fld)
+	} else if fld = doc.GetField("_prop_1"); fld == nil || fld.Type != BINARY {
+		t.Fatalf("wrong unresolved field: %v", fld)
+	} else if !bytes.Equal(fld.Value.([]byte), []byte{42, 1, 2, 3}) {
+		t.Fatalf("wrong raw data: %v", fld.Value)
+	}
+}
+
+func TestDeserializeZeroCopyStrings(t *testing.T) {
+	data, err := base64.StdEncoding.DecodeString(`AAASY2FyZXRha2VyAAAAJQcIbmFtZQAAAC0HBmFnZQAAADMBAA5NaWNoYWVsCkxpbnVzHg==`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := BinaryRecordFormat{}.FromStream(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out2, err := BinaryRecordFormat{ZeroCopyStrings: true}.FromStream(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, doc2 := out.(*Document), out2.(*Document)
+	if doc2.ClassName() != doc.ClassName() || !doc2.Equal(doc) {
+		t.Fatalf("documents differ: %v vs %v", doc2, doc)
+	}
+}
+
+// cancelAfterCtx is a context that is cancelled after Err was called n times.
+type cancelAfterCtx struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfterCtx) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestSerializeContext(t *testing.T) {
+	items := make([]interface{}, 1000)
+	for i := range items {
+		items[i] = int32(i)
+	}
+	doc := NewEmptyDocument()
+	doc.SetField("name", "big")
+	doc.SetFieldWithType("items", items, EMBEDDEDLIST)
+
+	f := BinaryRecordFormat{}
+	buf := bytes.NewBuffer(nil)
+	if err := f.ToStreamContext(context.Background(), buf, doc); err != nil {
+		t.Fatal(err)
+	} else if buf2 := bytes.NewBuffer(nil); f.ToStream(buf2, doc) != nil || !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatal("serialized data differs")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.ToStreamContext(ctx, ioutil.Discard, doc); err != context.Canceled {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+	// cancelled while writing collection items
+	ctx2 := &cancelAfterCtx{Context: context.Background(), n: 5}
+	if err := f.ToStreamContext(ctx2, ioutil.Discard, doc); err != context.Canceled {
+		t.Fatalf("expected cancellation, got: %v", err)
+	}
+}
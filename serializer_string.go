@@ -161,3 +161,107 @@ func (f StringRecordFormatAbs) FieldTypeFromStream(tp OType, s string) interface
 		panic(fmt.Errorf("unsupported type for stringRecordFormatAbs: %s", tp))
 	}
 }
+
+// splitFlatFields splits content of a flat record into a class name and a list of "name:value" entries.
+// Separators inside quoted strings and nested collections are ignored.
+func splitFlatFields(s string) (class string, fields []string, err error) {
+	var (
+		depth  int
+		quote  rune
+		escape bool
+		start  int
+	)
+	for i, c := range s {
+		switch {
+		case escape:
+			escape = false
+		case quote != 0:
+			if c == '\\' {
+				escape = true
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"':
+			quote = c
+		case c == '@' && depth == 0 && len(fields) == 0 && class == "" && !strings.ContainsRune(s[:i], string_ENTRY_SEPARATOR):
+			class, start = s[:i], i+1
+		case c == string_EMBEDDED_BEGIN, c == string_LIST_BEGIN, c == string_SET_BEGIN, c == string_MAP_BEGIN:
+			depth++
+		case c == string_EMBEDDED_END, c == string_LIST_END, c == string_SET_END, c == string_MAP_END:
+			depth--
+		case c == ',' && depth == 0:
+			fields = append(fields, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return "", nil, fmt.Errorf("unterminated value in flat record: %q", s)
+	}
+	if start < len(s) {
+		fields = append(fields, s[start:])
+	}
+	return class, fields, nil
+}
+
+// flatValue parses a single scalar value of a flat record. Values of other types are returned as strings as is.
+func (f StringRecordFormatAbs) flatValue(s string) (interface{}, OType, error) {
+	tp := f.GetType(s)
+	if tp == STRING && len(s) > 1 && s[0] == '-' { // GetType does not detect negative numbers
+		switch t := f.GetType(s[1:]); t {
+		case INTEGER, LONG, SHORT, BYTE, FLOAT, DOUBLE:
+			tp = t
+		}
+	}
+	switch tp {
+	case UNKNOWN:
+		return nil, UNKNOWN, nil
+	case STRING:
+		if n := len(s); n >= 2 && s[0] == '"' && s[n-1] == '"' {
+			r := strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+			return r.Replace(s[1 : n-1]), STRING, nil
+		}
+		return s, STRING, nil
+	case LINK:
+		rid, err := ParseRID(s)
+		return rid, LINK, err
+	case BOOLEAN:
+		return strings.ToLower(s) == "true", BOOLEAN, nil
+	case INTEGER:
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, tp, err
+		}
+		return int32(v), tp, nil
+	case LONG:
+		v, err := strconv.ParseInt(strings.TrimSuffix(s, "l"), 10, 64)
+		if err != nil {
+			return nil, tp, err
+		}
+		return v, tp, nil
+	case SHORT:
+		v, err := strconv.ParseInt(s[:len(s)-1], 10, 16)
+		if err != nil {
+			return nil, tp, err
+		}
+		return int16(v), tp, nil
+	case BYTE:
+		v, err := strconv.ParseInt(s[:len(s)-1], 10, 8)
+		if err != nil {
+			return nil, tp, err
+		}
+		return byte(v), tp, nil
+	case FLOAT:
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, "f"), 32)
+		if err != nil {
+			return nil, tp, err
+		}
+		return float32(v), tp, nil
+	case DOUBLE:
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return nil, tp, err
+		}
+		return v, tp, nil
+	}
+	return s, STRING, nil
+}
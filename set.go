@@ -0,0 +1,172 @@
+package orient
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// OrientSet is a collection of unique values. It's stored as EMBEDDEDSET, or as LINKSET if all items are links,
+// so the type of a field is preserved when a record is decoded into a struct and saved back.
+// EMBEDDEDSET and LINKSET fields can be decoded into OrientSet struct fields:
+//
+//		type Post struct {
+//			Tags orient.OrientSet
+//		}
+//
+// Sets can also be decoded into maps with empty struct values, like map[string]struct{}. Such maps are stored
+// as OrientSet when document is created from a struct or a field is set with SetField.
+type OrientSet []interface{}
+
+// NewOrientSet creates a set from given items. Duplicates are removed, order of other items is preserved.
+func NewOrientSet(items ...interface{}) OrientSet {
+	s := make(OrientSet, 0, len(items))
+	for _, v := range items {
+		s.Add(v)
+	}
+	return s
+}
+
+// setItemsEqual compares set items. Links are compared by RID.
+func setItemsEqual(a, b interface{}) bool {
+	if la, ok := a.(OIdentifiable); ok {
+		if lb, ok := b.(OIdentifiable); ok {
+			return la.GetIdentity() == lb.GetIdentity()
+		}
+		return false
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	} else if ta != nil && ta.Comparable() {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Contains checks if a set contains a given item.
+func (s OrientSet) Contains(v interface{}) bool {
+	for _, item := range s {
+		if setItemsEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add adds an item to the set. It returns false if the item was already in the set.
+func (s *OrientSet) Add(v interface{}) bool {
+	if s.Contains(v) {
+		return false
+	}
+	*s = append(*s, v)
+	return true
+}
+
+// unique returns a set without duplicates. It allows to serialize sets that were created without NewOrientSet.
+func (s OrientSet) unique() OrientSet {
+	for i := 1; i < len(s); i++ {
+		if s[:i].Contains(s[i]) {
+			return NewOrientSet(s...)
+		}
+	}
+	return s
+}
+
+// isLinks checks if all items of a non-empty set are links.
+func (s OrientSet) isLinks() bool {
+	for _, v := range s {
+		if _, ok := v.(OIdentifiable); !ok {
+			return false
+		}
+	}
+	return len(s) != 0
+}
+
+// oidentifiables returns set items as a slice of links.
+func (s OrientSet) oidentifiables() []OIdentifiable {
+	out := make([]OIdentifiable, len(s))
+	for i, v := range s {
+		out[i], _ = v.(OIdentifiable)
+	}
+	return out
+}
+
+// isSetMapType checks if a type is a map that is used as a set: map[T]struct{}.
+func isSetMapType(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Struct && t.Elem().NumField() == 0
+}
+
+// setFromMap converts map[T]struct{} to OrientSet. Items are sorted to keep serialized records stable.
+func setFromMap(m reflect.Value) OrientSet {
+	keys := m.MapKeys()
+	s := make(OrientSet, len(keys))
+	for i, k := range keys {
+		s[i] = k.Interface()
+	}
+	sort.Slice(s, func(i, j int) bool {
+		a, b := reflect.ValueOf(s[i]), reflect.ValueOf(s[j])
+		switch {
+		case a.Kind() == reflect.String && b.Kind() == reflect.String:
+			return a.String() < b.String()
+		case isIntKind(a.Kind()) && isIntKind(b.Kind()):
+			return a.Int() < b.Int()
+		}
+		return fmt.Sprint(s[i]) < fmt.Sprint(s[j])
+	})
+	return s
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// sliceToSetMap converts a collection into a map used as a set (see isSetMapType).
+func sliceToSetMap(typ reflect.Type, src reflect.Value) (reflect.Value, error) {
+	m := reflect.MakeMap(typ)
+	empty := reflect.New(typ.Elem()).Elem()
+	for i := 0; i < src.Len(); i++ {
+		v := src.Index(i)
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		if !v.IsValid() {
+			return reflect.Value{}, fmt.Errorf("nil item in set")
+		}
+		k := reflect.New(typ.Key()).Elem()
+		ok, err := convertLink(k, v)
+		switch {
+		case err != nil:
+			return reflect.Value{}, err
+		case ok:
+		case isNumberKind(v.Kind()) && isNumberKind(k.Kind()):
+			nv, err := convertNumber(v, k.Type())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			k.Set(nv)
+		case v.Type().ConvertibleTo(k.Type()):
+			k.Set(v.Convert(k.Type()))
+		default:
+			return reflect.Value{}, ErrUnsupportedConversion{From: v, To: k}
+		}
+		m.SetMapIndex(k, empty)
+	}
+	return m, nil
+}
+
+// setHookFunc is a map decoder hook that decodes collections into maps with empty struct values.
+func setHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if f.Kind() != reflect.Slice || !isSetMapType(t) {
+		return data, nil
+	}
+	m, err := sliceToSetMap(t, reflect.ValueOf(data))
+	if err != nil {
+		return nil, err
+	}
+	return m.Interface(), nil
+}
@@ -0,0 +1,68 @@
+package orient
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestOrientSet(t *testing.T) {
+	s := NewOrientSet("a", "b", "a", NewRID(9, 1), NewDocumentFromRID(NewRID(9, 1)))
+	if len(s) != 3 || !s.Contains("b") || !s.Contains(NewRID(9, 1)) || s.Contains("c") {
+		t.Fatalf("wrong set: %v", s)
+	}
+	if s.Add("b") || !s.Add("c") || len(s) != 4 {
+		t.Fatalf("wrong set after add: %v", s)
+	}
+}
+
+func TestOrientSetRoundTrip(t *testing.T) {
+	type Post struct {
+		Tags    OrientSet
+		Authors OrientSet
+		Scores  map[int32]struct{}
+		Names   []string
+	}
+	in := Post{
+		Tags:    OrientSet{"go", "db", "go"},
+		Authors: NewOrientSet(NewRID(9, 1), NewRID(9, 2)),
+		Scores:  map[int32]struct{}{3: {}, 1: {}, 2: {}},
+		Names:   []string{"a", "a"},
+	}
+	doc := NewDocument("Post")
+	if err := doc.From(in); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := GetDefaultRecordSerializer().ToStream(buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := GetDefaultRecordSerializer().FromStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := rec.(*Document)
+	for name, tp := range map[string]OType{"Tags": EMBEDDEDSET, "Authors": LINKSET, "Scores": EMBEDDEDSET, "Names": EMBEDDEDLIST} {
+		if fld := out.GetField(name); fld == nil || fld.Type != tp {
+			t.Fatalf("wrong type of %s: %+v", name, fld)
+		}
+	}
+	if v := out.GetField("Scores").Value; !reflect.DeepEqual(v, []interface{}{int32(1), int32(2), int32(3)}) {
+		t.Fatalf("set from map is not sorted: %v", v)
+	}
+	var res Post
+	if err = out.ToStruct(&res); err != nil {
+		t.Fatal(err)
+	}
+	in.Tags = OrientSet{"go", "db"}
+	in.Authors = OrientSet{NewRID(9, 1), NewRID(9, 2)}
+	if !reflect.DeepEqual(res, in) {
+		t.Fatalf("wrong data after round-trip:\n%+v\n%+v", res, in)
+	}
+	var scores map[int64]struct{}
+	if err = newResults(out.GetField("Scores").Value).All(&scores); err != nil {
+		t.Fatal(err)
+	} else if len(scores) != 3 {
+		t.Fatalf("wrong set: %v", scores)
+	}
+}
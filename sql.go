@@ -1,76 +1,66 @@
 package orient
 
-/*
 import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"io"
 	"net"
+	"reflect"
 	"regexp"
-	"runtime"
 	"strings"
 	"time"
 )
 
-// Driver name for database/sql package
-const DriverNameSQL = "orient"
+// DriverNameSQL is a driver name for database/sql package.
+const DriverNameSQL = "orientdb"
 
 var (
-	_ driver.Driver  = (*orientDriver)(nil)
-	_ driver.Conn    = (*Database)(nil)
-	_ driver.Execer  = (*Database)(nil)
-	_ driver.Queryer = (*Database)(nil)
+	_ driver.Driver  = (*sqlDriver)(nil)
+	_ driver.Conn    = (*sqlConn)(nil)
+	_ driver.Execer  = (*sqlConn)(nil)
+	_ driver.Queryer = (*sqlConn)(nil)
+	_ driver.Stmt    = (*sqlStmt)(nil)
+	_ driver.Rows    = (*sqlRows)(nil)
 )
 
 var dsnRx = regexp.MustCompile(`([^@]+)@([^:]+):([^/]+)/(.+)`)
 
 func init() {
-	sql.Register(DriverNameSQL, &orientDriver{})
+	sql.Register(DriverNameSQL, &sqlDriver{})
 }
 
 // DialDSN returns a new connection to the database.
 // The dsn (driver-specific name) is a string in a driver-specific format.
 // For orientgo, the dsn should be of the format:
-//   user@pass:host:port/db
-//   or
-//   user@pass:host/db  (default port of 2424 is used)
+//
+//		user@pass:host:port/db
+//		user@pass:host/db  (default port of 2424 is used)
 //
 // Function is also used for database/sql driver:
-//   db, err := sql.Open("orient", "admin@admin:127.0.0.1/db")
+//
+//		db, err := sql.Open("orientdb", "admin@admin:127.0.0.1/db")
+//
 func DialDSN(dsn string) (*Database, error) {
 	user, pass, host, port, dbname, err := parseDsn(dsn)
-	if port == "" {
-		port = "2424"
-	}
 	if err != nil {
 		return nil, err
 	}
-	dbc, err := Dial(net.JoinHostPort(host, port))
+	if port == "" {
+		port = "2424"
+	}
+	cli, err := Dial(net.JoinHostPort(host, port))
 	if err != nil {
 		return nil, err
 	}
-	// TODO: right now assumes DocumentDB type - pass in on the dsn??
-	//       NOTE: I tried a graphDB with DocumentDB type and it worked, so why is it necesary at all?
-	// TODO: this maybe shouldn't happen in this method -> might do it lazily in Query/Exec methods?
-	db, err := dbc.Open(dbname, DocumentDB, user, pass)
+	db, err := cli.Open(dbname, DocumentDB, user, pass)
 	if err != nil {
-		dbc.Close()
+		cli.Close()
 		return nil, err
 	}
 	return db, nil
 }
 
-// Implements the Go sql/driver.Driver interface.
-type orientDriver struct{}
-
-// Open implements sql/driver.Driver interface
-// See DialDSN for more info.
-func (d *orientDriver) Open(dsn string) (driver.Conn, error) {
-	glog.V(10).Infoln("OgonoriDriver#Open")
-	return DialDSN(dsn)
-}
-
 func parseDsn(dsn string) (uname, passw, host, port, dbname string, err error) {
 	matches := dsnRx.FindStringSubmatch(dsn)
 	if matches == nil || len(matches) != 5 {
@@ -86,242 +76,207 @@ func parseDsn(dsn string) (uname, passw, host, port, dbname string, err error) {
 	return matches[1], matches[2], host, port, matches[4], nil
 }
 
-// Prepare implements sql/driver.Conn interface
-func (db *Database) Prepare(query string) (driver.Stmt, error) {
-	glog.V(10).Infoln("ogoConn.Prepare: ", query)
-	return &ogonoriStmt{db: db, query: query}, nil
+// sqlDriver implements the Go sql/driver.Driver interface.
+type sqlDriver struct{}
+
+// Open implements sql/driver.Driver interface. See DialDSN for more info.
+func (d *sqlDriver) Open(dsn string) (driver.Conn, error) {
+	db, err := DialDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{db: db}, nil
 }
 
-// Begin implements sql/driver.Conn interface
-func (db *Database) Begin() (driver.Tx, error) {
-	glog.V(10).Infoln("ogoConn.Begin")
-	return nil, fmt.Errorf("orientgo: transactions are not supported for now")
+// sqlConn implements sql/driver.Conn interface on top of Database. Queries are executed with Database.Command,
+// and '?' placeholders are bound as command parameters.
+type sqlConn struct {
+	db *Database
 }
 
-// Exec implements sql/driver.Execer interface
-func (db *Database) Exec(cmd string, args []driver.Value) (driver.Result, error) {
-	glog.V(10).Infoln("ogoConn.Exec")
-	if db == nil {
-		return nil, ErrInvalidConn{Msg: "db not initialized in ogonoriConn#Exec"}
+// Prepare implements sql/driver.Conn interface.
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return &sqlStmt{conn: c, query: query}, nil
+}
+
+// Close implements sql/driver.Conn interface.
+func (c *sqlConn) Close() error {
+	err := c.db.Close()
+	if c.db.cli != nil {
+		c.db.cli.Close()
 	}
+	return err
+}
+
+// Begin implements sql/driver.Conn interface. SQL transactions are not supported.
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("orientgo: transactions are not supported by database/sql driver")
+}
+
+// Exec implements sql/driver.Execer interface.
+func (c *sqlConn) Exec(query string, args []driver.Value) (driver.Result, error) {
 	var o interface{}
-	err := db.Command(NewSQLCommand(cmd, driverArgs(args)...)).All(&o)
-	if err != nil {
-		return ogonoriResult{-1, -1}, err
+	if err := c.db.Command(NewSQLCommand(query, driverArgs(args)...)).All(&o); err != nil {
+		return nil, err
 	}
-
 	switch rec := o.(type) {
+	case nil:
+		return sqlResult{0, -1}, nil
 	case int32:
-		return ogonoriResult{int64(rec), -1}, nil
+		return sqlResult{int64(rec), -1}, nil
 	case int64:
-		return ogonoriResult{int64(rec), -1}, nil
+		return sqlResult{rec, -1}, nil
 	case int:
-		return ogonoriResult{int64(rec), -1}, nil
+		return sqlResult{int64(rec), -1}, nil
 	case []OIdentifiable:
-		return ogonoriResult{int64(len(rec)), rec[len(rec)-1].GetIdentity().ClusterPos}, nil
+		if len(rec) == 0 {
+			return sqlResult{0, -1}, nil
+		}
+		return sqlResult{int64(len(rec)), rec[len(rec)-1].GetIdentity().ClusterPos}, nil
 	case OIdentifiable:
-		return ogonoriResult{1, rec.GetIdentity().ClusterPos}, err
+		return sqlResult{1, rec.GetIdentity().ClusterPos}, nil
 	}
-	return nil, fmt.Errorf("exec with return values is not supported for now, out type: %T", o)
+	return nil, fmt.Errorf("exec with return values is not supported, out type: %T", o)
 }
 
-// Query implements sql/driver.Queryer interface
-func (db *Database) Query(query string, args []driver.Value) (driver.Rows, error) {
-	glog.V(10).Infoln("ogoConn.Query")
-	if db == nil {
-		return nil, ErrInvalidConn{Msg: "db not initialized in ogonoriConn#Exec"}
-	}
-	var o interface{}
-	err := db.Command(NewSQLQuery(query, driverArgs(args)...)).All(&o)
-	if err != nil {
+// Query implements sql/driver.Queryer interface.
+func (c *sqlConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	var recs []OIdentifiable
+	if err := c.db.Command(NewSQLQuery(query, driverArgs(args)...)).All(&recs); err != nil && err != ErrNoRecord {
 		return nil, err
 	}
-	switch rec := o.(type) {
-	case []OIdentifiable:
-		return newRows(rec)
-	}
-	return nil, fmt.Errorf("query with return values is not supported for now, out type: %T", o)
+	return newSQLRows(recs)
 }
 
 func driverArgs(args []driver.Value) []interface{} {
 	out := make([]interface{}, len(args))
 	for i, val := range args {
-		glog.V(10).Infof("valarg: %T: %v; isValue=%v\n", val, val, driver.IsValue(val)) // DEBUG
-		switch val.(type) {
-		case string, int64, float64, bool, []byte, time.Time:
-			out[i] = val
-		default:
-			_, file, line, _ := runtime.Caller(0)
-			glog.Warningf("Unexpected type in ogonoriConn#Exec: %T. (%s:%d)", val, file, line)
-		}
+		out[i] = val
 	}
 	return out
 }
 
-var _ driver.Result = (*ogonoriResult)(nil)
-
-// ogonoriResult implements the sql/driver.Result inteface.
-type ogonoriResult struct {
+// sqlResult implements the sql/driver.Result interface.
+type sqlResult struct {
 	affectedRows int64
-	insertId     int64
+	insertID     int64
 }
 
-// LastInsertId returns the database's auto-generated ID after,
-// for example, an INSERT into a table with primary key.
-func (res ogonoriResult) LastInsertId() (int64, error) {
-	return res.insertId, nil
+// LastInsertId returns the cluster position of the last created record. Use RETURN @rid in the command
+// and a query to get the full RID.
+func (res sqlResult) LastInsertId() (int64, error) {
+	return res.insertID, nil
 }
 
 // RowsAffected returns the number of rows affected by the query.
-func (res ogonoriResult) RowsAffected() (int64, error) {
+func (res sqlResult) RowsAffected() (int64, error) {
 	return res.affectedRows, nil
 }
 
-var _ driver.Rows = (*ogonoriRows)(nil)
-
-// ogonoriRows implements the sql/driver.Rows interface.
-type ogonoriRows struct {
-	pos     int // index of next row (doc) to return
-	docs    []OIdentifiable
-	cols    []string
-	fulldoc bool // whether query returned a full document or just properties
-	// TODO: maybe a reference to the appropriate schema is needed here?
+// sqlRows implements the sql/driver.Rows interface. Columns are taken from fields of the first record.
+// Records that are not documents are returned as a single "@rid" column.
+type sqlRows struct {
+	pos  int
+	docs []OIdentifiable
+	cols []string
 }
 
-func newRows(docs []OIdentifiable) (*ogonoriRows, error) {
-	var cols []string
-	if docs == nil || len(docs) == 0 {
-		cols = []string{}
-		return &ogonoriRows{docs: docs, cols: cols}, nil
-	}
-
-	for i := range docs {
-		if rdoc, ok := docs[i].(*Document); ok {
-			doc, err := rdoc.ToDocument()
-			if err != nil {
-				return nil, err
-			}
-			docs[i] = doc
-		} else {
-			return nil, fmt.Errorf("rows type: %T", docs[i])
-		}
+func newSQLRows(docs []OIdentifiable) (*sqlRows, error) {
+	rows := &sqlRows{docs: docs, cols: []string{}}
+	if len(docs) == 0 {
+		return rows, nil
 	}
-
-	var fulldoc bool
 	if doc, ok := docs[0].(*Document); ok {
-		if doc.classname == "" {
-			cols = make([]string, 0, len(doc.FieldNames()))
-			for _, fname := range doc.FieldNames() {
-				cols = append(cols, fname)
-			}
-		} else {
-			fulldoc = true
-			// if Classname is set then the user queried for a full document
-			// not individual properties of a Document/Class
-			cols = []string{doc.classname}
-		}
+		rows.cols = doc.FieldNames()
+	} else {
+		rows.cols = []string{"@rid"}
 	}
-	return &ogonoriRows{docs: docs, cols: cols, fulldoc: fulldoc}, nil
+	return rows, nil
 }
 
-// Columns returns the names of the columns. The number of
-// columns of the result is inferred from the length of the
-// slice.  If a particular column name isn't known, an empty
-// string should be returned for that entry.
-func (rows *ogonoriRows) Columns() []string {
-	glog.V(10).Infof("ogonoriRows.Columns = %v\n", rows.cols)
+// Columns returns the names of the columns.
+func (rows *sqlRows) Columns() []string {
 	return rows.cols
 }
 
-// Next is called to populate the next row of data into
-// the provided slice. The provided slice will be the same
-// size as the Columns() are wide.
-//
-// The dest slice may be populated only with
-// a driver Value type, but excluding string.
-// All string values must be converted to []byte.
-//
-// Next should return io.EOF when there are no more rows.
-func (rows *ogonoriRows) Next(dest []driver.Value) error {
-	glog.V(10).Infoln("ogonoriRows.Next")
-	// TODO: right now I return the entire resultSet as an array, thus all loaded into memory
-	//       it would be better to have obinary.dbCommands provide an iterator based model
-	//       that only needs to read a "row" (Document) at a time
+// Next populates the next row of data into the provided slice.
+func (rows *sqlRows) Next(dest []driver.Value) error {
 	if rows.pos >= len(rows.docs) {
 		return io.EOF
 	}
-	// TODO: may need to do a type switch here -> what else can come in from a query in OrientDB
-	//       besides an Document ??
-	currdoc := rows.docs[rows.pos]
-	if doc, ok := currdoc.(*Document); ok {
-		if rows.fulldoc {
-			dest[0] = doc
-		} else {
-			// was a property only query
-			for i := range dest {
-				// TODO: need to check field.Type and see if it is one that can map to Value
-				//       what will I do for types that don't map to Value (e.g., EmbeddedRecord, EmbeddedMap) ??
-				field := doc.GetField(rows.cols[i])
-				if field != nil {
-					dest[i] = field.Value
-				}
+	rec := rows.docs[rows.pos]
+	rows.pos++
+	doc, ok := rec.(*Document)
+	for i, col := range rows.cols {
+		dest[i] = nil
+		if !ok {
+			if col == "@rid" && rec != nil {
+				dest[i] = rec.GetIdentity().String()
 			}
+			continue
+		}
+		if fld := doc.GetField(col); fld != nil {
+			v, err := driverValue(fld.Value)
+			if err != nil {
+				return fmt.Errorf("column '%s': %v", col, err)
+			}
+			dest[i] = v
 		}
-	} else {
-		return fmt.Errorf("next on %T", currdoc)
 	}
-
-	rows.pos++
-	// TODO: this is where we need to return any errors that occur
 	return nil
 }
 
+// driverValue converts a document field value to one of driver.Value types.
+func driverValue(v interface{}) (driver.Value, error) {
+	switch val := v.(type) {
+	case nil, int64, float64, bool, []byte, string, time.Time:
+		return val, nil
+	case OIdentifiable:
+		return val.GetIdentity().String(), nil
+	case Decimal:
+		return decimalString(val), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(rv.Uint()), nil
+	case reflect.Float32:
+		return rv.Float(), nil
+	}
+	return nil, fmt.Errorf("unsupported value type: %T", v)
+}
+
 // Close closes the rows iterator.
-func (rows *ogonoriRows) Close() error {
-	glog.V(10).Infoln("ogonoriRows.Close")
+func (rows *sqlRows) Close() error {
+	rows.docs = nil
 	return nil
 }
 
-var _ driver.Stmt = (*ogonoriStmt)(nil)
-
-// ogonoriStmt implements the Go sql/driver.Stmt interface.
-type ogonoriStmt struct {
-	// TODO: review this - this is how the mysql driver does it
-	db    *Database
-	query string // the SQL query/cmd specified by the user
+// sqlStmt implements the Go sql/driver.Stmt interface. OrientDB has no server-side statement handles,
+// so statement only keeps the query text.
+type sqlStmt struct {
+	conn  *sqlConn
+	query string
 }
 
-// NumInput returns the number of placeholder parameters.
-func (st *ogonoriStmt) NumInput() int {
-	glog.V(10).Infoln("ogonoriStmt.NumInput")
-	return -1 // -1 means sql package will not "sanity check" arg counts
+// NumInput returns -1, so sql package will not check arguments count.
+func (st *sqlStmt) NumInput() int {
+	return -1
 }
 
 // Exec executes a query that doesn't return rows, such as an INSERT or UPDATE.
-func (st *ogonoriStmt) Exec(args []driver.Value) (driver.Result, error) {
-	glog.V(10).Infoln("ogonoriStmt.Exec")
-	if st.db == nil {
-		return nil, ErrInvalidConn{Msg: "obinary.DBClient not initialized in ogonoriStmt#Exec"}
-	}
-	return st.db.Exec(st.query, args)
+func (st *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return st.conn.Exec(st.query, args)
 }
 
 // Query executes a query that may return rows, such as a SELECT.
-func (st *ogonoriStmt) Query(args []driver.Value) (driver.Rows, error) {
-	glog.V(10).Infoln("ogonoriStmt.Query")
-	if st.db == nil {
-		return nil, ErrInvalidConn{Msg: "obinary.DBClient not initialized in ogonoriStmt#Query"}
-	}
-	return st.db.Query(st.query, args)
+func (st *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return st.conn.Query(st.query, args)
 }
 
 // Close closes the statement.
-//
-// As of Go 1.1, a Stmt will not be closed if it's in use by any queries.
-func (st *ogonoriStmt) Close() error {
-	glog.V(10).Info("ogonoriStmt.Close")
-	// nothing to do here since there is no special statement handle in OrientDB
-	// that is referenced by a client driver
+func (st *sqlStmt) Close() error {
 	return nil
 }
-*/
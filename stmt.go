@@ -0,0 +1,56 @@
+package orient
+
+import "fmt"
+
+// Stmt is a prepared SQL statement. It is safe for concurrent use.
+//
+// OrientDB binary protocol has no server-side statement handles, so Stmt only keeps the command text
+// and sends it with a new set of parameters on each call. Since the text is always the same, the server
+// is able to reuse the parsed command from it's command cache, if one is enabled.
+type Stmt struct {
+	db   *Database
+	text string
+	plan FetchPlan
+}
+
+// Prepare creates a prepared statement for later queries or executions. Example:
+//
+//		stmt, err := db.Prepare("SELECT FROM V WHERE id = ?")
+//		...
+//		var doc *Document
+//		err = stmt.Query(id).All(&doc)
+//
+func (db *Database) Prepare(sql string) (*Stmt, error) {
+	if db == nil || db.pool == nil {
+		return nil, ErrInvalidConn{Msg: "database is not opened"}
+	} else if sql == "" {
+		return nil, fmt.Errorf("empty statement")
+	}
+	return &Stmt{db: db, text: sql}, nil
+}
+
+// FetchPlan returns a copy of the statement that uses a given fetch plan for Query.
+// The original statement is not modified, so it can still be shared.
+func (st *Stmt) FetchPlan(plan FetchPlan) *Stmt {
+	c := *st
+	c.plan = plan
+	return &c
+}
+
+// Text returns statement text.
+func (st *Stmt) Text() string { return st.text }
+
+// Query executes prepared statement as a SELECT-like query with given params.
+func (st *Stmt) Query(params ...interface{}) Results {
+	return st.db.Command(NewSQLQuery(st.text, params...).FetchPlan(st.plan))
+}
+
+// Exec executes prepared statement as a non-SELECT command with given params.
+func (st *Stmt) Exec(params ...interface{}) Results {
+	return st.db.Command(NewSQLCommand(st.text, params...))
+}
+
+// Close closes the statement. It exists for compatibility with database/sql ergonomics and does nothing for now.
+func (st *Stmt) Close() error {
+	return nil
+}
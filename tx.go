@@ -0,0 +1,281 @@
+package orient
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// TxOperation is a type of record operation inside a transaction.
+type TxOperation byte
+
+// List of record operations supported in transactions. Values are defined by OrientDB binary protocol.
+const (
+	TxUpdate TxOperation = 1
+	TxDelete TxOperation = 2
+	TxCreate TxOperation = 3
+)
+
+// TxEntry is a single record operation that will be sent to the server on transaction commit.
+type TxEntry struct {
+	Op     TxOperation
+	Record ORecord
+}
+
+var lastTxID int32
+
+// ErrTxDone is returned by any operation on a transaction that has already been committed or rolled back.
+var ErrTxDone = fmt.Errorf("transaction has already been committed or rolled back")
+
+// Tx is an optimistic transaction. Record operations are collected on the client and sent to the server
+// in one request on Commit. If any of the records was changed concurrently, Commit returns ErrConcurrentModification.
+//
+// Records created inside a transaction get a temporary RID, which can be used in links to other records
+// of the same transaction. After commit, temporary RIDs are replaced with persistent ones.
+//
+// Transaction holds one database connection until Commit or Rollback is called. Only record operations are
+// a part of the transaction; use a script command with BEGIN/COMMIT statements to run SQL commands atomically.
+type Tx struct {
+	mu      sync.Mutex
+	db      *Database
+	conn    DBSession
+	id      int
+	lastPos int64
+	entries []TxEntry
+}
+
+// Begin starts a new transaction.
+func (db *Database) Begin() (*Tx, error) {
+	conn, err := db.pool.getConn()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(db.commandTimeout())
+	return &Tx{
+		db: db, conn: conn,
+		id:      int(atomic.AddInt32(&lastTxID, 1)),
+		lastPos: clusterPosInvalid,
+	}, nil
+}
+
+func (tx *Tx) findEntry(rid RID) int {
+	for i, e := range tx.entries {
+		if e.Record.GetIdentity() == rid {
+			return i
+		}
+	}
+	return -1
+}
+
+// clusterFor returns a cluster id for a new record, based on record class.
+func (tx *Tx) clusterFor(rec ORecord) (int16, error) {
+	if rid := rec.GetIdentity(); rid.ClusterID >= 0 {
+		return rid.ClusterID, nil
+	}
+	if doc, ok := rec.(*Document); ok && doc.ClassName() != "" {
+		if odb := tx.conn.GetCurDB(); odb != nil {
+			if class, ok := odb.Classes[doc.ClassName()]; ok {
+				return int16(class.DefaultClusterId), nil
+			}
+		}
+		return 0, fmt.Errorf("cannot find cluster for class %q", doc.ClassName())
+	}
+	return 0, fmt.Errorf("cannot determine cluster for a record without class; set it with NewRIDInCluster")
+}
+
+// CreateRecord adds a new record to the transaction and assigns a temporary RID to it.
+func (tx *Tx) CreateRecord(rec ORecord) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.conn == nil {
+		return ErrTxDone
+	}
+	cid, err := tx.clusterFor(rec)
+	if err != nil {
+		return err
+	}
+	tx.lastPos--
+	rec.SetRID(RID{ClusterID: cid, ClusterPos: tx.lastPos})
+	tx.entries = append(tx.entries, TxEntry{Op: TxCreate, Record: rec})
+	return nil
+}
+
+// UpdateRecord adds record update to the transaction.
+func (tx *Tx) UpdateRecord(rec ORecord) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.conn == nil {
+		return ErrTxDone
+	}
+	rid := rec.GetIdentity()
+	if i := tx.findEntry(rid); i >= 0 {
+		if tx.entries[i].Op == TxDelete {
+			return fmt.Errorf("record %v is deleted in this transaction", rid)
+		}
+		tx.entries[i].Record = rec // record content will be serialized on commit
+		return nil
+	} else if !rid.IsPersistent() {
+		return fmt.Errorf("record is not persistent: %v", rid)
+	}
+	tx.entries = append(tx.entries, TxEntry{Op: TxUpdate, Record: rec})
+	return nil
+}
+
+// DeleteRecord adds record removal to the transaction.
+func (tx *Tx) DeleteRecord(rec ORecord) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.conn == nil {
+		return ErrTxDone
+	}
+	rid := rec.GetIdentity()
+	if i := tx.findEntry(rid); i >= 0 {
+		if tx.entries[i].Op == TxCreate { // no need to send it at all
+			tx.entries = append(tx.entries[:i], tx.entries[i+1:]...)
+			return nil
+		}
+		tx.entries[i] = TxEntry{Op: TxDelete, Record: rec}
+		return nil
+	} else if !rid.IsPersistent() {
+		return fmt.Errorf("record is not persistent: %v", rid)
+	}
+	tx.entries = append(tx.entries, TxEntry{Op: TxDelete, Record: rec})
+	return nil
+}
+
+func (tx *Tx) release() {
+	if tx.conn.Alive() {
+		tx.db.pool.putConn(tx.conn)
+	} else {
+		tx.db.pool.discard(tx.conn)
+	}
+	tx.conn = nil
+	tx.entries = nil
+}
+
+// Commit sends all collected record operations to the server. Records RIDs and versions will be changed after the call.
+func (tx *Tx) Commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.conn == nil {
+		return ErrTxDone
+	}
+	defer tx.release()
+	if len(tx.entries) == 0 {
+		return nil
+	}
+	temp := make(map[int]RID)
+	for i, e := range tx.entries {
+		if e.Op == TxCreate {
+			temp[i] = e.Record.GetIdentity()
+		}
+	}
+	if err := tx.conn.Commit(tx.id, tx.entries); err != nil {
+		for _, e := range tx.entries {
+			tx.db.evictRecord(e.Record.GetIdentity())
+		}
+		return convertError(err)
+	}
+	rids := make(map[RID]RID, len(temp))
+	for i, rid := range temp {
+		rids[rid] = tx.entries[i].Record.GetIdentity()
+	}
+	for _, e := range tx.entries {
+		if doc, ok := e.Record.(*Document); ok && e.Op != TxDelete && replaceTempLinks(doc, rids) {
+			// content was serialized with temporary links; serialize it again for the record cache
+			if data, err := doc.Content(); err == nil {
+				doc.raw = data
+			} else {
+				doc.raw = nil
+			}
+		}
+		if e.Op == TxDelete {
+			tx.db.evictRecord(e.Record.GetIdentity())
+		} else {
+			tx.db.cacheRecord(e.Record)
+		}
+	}
+	return nil
+}
+
+// Rollback discards all collected record operations.
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.conn == nil {
+		return ErrTxDone
+	}
+	tx.release()
+	return nil
+}
+
+// replaceTempLinks rewrites links to temporary RIDs in document fields to persistent RIDs. Links are replaced
+// in embedded documents, collections, maps and RidBags as well. It reports if any link was changed.
+func replaceTempLinks(doc *Document, rids map[RID]RID) bool {
+	if len(rids) == 0 {
+		return false
+	}
+	changed := false
+	for _, fld := range doc.Fields() {
+		if v, ok := replaceTempLinksIn(fld.Value, rids); ok {
+			fld.Value = v
+			changed = true
+		}
+	}
+	return changed
+}
+
+// replaceTempLinksIn rewrites temporary links in a field value. Slices, maps and embedded documents are changed
+// in place; new value is returned for links stored by value.
+func replaceTempLinksIn(val interface{}, rids map[RID]RID) (interface{}, bool) {
+	switch v := val.(type) {
+	case nil:
+		return nil, false
+	case RID:
+		if rid, ok := rids[v]; ok {
+			return rid, true
+		}
+		return v, false
+	case *RID:
+		if v != nil {
+			if rid, ok := rids[*v]; ok {
+				*v = rid
+				return v, true
+			}
+		}
+		return v, false
+	case *Document:
+		if v == nil || v.RID.IsValid() {
+			return v, false // links to records of this transaction get new RIDs on commit
+		}
+		return v, replaceTempLinks(v, rids)
+	case *RidBag:
+		if v == nil {
+			return v, false
+		}
+		return v, v.replaceLinks(rids)
+	case []byte:
+		return v, false
+	}
+	rv := reflect.ValueOf(val)
+	changed := false
+	switch rv.Kind() {
+	case reflect.Slice:
+		for i := 0; i < rv.Len(); i++ {
+			el := rv.Index(i)
+			if nv, ok := replaceTempLinksIn(el.Interface(), rids); ok {
+				el.Set(reflect.ValueOf(nv))
+				changed = true
+			}
+		}
+	case reflect.Map:
+		for _, k := range rv.MapKeys() {
+			if nv, ok := replaceTempLinksIn(rv.MapIndex(k).Interface(), rids); ok {
+				rv.SetMapIndex(k, reflect.ValueOf(nv))
+				changed = true
+			}
+		}
+	}
+	return val, changed
+}
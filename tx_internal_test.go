@@ -0,0 +1,107 @@
+package orient
+
+import "testing"
+
+func TestReplaceTempLinks(t *testing.T) {
+	tmp1, tmp2 := RID{ClusterID: 9, ClusterPos: -2}, RID{ClusterID: 9, ClusterPos: -3}
+	rid1, rid2 := RID{ClusterID: 9, ClusterPos: 10}, RID{ClusterID: 9, ClusterPos: 11}
+	other := RID{ClusterID: 5, ClusterPos: 1}
+	rids := map[RID]RID{tmp1: rid1, tmp2: rid2}
+
+	emb := NewEmptyDocument().SetField("link", tmp1).SetField("list", []interface{}{tmp2, other})
+	bag := &RidBag{delegate: &embeddedRidBag{links: []OIdentifiable{tmp1, other}}}
+	doc := NewEmptyDocument().
+		SetField("link", tmp1).
+		SetField("other", other).
+		SetField("emb", emb).
+		SetField("embList", []*Document{NewEmptyDocument().SetField("link", tmp2)}).
+		SetField("linkMap", map[string]OIdentifiable{"a": tmp1, "b": other}).
+		SetField("embMap", map[string]interface{}{"a": []RID{tmp2}, "b": "text"}).
+		SetField("set", NewOrientSet(tmp1, tmp2)).
+		SetField("bag", bag)
+
+	if !replaceTempLinks(doc, rids) {
+		t.Fatal("expected links to be replaced")
+	}
+	check := func(name string, got, exp interface{}) {
+		if got != exp {
+			t.Errorf("%s: expected %v, got %v", name, exp, got)
+		}
+	}
+	check("link", doc.GetField("link").Value, rid1)
+	check("other", doc.GetField("other").Value, other)
+	check("emb.link", emb.GetField("link").Value, rid1)
+	list := emb.GetField("list").Value.([]interface{})
+	check("emb.list[0]", list[0], rid2)
+	check("emb.list[1]", list[1], other)
+	check("embList[0].link", doc.GetField("embList").Value.([]*Document)[0].GetField("link").Value, rid2)
+	lm := doc.GetField("linkMap").Value.(map[string]OIdentifiable)
+	check("linkMap.a", lm["a"], rid1)
+	check("linkMap.b", lm["b"], other)
+	em := doc.GetField("embMap").Value.(map[string]interface{})
+	check("embMap.a[0]", em["a"].([]RID)[0], rid2)
+	check("embMap.b", em["b"], "text")
+	set := doc.GetField("set").Value.(OrientSet)
+	check("set[0]", set[0], rid1)
+	check("set[1]", set[1], rid2)
+	links := bag.delegate.(*embeddedRidBag).links
+	check("bag[0]", links[0], rid1)
+	check("bag[1]", links[1], other)
+
+	if replaceTempLinks(doc, rids) {
+		t.Fatal("no temporary links expected after replacement")
+	}
+}
+
+type txSession struct {
+	fakeSession
+	pos int64
+}
+
+// Commit assigns persistent RIDs and fills records with content serialized before commit, as the server client does.
+func (s *txSession) Commit(txID int, entries []TxEntry) error {
+	for _, e := range entries {
+		data, err := e.Record.Content()
+		if err != nil {
+			return err
+		}
+		rid := e.Record.GetIdentity()
+		if e.Op == TxCreate {
+			s.pos++
+			rid.ClusterPos = s.pos
+		}
+		if err = e.Record.Fill(rid, 1, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTxCommitCachesPersistentLinks(t *testing.T) {
+	sess := &txSession{}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	db.EnableRecordCache(10)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewDocumentFromRID(RID{ClusterID: 9, ClusterPos: -1})
+	b := NewDocumentFromRID(RID{ClusterID: 9, ClusterPos: -1})
+	if err = tx.CreateRecord(a); err != nil {
+		t.Fatal(err)
+	} else if err = tx.CreateRecord(b); err != nil {
+		t.Fatal(err)
+	}
+	b.SetField("emb", NewEmptyDocument().SetField("to", a.GetIdentity()))
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := db.GetRecordByRID(b.GetIdentity(), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emb := rec.(*Document).GetField("emb").Value.(*Document)
+	if to := emb.GetField("to").Value; to != a.GetIdentity() {
+		t.Fatalf("expected cached link to %v, got %v", a.GetIdentity(), to)
+	}
+}
@@ -1,7 +1,6 @@
 package orient
 
 import (
-	"log"
 	"reflect"
 	"time"
 	"unsafe"
@@ -48,6 +47,8 @@ func (t OType) String() string { // do not change - it may be used as field type
 		return "BOOLEAN"
 	case INTEGER:
 		return "INTEGER"
+	case SHORT:
+		return "SHORT"
 	case LONG:
 		return "LONG"
 	case FLOAT:
@@ -130,6 +131,8 @@ func (t OType) ReflectType() reflect.Type {
 	switch t {
 	case BOOLEAN:
 		return reflect.TypeOf(bool(false))
+	case SHORT:
+		return reflect.TypeOf(int16(0))
 	case INTEGER:
 		return reflect.TypeOf(int32(0))
 	case LONG:
@@ -173,8 +176,67 @@ func (t OType) ReflectType() reflect.Type {
 	}
 }
 
+var (
+	reflInterfaceType      = reflect.TypeOf((*interface{})(nil)).Elem()
+	reflOIdentifiableSlice = reflect.TypeOf([]OIdentifiable(nil))
+	reflOIdentifiableMap   = reflect.TypeOf(map[string]OIdentifiable(nil))
+	reflInterfaceSliceType = reflect.TypeOf([]interface{}(nil))
+	reflInterfaceMapType   = reflect.TypeOf(map[string]interface{}(nil))
+	reflRidBagType         = reflect.TypeOf((*RidBag)(nil))
+	reflDecimalType        = reflect.TypeOf(Decimal{})
+)
+
+// GoType returns a Go type of field values of this type, as they are stored in Document.
+// Unlike ReflectType, it covers embedded and link types. Items of embedded collections may
+// have more specific types, depending on the data.
+func (t OType) GoType() reflect.Type {
+	switch t {
+	case BOOLEAN:
+		return reflect.TypeOf(false)
+	case INTEGER:
+		return reflect.TypeOf(int32(0))
+	case SHORT:
+		return reflect.TypeOf(int16(0))
+	case LONG:
+		return reflect.TypeOf(int64(0))
+	case FLOAT:
+		return reflect.TypeOf(float32(0))
+	case DOUBLE:
+		return reflect.TypeOf(float64(0))
+	case DATETIME, DATE:
+		return reflTimeType
+	case STRING:
+		return reflect.TypeOf("")
+	case BINARY:
+		return reflByteSliceType
+	case BYTE:
+		return reflect.TypeOf(byte(0))
+	case EMBEDDED:
+		return reflDocumentType
+	case EMBEDDEDLIST, EMBEDDEDSET:
+		return reflInterfaceSliceType
+	case EMBEDDEDMAP:
+		return reflInterfaceMapType
+	case LINK:
+		return reflOIdentifiableType
+	case LINKLIST, LINKSET:
+		return reflOIdentifiableSlice
+	case LINKMAP:
+		return reflOIdentifiableMap
+	case LINKBAG:
+		return reflRidBagType
+	case DECIMAL:
+		return reflDecimalType
+	default: // ANY, TRANSIENT, CUSTOM
+		return reflInterfaceType
+	}
+}
+
 func OTypeForValue(val interface{}) (ftype OType) {
 	ftype = UNKNOWN
+	if val == nil {
+		return
+	}
 	// TODO: need to add more types: LINKSET, LINKLIST, etc. ...
 	switch val.(type) {
 	case string:
@@ -207,10 +269,18 @@ func OTypeForValue(val interface{}) (ftype OType) {
 		ftype = LINK
 	case []OIdentifiable, []RID:
 		ftype = LINKLIST
+	case OrientSet:
+		if val.(OrientSet).isLinks() {
+			ftype = LINKSET
+		} else {
+			ftype = EMBEDDEDSET
+		}
 	case *RidBag:
 		ftype = LINKBAG
 	case time.Time:
 		ftype = DATETIME
+	case Geometry:
+		ftype = STRING // stored as WKT
 	// TODO: more types need to be added
 	default:
 		if isDecimal(val) {
@@ -259,63 +329,71 @@ func OTypeForValue(val interface{}) (ftype OType) {
 		case reflect.Struct:
 			ftype = EMBEDDED
 		default:
-			log.Printf("unknown type in serialization: %T, kind: %v", val, reflect.TypeOf(val).Kind())
+			Logf("unknown type in serialization: %T, kind: %v", val, reflect.TypeOf(val).Kind())
 		}
 	}
 	return
 }
 
 func OTypeFromString(typ string) OType {
+	tp, ok := lookupOType(typ)
+	if !ok {
+		panic("Unkwown type: " + typ)
+	}
+	return tp
+}
+
+// lookupOType returns a type with a given name, as returned by OType.String.
+func lookupOType(typ string) (OType, bool) {
 	switch typ {
 	case "BOOLEAN":
-		return BOOLEAN
+		return BOOLEAN, true
 	case "INTEGER":
-		return INTEGER
+		return INTEGER, true
 	case "SHORT":
-		return SHORT
+		return SHORT, true
 	case "LONG":
-		return LONG
+		return LONG, true
 	case "FLOAT":
-		return FLOAT
+		return FLOAT, true
 	case "DOUBLE":
-		return DOUBLE
+		return DOUBLE, true
 	case "DATETIME":
-		return DATETIME
+		return DATETIME, true
 	case "STRING":
-		return STRING
+		return STRING, true
 	case "BINARY":
-		return BINARY
+		return BINARY, true
 	case "EMBEDDED":
-		return EMBEDDED
+		return EMBEDDED, true
 	case "EMBEDDEDLIST":
-		return EMBEDDEDLIST
+		return EMBEDDEDLIST, true
 	case "EMBEDDEDSET":
-		return EMBEDDEDSET
+		return EMBEDDEDSET, true
 	case "EMBEDDEDMAP":
-		return EMBEDDEDMAP
+		return EMBEDDEDMAP, true
 	case "LINK":
-		return LINK
+		return LINK, true
 	case "LINKLIST":
-		return LINKLIST
+		return LINKLIST, true
 	case "LINKSET":
-		return LINKSET
+		return LINKSET, true
 	case "LINKMAP":
-		return LINKMAP
+		return LINKMAP, true
 	case "BYTE":
-		return BYTE
+		return BYTE, true
 	case "TRANSIENT":
-		return TRANSIENT
+		return TRANSIENT, true
 	case "DATE":
-		return DATE
+		return DATE, true
 	case "CUSTOM":
-		return CUSTOM
+		return CUSTOM, true
 	case "DECIMAL":
-		return DECIMAL
+		return DECIMAL, true
 	case "LINKBAG":
-		return LINKBAG
+		return LINKBAG, true
 	case "ANY":
-		return ANY
-	default:
-		panic("Unkwown type: " + typ)
+		return ANY, true
 	}
+	return UNKNOWN, false
 }
@@ -0,0 +1,35 @@
+package orient_test
+
+import (
+	"gopkg.in/istreamdata/orientgo.v2"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOTypeString(t *testing.T) {
+	for tp := orient.BOOLEAN; tp <= orient.ANY; tp++ {
+		if tp == orient.CUSTOM {
+			continue
+		}
+		if s := tp.String(); orient.OTypeFromString(s) != tp {
+			t.Fatalf("wrong string for %d: %s", tp, s)
+		}
+	}
+}
+
+func TestOTypeGoType(t *testing.T) {
+	for _, v := range []interface{}{
+		true, int16(1), int32(1), int64(1), float32(1), float64(1), "s", []byte("b"), byte(1),
+		time.Now(), orient.NewEmptyDocument(), []orient.OIdentifiable{}, orient.NewRidBag(),
+		[]interface{}{}, map[string]interface{}{},
+	} {
+		tp := orient.OTypeForValue(v)
+		if gt := tp.GoType(); gt != reflect.TypeOf(v) {
+			t.Fatalf("wrong Go type for %v: %v, expected: %T", tp, gt, v)
+		}
+	}
+	if tp := orient.OTypeForValue(nil); tp != orient.UNKNOWN {
+		t.Fatalf("wrong type for nil: %v", tp)
+	}
+}
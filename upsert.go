@@ -0,0 +1,188 @@
+package orient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// indexFields returns field names of index definition. Composite definitions are supported.
+func indexFields(def *Document) []string {
+	if def == nil {
+		return nil
+	}
+	if fld := def.GetField("field"); fld != nil {
+		if s, ok := fld.Value.(string); ok {
+			return []string{s}
+		}
+	}
+	var out []string
+	if fld := def.GetField("indexDefinitions"); fld != nil {
+		if defs, ok := fld.Value.([]interface{}); ok {
+			for _, d := range defs {
+				if doc, ok := d.(*Document); ok {
+					out = append(out, indexFields(doc)...)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// isUniqueIndex checks if index document describes a unique index of a given class on a given set of fields.
+func isUniqueIndex(idx *Document, class string, fields []string) bool {
+	fld := idx.GetField("type")
+	if fld == nil {
+		return false
+	} else if tp, _ := fld.Value.(string); !strings.HasPrefix(strings.ToUpper(tp), "UNIQUE") {
+		return false
+	}
+	fld = idx.GetField("indexDefinition")
+	if fld == nil {
+		return false
+	}
+	def, ok := fld.Value.(*Document)
+	if !ok {
+		return false
+	}
+	if fld := def.GetField("className"); fld == nil {
+		return false
+	} else if cname, _ := fld.Value.(string); !strings.EqualFold(cname, class) {
+		return false
+	}
+	ifields := indexFields(def)
+	if len(ifields) != len(fields) {
+		return false
+	}
+	for i := range ifields {
+		ifields[i] = strings.ToLower(ifields[i])
+	}
+	sort.Strings(ifields)
+	for i, name := range fields {
+		if ifields[i] != strings.ToLower(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// upsertSQL builds UPDATE ... UPSERT command. Key fields are also set, so they will be present in inserted record.
+func upsertSQL(class string, key, set map[string]interface{}) (string, []interface{}) {
+	all := make(map[string]interface{}, len(key)+len(set))
+	for k, v := range set {
+		all[k] = v
+	}
+	for k, v := range key {
+		all[k] = v
+	}
+	sets, params := setClause(all)
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	conds := make([]string, len(names))
+	for i, name := range names {
+		conds[i] = name + ` = ?`
+		params = append(params, key[name])
+	}
+	return `UPDATE ` + class + sets + ` UPSERT RETURN AFTER WHERE ` + strings.Join(conds, " AND "), params
+}
+
+// Upsert updates a record of a given class, found by key fields, or inserts a new one, if no records match.
+// Key fields must be covered by a UNIQUE index, otherwise concurrent upserts may create duplicates,
+// so an error is returned without executing the command.
+func (db *Database) Upsert(class string, key map[string]interface{}, set map[string]interface{}) (*Document, error) {
+	if class == "" {
+		return nil, fmt.Errorf("class name is required")
+	} else if !isSQLName(class) {
+		return nil, fmt.Errorf("invalid class name: %q", class)
+	} else if len(key) == 0 {
+		return nil, fmt.Errorf("upsert key is required")
+	}
+	if err := checkPropNames(key); err != nil {
+		return nil, err
+	} else if err = checkPropNames(set); err != nil {
+		return nil, err
+	}
+	fields := make([]string, 0, len(key))
+	for name := range key {
+		fields = append(fields, strings.ToLower(name))
+	}
+	sort.Strings(fields)
+
+	var indexes []*Document
+	if err := db.Command(NewSQLQuery(`SELECT expand(indexes) FROM metadata:indexmanager`)).All(&indexes); err != nil && err != ErrNoRecord {
+		return nil, err
+	}
+	indexed := false
+	for _, idx := range indexes {
+		if isUniqueIndex(idx, class, fields) {
+			indexed = true
+			break
+		}
+	}
+	if !indexed {
+		return nil, fmt.Errorf("no unique index on %s(%s), upsert requires one", class, strings.Join(fields, ", "))
+	}
+	sql, params := upsertSQL(class, key, set)
+	var doc *Document
+	if err := db.Command(NewSQLCommand(sql, params...)).All(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// updateFieldsSQL builds UPDATE command for a single record. Version condition is added if version is not negative.
+func updateFieldsSQL(rid RID, version int32, fields map[string]interface{}) (string, []interface{}) {
+	sets, params := setClause(fields)
+	sql := `UPDATE ` + rid.String() + sets + ` RETURN AFTER`
+	if version >= 0 {
+		sql += ` WHERE @version = ?`
+		params = append(params, version)
+	}
+	return sql, params
+}
+
+// UpdateFields sets given fields of a record and returns a new record version. Only these fields are sent
+// to the server, and other fields of the record are left as is, so concurrent changes of them are not lost.
+//
+// If version is not negative, the record is updated only if it still has this version, otherwise
+// ErrConcurrentModification is returned. Negative version disables the check. If record does not exist,
+// ErrRecordNotFound is returned.
+//
+// Binary protocol can only update whole records, so UPDATE command is used.
+func (db *Database) UpdateFields(rid RID, version int32, fields map[string]interface{}) (int32, error) {
+	if !rid.IsPersistent() {
+		return -1, fmt.Errorf("invalid record: %v", rid)
+	} else if len(fields) == 0 {
+		return -1, fmt.Errorf("no fields to update")
+	} else if err := checkPropNames(fields); err != nil {
+		return -1, err
+	}
+	sql, params := updateFieldsSQL(rid, version, fields)
+	var docs []*Document
+	err := db.Command(NewSQLCommand(sql, params...)).All(&docs)
+	db.evictRecord(rid)
+	if err != nil && err != ErrNoRecord {
+		return -1, err
+	} else if len(docs) != 0 {
+		return int32(docs[0].Vers), nil
+	}
+	// nothing was updated; check if the record is missing or has a different version
+	rec, err := db.GetRecordByRID(rid, "", true)
+	if err != nil {
+		return -1, err
+	} else if rec == nil {
+		return -1, ErrRecordNotFound{RID: rid}
+	} else if actual := rec.Version(); version >= 0 && actual != int(version) {
+		return -1, ErrConcurrentModification{
+			Exception: UnknownException{
+				Class:   "OConcurrentModificationException",
+				Message: fmt.Sprintf("Cannot UPDATE the record %v because the version is not the latest (db=v%d your=v%d)", rid, actual, version),
+			},
+			RID: rid, Expected: int(version), Actual: actual,
+		}
+	}
+	return -1, fmt.Errorf("record %v was not updated", rid)
+}
@@ -0,0 +1,105 @@
+package orient
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUpsertSQL(t *testing.T) {
+	sql, params := upsertSQL("User", map[string]interface{}{"email": "a@b.c"}, map[string]interface{}{"name": "A", "age": 3})
+	if expect := `UPDATE User SET age = ?, email = ?, name = ? UPSERT RETURN AFTER WHERE email = ?`; sql != expect {
+		t.Fatalf("wrong sql:\n%s\nexpected:\n%s", sql, expect)
+	} else if fmt.Sprint(params) != "[3 a@b.c A a@b.c]" {
+		t.Fatalf("wrong params: %v", params)
+	}
+}
+
+func TestUpsertInvalidClass(t *testing.T) {
+	var db Database // class name is checked before any request is made
+	if _, err := db.Upsert("User SET admin = true; UPDATE User", map[string]interface{}{"email": "a@b.c"}, nil); err == nil {
+		t.Fatal("expected error for invalid class name")
+	}
+	for _, name := range []string{"email/*", "a-b", "a.b", "a[0]", "#9:0", "a:b"} {
+		props := map[string]interface{}{name: 1}
+		if _, err := db.Upsert("User", props, nil); err == nil {
+			t.Fatalf("expected error for invalid key name %q", name)
+		}
+		if _, err := db.Upsert("User", map[string]interface{}{"email": "a@b.c"}, props); err == nil {
+			t.Fatalf("expected error for invalid field name %q", name)
+		}
+	}
+}
+
+func TestIsUniqueIndex(t *testing.T) {
+	def := NewEmptyDocument()
+	def.SetField("className", "User")
+	def.SetField("field", "email")
+	idx := NewEmptyDocument()
+	idx.SetField("type", "UNIQUE_HASH_INDEX")
+	idx.SetFieldWithType("indexDefinition", def, EMBEDDED)
+	if !isUniqueIndex(idx, "user", []string{"email"}) {
+		t.Fatal("index is not detected")
+	} else if isUniqueIndex(idx, "User", []string{"name"}) || isUniqueIndex(idx, "Admin", []string{"email"}) {
+		t.Fatal("wrong index detected")
+	}
+	idx = NewEmptyDocument()
+	idx.SetField("type", "NOTUNIQUE")
+	idx.SetFieldWithType("indexDefinition", def, EMBEDDED)
+	if isUniqueIndex(idx, "User", []string{"email"}) {
+		t.Fatal("non-unique index detected")
+	}
+}
+
+func TestUpdateFieldsSQL(t *testing.T) {
+	fields := map[string]interface{}{"name": "A", "age": 3}
+	sql, params := updateFieldsSQL(NewRID(9, 1), 4, fields)
+	if expect := `UPDATE #9:1 SET age = ?, name = ? RETURN AFTER WHERE @version = ?`; sql != expect {
+		t.Fatalf("wrong sql:\n%s\nexpected:\n%s", sql, expect)
+	} else if fmt.Sprint(params) != "[3 A 4]" {
+		t.Fatalf("wrong params: %v", params)
+	}
+	if sql, _ = updateFieldsSQL(NewRID(9, 1), -1, fields); sql != `UPDATE #9:1 SET age = ?, name = ? RETURN AFTER` {
+		t.Fatalf("wrong sql without version: %s", sql)
+	}
+}
+
+type updateSession struct {
+	cmdSession
+	rec ORecord // current record in database
+}
+
+func (s *updateSession) GetRecordByRID(rid RID, fetchPlan FetchPlan, ignoreCache bool) (ORecord, error) {
+	return s.rec, nil
+}
+
+func TestUpdateFieldsConcurrent(t *testing.T) {
+	cur := NewEmptyDocument()
+	cur.RID, cur.Vers = NewRID(9, 1), 3
+	sess := &updateSession{cmdSession: cmdSession{result: []OIdentifiable{}}, rec: cur}
+	db := &Database{pool: newConnPool(1, func() (DBSession, error) { return sess, nil })}
+	if _, err := db.UpdateFields(NewRID(9, 1), 2, map[string]interface{}{"name": "A"}); !IsConcurrentModification(err) {
+		t.Fatalf("expected concurrent modification, got: %v", err)
+	} else if e := err.(ErrConcurrentModification); e.Expected != 2 || e.Actual != 3 {
+		t.Fatalf("wrong versions: %+v", e)
+	}
+	sess.rec = nil
+	for _, vers := range []int32{2, -1} {
+		if _, err := db.UpdateFields(NewRID(9, 1), vers, map[string]interface{}{"name": "A"}); err != (ErrRecordNotFound{RID: NewRID(9, 1)}) {
+			t.Fatalf("expected record not found for version %d, got: %v", vers, err)
+		}
+	}
+	doc := NewEmptyDocument()
+	doc.RID, doc.Vers = NewRID(9, 1), 3
+	sess.result = []OIdentifiable{doc}
+	if v, err := db.UpdateFields(NewRID(9, 1), 2, map[string]interface{}{"name": "A"}); err != nil || v != 3 {
+		t.Fatalf("wrong version: %v, %v", v, err)
+	}
+	if _, err := db.UpdateFields(NewRID(9, 1), 2, nil); err == nil {
+		t.Fatal("expected an error without fields")
+	}
+	for _, name := range []string{"name/*", "a-b", "a.b", "a[0]", "#9:0", "a:b"} {
+		if _, err := db.UpdateFields(NewRID(9, 1), 2, map[string]interface{}{name: 1}); err == nil {
+			t.Fatalf("expected error for invalid field name %q", name)
+		}
+	}
+}
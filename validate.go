@@ -0,0 +1,193 @@
+package orient
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrValidation is returned when a value does not satisfy property constraints.
+type ErrValidation struct {
+	Field string
+	Msg   string
+}
+
+func (e ErrValidation) Error() string {
+	return fmt.Sprintf("validation failed for field '%s': %s", e.Field, e.Msg)
+}
+
+// validationTimeLayouts are used to parse min and max constraints of DATE and DATETIME properties.
+var validationTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02", time.RFC3339Nano}
+
+// Validate checks if value satisfies property constraints: NotNull, Min, Max and Regexp.
+// For strings, binary data and collections, Min and Max limit the length of a value.
+// Mandatory constraint can be checked only for a document (see OClass.Validate).
+func (p *OProperty) Validate(value interface{}) error {
+	name := p.Fullname
+	if name == "" {
+		name = p.Name
+	}
+	fail := func(format string, args ...interface{}) error {
+		return ErrValidation{Field: name, Msg: fmt.Sprintf(format, args...)}
+	}
+	if value == nil {
+		if p.NotNull {
+			return fail("value cannot be null")
+		}
+		return nil
+	}
+	if p.Min != "" {
+		if c, err := compareLimit(value, p.Min); err != nil {
+			return fail("cannot check min constraint: %v", err)
+		} else if c < 0 {
+			return fail("value %v is less than %s", value, p.Min)
+		}
+	}
+	if p.Max != "" {
+		if c, err := compareLimit(value, p.Max); err != nil {
+			return fail("cannot check max constraint: %v", err)
+		} else if c > 0 {
+			return fail("value %v is greater than %s", value, p.Max)
+		}
+	}
+	if p.Regexp != "" {
+		re, err := validationRegexp(p.Regexp)
+		if err != nil {
+			return fail("invalid regexp %q: %v", p.Regexp, err)
+		}
+		s, ok := value.(string)
+		if !ok {
+			s = fmt.Sprint(value)
+		}
+		if !re.MatchString(s) {
+			return fail("value %q does not match %q", s, p.Regexp)
+		}
+	}
+	return nil
+}
+
+// validationRegexps caches compiled Regexp constraints of properties, including invalid ones.
+var validationRegexps = struct {
+	sync.RWMutex
+	byPattern map[string]compiledRegexp
+}{byPattern: make(map[string]compiledRegexp)}
+
+type compiledRegexp struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// validationRegexp returns a compiled Regexp constraint. Java matches the whole string, so the pattern is anchored.
+func validationRegexp(pattern string) (*regexp.Regexp, error) {
+	validationRegexps.RLock()
+	c, ok := validationRegexps.byPattern[pattern]
+	validationRegexps.RUnlock()
+	if ok {
+		return c.re, c.err
+	}
+	c.re, c.err = regexp.Compile(`^(?:` + pattern + `)$`)
+	validationRegexps.Lock()
+	validationRegexps.byPattern[pattern] = c
+	validationRegexps.Unlock()
+	return c.re, c.err
+}
+
+// compareLimit compares a value (or it's length) with min or max constraint.
+func compareLimit(value interface{}, limit string) (int, error) {
+	if t, ok := value.(time.Time); ok {
+		for _, layout := range validationTimeLayouts {
+			if lt, err := time.Parse(layout, limit); err == nil {
+				switch {
+				case t.Before(lt):
+					return -1, nil
+				case t.After(lt):
+					return 1, nil
+				}
+				return 0, nil
+			}
+		}
+		return 0, fmt.Errorf("invalid date: %q", limit)
+	}
+	var v float64
+	switch val := value.(type) {
+	case Decimal:
+		s := decimalString(val)
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, err
+		}
+		v = f
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v = float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v = float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			v = rv.Float()
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			v = float64(rv.Len())
+		default:
+			return 0, fmt.Errorf("unsupported type: %T", value)
+		}
+	}
+	l, err := strconv.ParseFloat(limit, 64)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case v < l:
+		return -1, nil
+	case v > l:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Validate checks document fields against constraints of class properties, including properties
+// inherited from super classes (see Schema). In strict mode, fields that are not defined in schema are rejected.
+func (c *OClass) Validate(doc *Document) error {
+	props := make(map[string]*OProperty)
+	for cl := c; cl != nil; cl = cl.Super {
+		for name, p := range cl.Properties {
+			if _, ok := props[name]; !ok {
+				props[name] = p
+			}
+		}
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := props[name]
+		fld := doc.GetField(name)
+		if fld == nil {
+			if p.Mandatory {
+				return ErrValidation{Field: c.Name + "." + name, Msg: "field is mandatory"}
+			}
+			continue
+		}
+		if err := p.Validate(fld.Value); err != nil {
+			if e, ok := err.(ErrValidation); ok && p.Fullname == "" {
+				e.Field = c.Name + "." + name
+				err = e
+			}
+			return err
+		}
+	}
+	if c.StrictMode {
+		for _, name := range doc.FieldNames() {
+			if _, ok := props[name]; !ok {
+				return ErrValidation{Field: c.Name + "." + name, Msg: "field is not defined in strict mode"}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package orient
+
+import (
+	"reflect"
+	"sync"
+)
+
+// ValueSerializer converts values of a custom Go type to values that can be stored in records, and back.
+//
+// For example, a Point type can be stored as an embedded document:
+//
+//		type pointSerializer struct{}
+//
+//		func (pointSerializer) ToValue(v interface{}) (interface{}, orient.OType, error) {
+//			p := v.(Point)
+//			doc := orient.NewDocument("OPoint")
+//			doc.SetField("coordinates", []float64{p.X, p.Y})
+//			return doc, orient.EMBEDDED, nil
+//		}
+//
+//		func (pointSerializer) FromValue(v interface{}) (interface{}, error) {
+//			...
+//		}
+//
+//		orient.RegisterValueSerializer(reflect.TypeOf(Point{}), pointSerializer{})
+type ValueSerializer interface {
+	// ToValue converts a value of registered type to a value supported by record serializer and returns its type.
+	ToValue(v interface{}) (interface{}, OType, error)
+	// FromValue converts a value read from a record to a value of registered type.
+	FromValue(v interface{}) (interface{}, error)
+}
+
+var valueSerializers = struct {
+	sync.RWMutex
+	byType map[reflect.Type]ValueSerializer
+}{byType: make(map[reflect.Type]ValueSerializer)}
+
+// RegisterValueSerializer registers a serializer for values of a given Go type. Values of this type are converted
+// with ser.ToValue when records are serialized, including values in embedded collections and maps, and are
+// converted back with ser.FromValue when results or documents are decoded into fields of this type.
+// Passing nil serializer removes registration.
+func RegisterValueSerializer(t reflect.Type, ser ValueSerializer) {
+	valueSerializers.Lock()
+	defer valueSerializers.Unlock()
+	if ser == nil {
+		delete(valueSerializers.byType, t)
+		return
+	}
+	valueSerializers.byType[t] = ser
+}
+
+func valueSerializerFor(t reflect.Type) ValueSerializer {
+	valueSerializers.RLock()
+	defer valueSerializers.RUnlock()
+	return valueSerializers.byType[t]
+}
+
+// toStoredValue converts a value with registered serializer. It returns false if there is no serializer for the value.
+func toStoredValue(v interface{}) (interface{}, OType, bool, error) {
+	if v == nil {
+		return nil, UNKNOWN, false, nil
+	}
+	ser := valueSerializerFor(reflect.TypeOf(v))
+	if ser == nil {
+		return v, UNKNOWN, false, nil
+	}
+	out, tp, err := ser.ToValue(v)
+	return out, tp, true, err
+}
+
+// fromStoredValue converts a stored value into a value of a given type, if it has a registered serializer.
+func fromStoredValue(t reflect.Type, v interface{}) (interface{}, bool, error) {
+	if v == nil || reflect.TypeOf(v) == t {
+		return v, false, nil
+	}
+	ser := valueSerializerFor(t)
+	if ser == nil {
+		return v, false, nil
+	}
+	out, err := ser.FromValue(v)
+	return out, true, err
+}
+
+// valueSerializerHookFunc is a map decoder hook that applies registered value serializers.
+func valueSerializerHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	out, _, err := fromStoredValue(t, data)
+	return out, err
+}
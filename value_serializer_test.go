@@ -0,0 +1,75 @@
+package orient
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testMoney struct {
+	Cents    int64
+	Currency string
+}
+
+type testMoneySerializer struct{}
+
+func (testMoneySerializer) ToValue(v interface{}) (interface{}, OType, error) {
+	m := v.(testMoney)
+	return fmt.Sprintf("%d %s", m.Cents, m.Currency), STRING, nil
+}
+
+func (testMoneySerializer) FromValue(v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected money value: %T", v)
+	}
+	var m testMoney
+	if _, err := fmt.Sscanf(s, "%d %s", &m.Cents, &m.Currency); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func TestValueSerializer(t *testing.T) {
+	typ := reflect.TypeOf(testMoney{})
+	RegisterValueSerializer(typ, testMoneySerializer{})
+	defer RegisterValueSerializer(typ, nil)
+
+	type Order struct {
+		Total  testMoney
+		Items  []testMoney
+		ByUser map[string]testMoney
+	}
+	in := Order{
+		Total:  testMoney{1250, "USD"},
+		Items:  []testMoney{{1000, "USD"}, {250, "USD"}},
+		ByUser: map[string]testMoney{"anna": {1250, "USD"}},
+	}
+	doc := NewDocument("Order")
+	if err := doc.From(in); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := GetDefaultRecordSerializer().ToStream(buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := GetDefaultRecordSerializer().FromStream(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := rec.(*Document)
+	if fld := out.GetField("Total"); fld == nil || fld.Type != STRING || fld.Value != "1250 USD" {
+		t.Fatalf("wrong stored value: %+v", fld)
+	} else if fld = out.GetField("Items"); fld == nil || !reflect.DeepEqual(fld.Value, []interface{}{"1000 USD", "250 USD"}) {
+		t.Fatalf("wrong stored collection: %+v", fld)
+	}
+	var res Order
+	if err = out.ToStruct(&res); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(res, in) {
+		t.Fatalf("wrong data after round-trip:\n%+v\n%+v", res, in)
+	}
+	var total testMoney
+	testResults(t, "1250 USD", &total, in.Total)
+}